@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+type askAllOptions struct {
+	Notebooks []string
+	Tags      []string
+	Parallel  int
+	Compare   bool
+	JSON      bool
+}
+
+// notebookAnswer is one notebook's answer to an ask-all question.
+type notebookAnswer struct {
+	NotebookID string         `json:"notebook_id"`
+	Title      string         `json:"notebook_title,omitempty"`
+	Answer     string         `json:"answer,omitempty"`
+	Citations  []api.Citation `json:"citations,omitempty"`
+	Error      string         `json:"error,omitempty"`
+
+	err error
+}
+
+// askAllClaim is a span of an answer together with the sources cited for it.
+type askAllClaim struct {
+	Text       string   `json:"text"`
+	NotebookID string   `json:"notebook_id"`
+	Notebook   string   `json:"notebook"`
+	Sources    []string `json:"sources"`
+}
+
+// askAllReport is the merged result of an ask-all run.
+type askAllReport struct {
+	Question      string           `json:"question"`
+	Answers       []notebookAnswer `json:"answers"`
+	Claims        []askAllClaim    `json:"claims,omitempty"`
+	Disagreements string           `json:"disagreements,omitempty"`
+	ComparedIn    string           `json:"compared_in,omitempty"` // notebook used for the comparison
+}
+
+func parseAskAllArgs(args []string) (askAllOptions, string, error) {
+	opts := askAllOptions{Parallel: 4}
+	var notebooks, tags string
+	flags := flag.NewFlagSet("ask-all", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&notebooks, "notebooks", "", "comma-separated notebook IDs")
+	flags.StringVar(&tags, "tag", "", "comma-separated tags from the [tags] config table")
+	flags.IntVar(&opts.Parallel, "parallel", opts.Parallel, "notebooks to query at once")
+	flags.BoolVar(&opts.Compare, "compare", opts.Compare, "ask the first notebook that answered to point out disagreements")
+	flags.BoolVar(&opts.JSON, "json", false, "print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nlm ask-all \"question\" [-notebooks a,b,c] [-tag name] [-parallel 4] [-compare] [-json]\n")
+	}
+
+	flagArgs, positional := splitFlagArgs(args, "json", "compare")
+	if err := flags.Parse(flagArgs); err != nil {
+		return opts, "", fmt.Errorf("invalid arguments")
+	}
+	question := strings.TrimSpace(strings.Join(positional, " "))
+	if question == "" || notebooks == "" && tags == "" {
+		flags.Usage()
+		return opts, "", fmt.Errorf("invalid arguments")
+	}
+	if opts.Parallel < 1 {
+		return opts, "", fmt.Errorf("-parallel must be at least 1")
+	}
+	opts.Notebooks = splitList(notebooks)
+	opts.Tags = splitList(tags)
+	return opts, question, nil
+}
+
+// splitFlagArgs separates flags from positional arguments so that flags may
+// follow them. Flags named in boolFlags take no separate value.
+func splitFlagArgs(args []string, boolFlags ...string) (flagArgs, positional []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+		flagArgs = append(flagArgs, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if !slices.Contains(boolFlags, name) && i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+	}
+	return flagArgs, positional
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// askAllNotebooks returns the notebooks named directly or through tags,
+// without duplicates, in the order given.
+func askAllNotebooks(opts askAllOptions, tags map[string][]string) ([]string, error) {
+	ids := append([]string(nil), opts.Notebooks...)
+	for _, tag := range opts.Tags {
+		tagged, ok := tags[tag]
+		if !ok {
+			return nil, fmt.Errorf("unknown tag %q (define it under [tags] in %s)", tag, configFile)
+		}
+		ids = append(ids, tagged...)
+	}
+	seen := make(map[string]bool)
+	out := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no notebooks selected")
+	}
+	return out, nil
+}
+
+// askNotebooks calls ask for each notebook, at most parallel at a time,
+// and returns the answers in the order of ids.
+func askNotebooks(ids []string, parallel int, ask func(id string) notebookAnswer) []notebookAnswer {
+	answers := make([]notebookAnswer, len(ids))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			answers[i] = ask(id)
+		}()
+	}
+	wg.Wait()
+	return answers
+}
+
+// askAll asks the question of every selected notebook and prints a merged
+// report.
+func askAll(c *api.Client, opts askAllOptions, question string) error {
+	ids, err := askAllNotebooks(opts, notebookTags)
+	if err != nil {
+		return err
+	}
+	titles := make(map[string]string)
+	if notebooks, err := c.ListRecentlyViewedProjects(); err == nil {
+		for _, nb := range notebooks {
+			titles[nb.GetProjectId()] = strings.TrimSpace(nb.GetTitle())
+		}
+	} else if isAuthenticationError(err) {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Asking %d notebooks...\n", len(ids))
+	report := &askAllReport{Question: question}
+	report.Answers = askNotebooks(ids, opts.Parallel, func(id string) notebookAnswer {
+		a := notebookAnswer{NotebookID: id, Title: titles[id]}
+		a.Answer, a.Citations, a.err = askNotebook(c, id, question)
+		if a.err != nil {
+			a.Error = a.err.Error()
+			fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", a.label(), a.err)
+		} else {
+			fmt.Fprintf(os.Stderr, "  ✓ %s\n", a.label())
+		}
+		return a
+	})
+
+	var answered []notebookAnswer
+	for _, a := range report.Answers {
+		if a.err == nil && a.Answer != "" {
+			answered = append(answered, a)
+		}
+	}
+	if len(answered) == 0 {
+		for _, a := range report.Answers {
+			if isAuthenticationError(a.err) {
+				return a.err
+			}
+		}
+		return fmt.Errorf("no notebook answered the question")
+	}
+	report.Claims = askAllClaims(answered)
+
+	// The comparison is a chat in the first notebook that answered, in
+	// -notebooks or tag order, so it is opt-in: it adds a conversation to
+	// that notebook.
+	if opts.Compare && len(answered) > 1 {
+		fmt.Fprintf(os.Stderr, "Comparing answers in %s...\n", answered[0].label())
+		text, _, err := askNotebook(c, answered[0].NotebookID, compareAnswersPrompt(question, answered))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "nlm: compare answers: %v\n", err)
+		} else {
+			report.Disagreements, report.ComparedIn = text, answered[0].NotebookID
+		}
+	}
+
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	writeAskAllReport(os.Stdout, report)
+	return nil
+}
+
+// askNotebook sends a single chat turn to a notebook and returns the answer
+// and its citations.
+func askNotebook(c *api.Client, notebookID, prompt string) (string, []api.Citation, error) {
+	var answer strings.Builder
+	var citations []api.Citation
+	err := c.StreamChat(api.ChatRequest{ProjectID: notebookID, Prompt: prompt}, func(chunk api.ChatChunk) bool {
+		switch chunk.Phase {
+		case api.ChatChunkAnswer:
+			answer.WriteString(chunk.Text)
+		case api.ChatChunkCitations:
+			citations = chunk.Citations
+		}
+		return true
+	})
+	// Keep leading text intact: citation spans are offsets into the answer.
+	return strings.TrimRight(answer.String(), " \t\n"), citations, err
+}
+
+func (a notebookAnswer) label() string {
+	if a.Title != "" {
+		return a.Title
+	}
+	return a.NotebookID
+}
+
+// askAllClaims returns the cited spans of each answer with the titles of
+// the sources cited for them. Spans are taken as rune offsets into the
+// answer text.
+func askAllClaims(answers []notebookAnswer) []askAllClaim {
+	var claims []askAllClaim
+	for _, a := range answers {
+		text := []rune(a.Answer)
+		sources := make(map[api.TextRange][]string)
+		for _, c := range a.Citations {
+			label := firstNonEmpty(c.SourceTitle, c.SourceID, fmt.Sprintf("source [%d]", c.Number))
+			for _, span := range c.Spans {
+				if !slices.Contains(sources[span], label) {
+					sources[span] = append(sources[span], label)
+				}
+			}
+		}
+		spans := make([]api.TextRange, 0, len(sources))
+		for span := range sources {
+			spans = append(spans, span)
+		}
+		sort.Slice(spans, func(i, j int) bool {
+			if spans[i].Start != spans[j].Start {
+				return spans[i].Start < spans[j].Start
+			}
+			return spans[i].End < spans[j].End
+		})
+		for _, span := range spans {
+			start, end := min(max(span.Start, 0), len(text)), min(max(span.End, 0), len(text))
+			claim := strings.Join(strings.Fields(string(text[start:end])), " ")
+			if claim == "" {
+				continue
+			}
+			claims = append(claims, askAllClaim{
+				Text:       claim,
+				NotebookID: a.NotebookID,
+				Notebook:   a.label(),
+				Sources:    sources[span],
+			})
+		}
+	}
+	return claims
+}
+
+// compareAnswersPrompt asks for the points on which the answers disagree.
+func compareAnswersPrompt(question string, answers []notebookAnswer) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Several notebooks answered the question %q. ", question)
+	b.WriteString("Compare the answers below and list only the points where they disagree or contradict each other, naming the notebooks involved. ")
+	b.WriteString("Base the comparison on these answers alone. If they agree, reply \"No disagreements.\"\n")
+	for _, a := range answers {
+		fmt.Fprintf(&b, "\n### Notebook: %s\n%s\n", a.label(), a.Answer)
+	}
+	return b.String()
+}
+
+func writeAskAllReport(w io.Writer, r *askAllReport) {
+	answered := 0
+	for _, a := range r.Answers {
+		if a.Error == "" && a.Answer != "" {
+			answered++
+		}
+	}
+	fmt.Fprintf(w, "# %s\n\nAsked %d notebooks, %d answered.\n", r.Question, len(r.Answers), answered)
+
+	for _, a := range r.Answers {
+		if a.Error != "" || a.Answer == "" {
+			continue
+		}
+		fmt.Fprintf(w, "\n## %s\n\n", a.label())
+		if a.Title != "" {
+			fmt.Fprintf(w, "Notebook %s\n\n", a.NotebookID)
+		}
+		fmt.Fprintln(w, a.Answer)
+		if len(a.Citations) > 0 {
+			fmt.Fprintln(w)
+			writeCitationFootnotes(w, a.Citations)
+			fmt.Fprintln(w)
+		}
+	}
+
+	if len(r.Claims) > 0 {
+		fmt.Fprintf(w, "\n## Claims by source\n\n")
+		for _, c := range r.Claims {
+			fmt.Fprintf(w, "- %s\n  — %s: %s\n", c.Text, c.Notebook, strings.Join(c.Sources, "; "))
+		}
+	}
+
+	if r.Disagreements != "" {
+		fmt.Fprintf(w, "\n## Disagreements\n\n%s\n", r.Disagreements)
+		label := r.ComparedIn
+		for _, a := range r.Answers {
+			if a.NotebookID == r.ComparedIn {
+				label = a.label()
+			}
+		}
+		fmt.Fprintf(w, "\n(Compared by NotebookLM in %s.)\n", label)
+	}
+
+	var failed []notebookAnswer
+	for _, a := range r.Answers {
+		if a.Error != "" {
+			failed = append(failed, a)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(w, "\n## Failed\n\n")
+		for _, a := range failed {
+			fmt.Fprintf(w, "- %s: %s\n", a.label(), a.Error)
+		}
+	}
+}
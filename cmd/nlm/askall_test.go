@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+func TestParseAskAllArgs(t *testing.T) {
+	opts, q, err := parseAskAllArgs([]string{"What", "changed?", "-notebooks", "a, b", "-tag=x", "-json", "-compare", "-parallel", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q != "What changed?" {
+		t.Errorf("question = %q", q)
+	}
+	if strings.Join(opts.Notebooks, ",") != "a,b" || strings.Join(opts.Tags, ",") != "x" {
+		t.Errorf("notebooks = %q, tags = %q", opts.Notebooks, opts.Tags)
+	}
+	if !opts.JSON || !opts.Compare || opts.Parallel != 2 {
+		t.Errorf("opts = %+v", opts)
+	}
+	if opts, _, err := parseAskAllArgs([]string{"Q?", "-notebooks", "a,b"}); err != nil || opts.Compare {
+		t.Errorf("default opts = %+v, %v; compare must be opt-in", opts, err)
+	}
+}
+
+func TestAskAllNotebooks(t *testing.T) {
+	tags := map[string][]string{"research": {"b", "c"}}
+	got, err := askAllNotebooks(askAllOptions{Notebooks: []string{"a", "b"}, Tags: []string{"research"}}, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Errorf("notebooks = %q, want a,b,c", got)
+	}
+	if _, err := askAllNotebooks(askAllOptions{Tags: []string{"nope"}}, tags); err == nil || !strings.Contains(err.Error(), `unknown tag "nope"`) {
+		t.Errorf("unknown tag error = %v", err)
+	}
+}
+
+func TestAskNotebooksKeepsOrderAndBoundsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	ids := []string{"a", "b", "c", "d", "e"}
+	answers := askNotebooks(ids, 2, func(id string) notebookAnswer {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return notebookAnswer{NotebookID: id, Answer: "answer " + id}
+	})
+	for i, a := range answers {
+		if a.NotebookID != ids[i] {
+			t.Errorf("answers[%d] = %s, want %s", i, a.NotebookID, ids[i])
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("peak concurrency = %d, want <= 2", p)
+	}
+}
+
+func TestAskAllClaims(t *testing.T) {
+	answers := []notebookAnswer{{
+		NotebookID: "nb-1",
+		Title:      "Ops",
+		Answer:     "Deploys run nightly [1]. Rollbacks are manual [1, 2].",
+		Citations: []api.Citation{
+			{Number: 1, SourceTitle: "Runbook", Spans: []api.TextRange{{Start: 0, End: 24}, {Start: 25, End: 53}}},
+			{Number: 2, SourceID: "src-2", Spans: []api.TextRange{{Start: 25, End: 53}, {Start: 39, End: 400}}},
+		},
+	}}
+	claims := askAllClaims(answers)
+	got := make([]string, len(claims))
+	for i, c := range claims {
+		got[i] = fmt.Sprintf("%s|%s|%s", c.Text, c.Notebook, strings.Join(c.Sources, ";"))
+	}
+	want := []string{
+		"Deploys run nightly [1].|Ops|Runbook",
+		"Rollbacks are manual [1, 2].|Ops|Runbook;src-2",
+		"manual [1, 2].|Ops|src-2",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("claims:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWriteAskAllReport(t *testing.T) {
+	r := &askAllReport{
+		Question: "When do deploys run?",
+		Answers: []notebookAnswer{
+			{NotebookID: "nb-1", Title: "Ops", Answer: "Nightly [1].",
+				Citations: []api.Citation{{Number: 1, SourceTitle: "Runbook"}}},
+			{NotebookID: "nb-2", Answer: "Weekly."},
+			{NotebookID: "nb-3", Error: "chat request failed"},
+		},
+		Claims:        []askAllClaim{{Text: "Nightly [1].", Notebook: "Ops", Sources: []string{"Runbook"}}},
+		Disagreements: "Ops says nightly; nb-2 says weekly.",
+		ComparedIn:    "nb-1",
+	}
+	var buf bytes.Buffer
+	writeAskAllReport(&buf, r)
+	got := buf.String()
+	for _, want := range []string{
+		"# When do deploys run?\n\nAsked 3 notebooks, 2 answered.\n",
+		"## Ops\n\nNotebook nb-1\n\nNightly [1].\n\nSources:\n  [1] Runbook\n",
+		"## nb-2\n\nWeekly.\n",
+		"- Nightly [1].\n  — Ops: Runbook\n",
+		"## Disagreements\n\nOps says nightly; nb-2 says weekly.\n\n(Compared by NotebookLM in Ops.)\n",
+		"## Failed\n\n- nb-3: chat request failed\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCompareAnswersPrompt(t *testing.T) {
+	p := compareAnswersPrompt("Q?", []notebookAnswer{{NotebookID: "a", Title: "Alpha", Answer: "yes"}, {NotebookID: "b", Answer: "no"}})
+	for _, want := range []string{`"Q?"`, "### Notebook: Alpha\nyes\n", "### Notebook: b\nno\n"} {
+		if !strings.Contains(p, want) {
+			t.Errorf("prompt missing %q:\n%s", want, p)
+		}
+	}
+}
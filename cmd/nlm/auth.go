@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -307,13 +308,12 @@ func persistAuthToDisk(cookies, authToken, profileName, sessionID, blParam, auth
 	}
 
 	// Create .nlm directory if it doesn't exist
-	nlmDir := filepath.Join(homeDir, ".nlm")
-	if err := os.MkdirAll(nlmDir, 0700); err != nil {
+	envFile := storedEnvFile(homeDir)
+	if err := os.MkdirAll(filepath.Dir(envFile), 0700); err != nil {
 		return "", "", fmt.Errorf("create .nlm directory: %w", err)
 	}
 
 	// Create or update env file
-	envFile := filepath.Join(nlmDir, "env")
 	content := fmt.Sprintf("NLM_COOKIES=%q\nNLM_AUTH_TOKEN=%q\nNLM_BROWSER_PROFILE=%q\nNLM_SESSION_ID=%q\nNLM_BL_PARAM=%q\nNLM_SIGNALER_AUTH=%q\nNLM_AUTHUSER=%q\n",
 		cookies,
 		authToken,
@@ -354,7 +354,10 @@ func persistSignalerAuthorization(authz string) error {
 	if err != nil {
 		return fmt.Errorf("get home dir: %w", err)
 	}
-	envFile := filepath.Join(homeDir, ".nlm", "env")
+	envFile := storedEnvFile(homeDir)
+	if err := os.MkdirAll(filepath.Dir(envFile), 0700); err != nil {
+		return fmt.Errorf("create .nlm directory: %w", err)
+	}
 	values := readStoredEnv()
 	if values == nil {
 		values = make(map[string]string)
@@ -399,7 +402,11 @@ func readStoredEnv() map[string]string {
 		return nil
 	}
 
-	data, err := os.ReadFile(filepath.Join(home, ".nlm", "env"))
+	data, err := os.ReadFile(storedEnvFile(home))
+	if errors.Is(err, os.ErrNotExist) && activeProfileName != "" {
+		// Profiles without their own credentials share the default ones.
+		data, err = os.ReadFile(filepath.Join(home, ".nlm", "env"))
+	}
 	if err != nil {
 		return nil
 	}
@@ -427,6 +434,12 @@ func readStoredEnv() map[string]string {
 	return values
 }
 
+// storedEnvFile returns the credentials file written by nlm auth:
+// ~/.nlm/profiles/<name>/env for the active config profile, else ~/.nlm/env.
+func storedEnvFile(home string) string {
+	return filepath.Join(profileStateDir(home), "env")
+}
+
 func firstNonEmpty(values ...string) string {
 	for _, value := range values {
 		if value != "" {
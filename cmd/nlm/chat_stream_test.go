@@ -104,3 +104,49 @@ func TestChatStreamRendererThinkingModes(t *testing.T) {
 		}
 	})
 }
+
+func TestChatStreamRendererCitationFootnotes(t *testing.T) {
+	var out bytes.Buffer
+	var status bytes.Buffer
+
+	r := newChatStreamRenderer(&out, &status, false, false)
+	r.WriteChunk(api.ChatChunk{
+		Phase: api.ChatChunkAnswer,
+		Text:  "Audio uses WebRTC [1, 2].",
+	})
+	citations := []api.Citation{
+		{Number: 1, SourceID: "src-1", SourceTitle: "Design Notes", Text: "Real-time audio via WebRTC"},
+		{Number: 2},
+	}
+	r.WriteChunk(api.ChatChunk{Phase: api.ChatChunkCitations, Citations: citations})
+	r.Finish()
+
+	want := "Audio uses WebRTC [1, 2].\n\nSources:\n" +
+		"  [1] Design Notes (src-1)\n" +
+		"      \"Real-time audio via WebRTC\"\n" +
+		"  [2] (source unknown)"
+	if got := out.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if got := r.Citations(); len(got) != 2 {
+		t.Fatalf("Citations() = %+v, want 2", got)
+	}
+	if got := r.Answer(); got != "Audio uses WebRTC [1, 2]." {
+		t.Fatalf("Answer() = %q, citations must not be part of the answer", got)
+	}
+}
+
+func TestChatStreamRendererNoFootnotes(t *testing.T) {
+	var out, status bytes.Buffer
+	r := newChatStreamRenderer(&out, &status, false, false)
+	r.footnotes = false
+	r.WriteChunk(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "Audio uses WebRTC [1]."})
+	r.WriteChunk(api.ChatChunk{Phase: api.ChatChunkCitations, Citations: []api.Citation{{Number: 1, SourceTitle: "Design Notes"}}})
+	r.Finish()
+	if got := out.String(); got != "Audio uses WebRTC [1]." {
+		t.Fatalf("output = %q, want the answer only", got)
+	}
+	if got := r.Citations(); len(got) != 1 {
+		t.Fatalf("Citations() = %+v, want 1", got)
+	}
+}
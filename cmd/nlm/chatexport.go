@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// chatTranscript is a conversation prepared for export.
+type chatTranscript struct {
+	NotebookID     string              `json:"notebook_id"`
+	NotebookTitle  string              `json:"notebook_title,omitempty"`
+	ConversationID string              `json:"conversation_id,omitempty"`
+	Origin         string              `json:"origin"` // "local" or "server"
+	ExportedAt     time.Time           `json:"exported_at"`
+	Messages       []transcriptMessage `json:"messages"`
+}
+
+type transcriptMessage struct {
+	Role      string               `json:"role"` // "user" or "assistant"
+	Content   string               `json:"content"`
+	Timestamp *time.Time           `json:"timestamp,omitempty"`
+	Thinking  string               `json:"thinking,omitempty"`
+	Citations []transcriptCitation `json:"citations,omitempty"`
+}
+
+type transcriptCitation struct {
+	Number   int    `json:"number"`
+	SourceID string `json:"source_id,omitempty"`
+	Title    string `json:"title"`
+	Text     string `json:"text,omitempty"` // cited source fragment
+}
+
+// chatExportFormat returns the export format selected with -format. The
+// generate-chat formats (stream, plain) select the default, Markdown.
+func chatExportFormat(format string) (string, error) {
+	switch format {
+	case "", "stream", "plain", "md", "markdown":
+		return "md", nil
+	case "html", "json":
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q (use md, html or json)", format)
+}
+
+// exportChat writes a conversation to stdout. Without a conversation ID it
+// exports the notebook's local chat session, or else its first server-side
+// conversation. Local sessions are preferred because they keep timestamps
+// and thinking traces; server history has neither.
+func exportChat(c *api.Client, notebookID, conversationID, format string) error {
+	format, err := chatExportFormat(format)
+	if err != nil {
+		return err
+	}
+	t := &chatTranscript{NotebookID: notebookID, ExportedAt: time.Now()}
+
+	session := findLocalChatSession(notebookID, conversationID)
+	if session == nil {
+		if conversationID == "" {
+			convs, err := c.GetConversations(notebookID)
+			if err != nil {
+				return fmt.Errorf("list conversations: %w", err)
+			}
+			if len(convs) == 0 {
+				return fmt.Errorf("no conversations found for notebook %s", notebookID)
+			}
+			conversationID = convs[0]
+		}
+		var err error
+		if session, err = fetchServerChatSession(c, notebookID, conversationID); err != nil {
+			return err
+		}
+		t.Origin = "server"
+	} else {
+		t.Origin = "local"
+	}
+	if len(session.Messages) == 0 {
+		return fmt.Errorf("conversation has no messages")
+	}
+	t.ConversationID = session.ConversationID
+
+	titles := map[string]string{}
+	if p, err := c.GetProject(notebookID); err == nil {
+		t.NotebookTitle = p.GetTitle()
+		for _, src := range p.GetSources() {
+			titles[src.GetSourceId().GetSourceId()] = strings.TrimSpace(src.GetTitle())
+		}
+	} else if debug {
+		fmt.Fprintf(os.Stderr, "nlm: could not load source titles: %v\n", err)
+	}
+	t.Messages = transcriptMessages(session, titles)
+	return writeChatTranscript(os.Stdout, t, format)
+}
+
+// fetchServerChatSession loads a conversation's messages from the server.
+// Server history has no timestamps, reasoning or citations.
+func fetchServerChatSession(c *api.Client, notebookID, conversationID string) (*ChatSession, error) {
+	msgs, err := c.GetConversationHistory(notebookID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	session := &ChatSession{NotebookID: notebookID, ConversationID: conversationID}
+	for _, m := range msgs {
+		role := "user"
+		if m.Role == 2 {
+			role = "assistant"
+		}
+		session.Messages = append(session.Messages, ChatMessage{Role: role, Content: m.Content})
+	}
+	return session, nil
+}
+
+// findLocalChatSession returns the stored session for the conversation,
+// matching full or shortened conversation IDs, or the notebook's current
+// session when conversationID is empty.
+func findLocalChatSession(notebookID, conversationID string) *ChatSession {
+	if conversationID == "" {
+		session, err := loadChatSession(notebookID)
+		if err != nil {
+			return nil
+		}
+		return session
+	}
+	sessions, _ := listLocalChatSessions(notebookID)
+	for i, s := range sessions {
+		if s.ConversationID != "" && strings.HasPrefix(s.ConversationID, conversationID) {
+			return &sessions[i]
+		}
+	}
+	return nil
+}
+
+// transcriptMessages converts session messages, resolving citations to
+// source titles where the stream did not already provide them.
+func transcriptMessages(session *ChatSession, titles map[string]string) []transcriptMessage {
+	var out []transcriptMessage
+	for _, m := range session.Messages {
+		tm := transcriptMessage{
+			Role:     m.Role,
+			Content:  strings.TrimSpace(m.Content),
+			Thinking: strings.TrimSpace(m.Thinking),
+		}
+		if !m.Timestamp.IsZero() {
+			ts := m.Timestamp
+			tm.Timestamp = &ts
+		}
+		for _, c := range m.Citations {
+			title := c.SourceTitle
+			if title == "" {
+				title = titles[c.SourceID]
+			}
+			if title == "" {
+				title = c.SourceID
+			}
+			if title == "" {
+				title = "(source unknown)"
+			}
+			tm.Citations = append(tm.Citations, transcriptCitation{
+				Number: c.Number, SourceID: c.SourceID, Title: title, Text: c.Text,
+			})
+		}
+		out = append(out, tm)
+	}
+	return out
+}
+
+func writeChatTranscript(w io.Writer, t *chatTranscript, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t)
+	case "html":
+		return writeChatHTML(w, t)
+	}
+	return writeChatMarkdown(w, t)
+}
+
+func (t *chatTranscript) title() string {
+	if t.NotebookTitle != "" {
+		return t.NotebookTitle
+	}
+	return "Notebook " + t.NotebookID
+}
+
+func (t *chatTranscript) summary() string {
+	var parts []string
+	if t.ConversationID != "" {
+		parts = append(parts, "Conversation "+t.ConversationID)
+	}
+	parts = append(parts, fmt.Sprintf("%d messages", len(t.Messages)))
+	parts = append(parts, "exported "+t.ExportedAt.Format("2006-01-02 15:04 MST"))
+	return strings.Join(parts, " · ")
+}
+
+func (m transcriptMessage) speaker() string {
+	if m.Role == "user" {
+		return "You"
+	}
+	return "NotebookLM"
+}
+
+func (m transcriptMessage) when() string {
+	if m.Timestamp == nil {
+		return ""
+	}
+	return m.Timestamp.Format("2006-01-02 15:04")
+}
+
+func writeChatMarkdown(w io.Writer, t *chatTranscript) error {
+	fmt.Fprintf(w, "# %s\n\n_%s_\n", t.title(), t.summary())
+	for _, m := range t.Messages {
+		fmt.Fprintf(w, "\n## %s", m.speaker())
+		if when := m.when(); when != "" {
+			fmt.Fprintf(w, " · %s", when)
+		}
+		fmt.Fprint(w, "\n\n")
+		if m.Thinking != "" {
+			fmt.Fprintf(w, "<details>\n<summary>Thinking</summary>\n\n%s\n\n</details>\n\n", m.Thinking)
+		}
+		fmt.Fprintf(w, "%s\n", m.Content)
+		if len(m.Citations) > 0 {
+			fmt.Fprint(w, "\n**Sources**\n\n")
+			for _, c := range m.Citations {
+				fmt.Fprintf(w, "%d. %s", c.Number, c.Title)
+				if c.Text != "" {
+					fmt.Fprintf(w, " — “%s”", c.Text)
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+	return nil
+}
+
+const chatHTMLStyle = `body{font-family:system-ui,sans-serif;max-width:48rem;margin:2rem auto;padding:0 1rem;line-height:1.5;color:#222}
+.meta{color:#666;font-size:.9em}
+.msg{border-left:4px solid #ccc;padding:.25rem 1rem;margin:1.5rem 0}
+.user{border-color:#4a7bd0}
+.assistant{border-color:#3a9a5b}
+.msg h2{font-size:1rem;margin:.5rem 0}
+.msg time{color:#666;font-weight:normal;margin-left:.5rem}
+.content{white-space:pre-wrap}
+details{color:#555;margin:.5rem 0}
+details div{white-space:pre-wrap;font-size:.9em}
+ol.sources{font-size:.9em;color:#444}`
+
+func writeChatHTML(w io.Writer, t *chatTranscript) error {
+	esc := html.EscapeString
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", esc(t.title()), chatHTMLStyle)
+	fmt.Fprintf(w, "<h1>%s</h1>\n<p class=\"meta\">%s</p>\n", esc(t.title()), esc(t.summary()))
+	for _, m := range t.Messages {
+		fmt.Fprintf(w, "<section class=\"msg %s\">\n<h2>%s", esc(m.Role), esc(m.speaker()))
+		if m.Timestamp != nil {
+			fmt.Fprintf(w, "<time datetime=\"%s\">%s</time>", m.Timestamp.Format(time.RFC3339), esc(m.when()))
+		}
+		fmt.Fprint(w, "</h2>\n")
+		if m.Thinking != "" {
+			fmt.Fprintf(w, "<details><summary>Thinking</summary><div>%s</div></details>\n", esc(m.Thinking))
+		}
+		fmt.Fprintf(w, "<div class=\"content\">%s</div>\n", esc(m.Content))
+		if len(m.Citations) > 0 {
+			fmt.Fprint(w, "<ol class=\"sources\">\n")
+			for _, c := range m.Citations {
+				fmt.Fprintf(w, "<li value=\"%d\">%s", c.Number, esc(c.Title))
+				if c.Text != "" {
+					fmt.Fprintf(w, " <q>%s</q>", esc(c.Text))
+				}
+				fmt.Fprint(w, "</li>\n")
+			}
+			fmt.Fprint(w, "</ol>\n")
+		}
+		fmt.Fprint(w, "</section>\n")
+	}
+	_, err := fmt.Fprint(w, "</body>\n</html>\n")
+	return err
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+func testTranscript() *chatTranscript {
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	session := &ChatSession{
+		NotebookID:     "nb-1",
+		ConversationID: "conv-1234",
+		Messages: []ChatMessage{
+			{Role: "user", Content: "What is <b>bold</b>?", Timestamp: at},
+			{Role: "assistant", Content: "An HTML tag.\n", Timestamp: at.Add(time.Minute),
+				Thinking: "Checking sources", Citations: []api.Citation{
+					{Number: 1, SourceID: "src-1", Text: "Tags mark up text."},
+					{Number: 2, SourceID: "src-2", SourceTitle: "Streamed Title"},
+					{Number: 3},
+				}},
+		},
+	}
+	return &chatTranscript{
+		NotebookID:     "nb-1",
+		NotebookTitle:  "Web Notes",
+		ConversationID: "conv-1234",
+		Origin:         "local",
+		ExportedAt:     at.Add(time.Hour),
+		Messages:       transcriptMessages(session, map[string]string{"src-1": "HTML Primer"}),
+	}
+}
+
+func TestChatExportFormat(t *testing.T) {
+	for in, want := range map[string]string{"stream": "md", "plain": "md", "markdown": "md", "html": "html", "json": "json"} {
+		got, err := chatExportFormat(in)
+		if err != nil || got != want {
+			t.Errorf("chatExportFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := chatExportFormat("pdf"); err == nil {
+		t.Error("chatExportFormat(pdf) succeeded, want error")
+	}
+}
+
+func TestWriteChatTranscriptMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeChatTranscript(&buf, testTranscript(), "md"); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"# Web Notes\n",
+		"Conversation conv-1234 · 2 messages · exported 2026-03-01 10:30 UTC",
+		"## You · 2026-03-01 09:30\n\nWhat is <b>bold</b>?\n",
+		"## NotebookLM · 2026-03-01 09:31\n",
+		"<summary>Thinking</summary>\n\nChecking sources\n",
+		"1. HTML Primer — “Tags mark up text.”\n2. Streamed Title\n3. (source unknown)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("markdown missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteChatTranscriptHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeChatTranscript(&buf, testTranscript(), "html"); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"<title>Web Notes</title>",
+		`<time datetime="2026-03-01T09:30:00Z">2026-03-01 09:30</time>`,
+		"What is &lt;b&gt;bold&lt;/b&gt;?",
+		"<details><summary>Thinking</summary><div>Checking sources</div></details>",
+		`<li value="1">HTML Primer <q>Tags mark up text.</q></li>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("html missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<b>bold</b>") {
+		t.Error("message content was not escaped")
+	}
+}
+
+func TestWriteChatTranscriptJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeChatTranscript(&buf, testTranscript(), "json"); err != nil {
+		t.Fatal(err)
+	}
+	var got chatTranscript
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ConversationID != "conv-1234" || got.Origin != "local" || len(got.Messages) != 2 {
+		t.Fatalf("unexpected transcript: %+v", got)
+	}
+	m := got.Messages[1]
+	if m.Content != "An HTML tag." || m.Thinking != "Checking sources" || m.Timestamp == nil {
+		t.Errorf("assistant message = %+v", m)
+	}
+	want := []transcriptCitation{
+		{Number: 1, SourceID: "src-1", Title: "HTML Primer", Text: "Tags mark up text."},
+		{Number: 2, SourceID: "src-2", Title: "Streamed Title"},
+	}
+	if len(m.Citations) != 3 || m.Citations[0] != want[0] || m.Citations[1] != want[1] {
+		t.Errorf("citations = %+v, want %+v", m.Citations, want)
+	}
+}
@@ -0,0 +1,612 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+	"golang.org/x/term"
+)
+
+// chatIndexServerTTL is how long a fetched server transcript, or a listing
+// of notebooks or conversations, is used before it is fetched again.
+const chatIndexServerTTL = time.Hour
+
+const chatIndexVersion = 1
+
+// chatIndex is a full-text index over local chat sessions and server
+// conversation transcripts, stored as JSON under the profile state
+// directory. Local sessions are re-read when their file changes; server
+// listings and transcripts when they are older than chatIndexServerTTL.
+type chatIndex struct {
+	Version       int                             `json:"version"`
+	Conversations map[string]*indexedConversation `json:"conversations"` // by "local:<file>" or "server:<notebook>/<conversation>"
+	Titles        map[string]string               `json:"titles,omitempty"`
+	Listed        map[string]time.Time            `json:"listed,omitempty"` // when each notebook's conversations were listed; "" for the notebook list
+	Terms         map[string][]chatIndexRef       `json:"terms"`
+
+	path  string
+	dirty bool
+}
+
+// indexedConversation is one conversation as stored in the index.
+type indexedConversation struct {
+	NotebookID     string        `json:"notebook_id"`
+	ConversationID string        `json:"conversation_id,omitempty"`
+	Origin         string        `json:"origin"`  // local or server
+	Updated        time.Time     `json:"updated"` // file mod time, or fetch time for server transcripts
+	Messages       []ChatMessage `json:"messages"`
+}
+
+// chatIndexRef is a posting: term frequency in one message.
+type chatIndexRef struct {
+	Conv  string `json:"c"`
+	Msg   int    `json:"m"`
+	Count int    `json:"n"`
+}
+
+// chatSearchHit is one matching message.
+type chatSearchHit struct {
+	NotebookID     string     `json:"notebook_id"`
+	NotebookTitle  string     `json:"notebook_title,omitempty"`
+	ConversationID string     `json:"conversation_id,omitempty"`
+	Origin         string     `json:"origin"`
+	Role           string     `json:"role"`
+	Timestamp      *time.Time `json:"timestamp,omitempty"`
+	Snippet        string     `json:"snippet"`
+	Command        string     `json:"command"`
+	Score          float64    `json:"score"`
+}
+
+type chatSearchOptions struct {
+	Notebook string
+	Limit    int
+	Offline  bool
+	Refresh  bool
+	JSON     bool
+}
+
+func parseChatSearchArgs(args []string) (chatSearchOptions, string, error) {
+	opts := chatSearchOptions{Limit: 20}
+	flags := flag.NewFlagSet("chat-search", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&opts.Notebook, "notebook", "", "search only this notebook")
+	flags.IntVar(&opts.Limit, "limit", opts.Limit, "maximum number of results")
+	flags.BoolVar(&opts.Offline, "offline", false, "search the existing index without fetching server history")
+	flags.BoolVar(&opts.Refresh, "refresh", false, "fetch all server listings and transcripts again")
+	flags.BoolVar(&opts.JSON, "json", false, "print results as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nlm chat-search \"term\" [-notebook id] [-limit 20] [-offline] [-refresh] [-json]\n")
+	}
+
+	flagArgs, positional := splitFlagArgs(args, "offline", "refresh", "json")
+	if err := flags.Parse(flagArgs); err != nil {
+		return opts, "", fmt.Errorf("invalid arguments")
+	}
+	query := strings.TrimSpace(strings.Join(positional, " "))
+	if len(chatIndexTokens(query)) == 0 {
+		flags.Usage()
+		return opts, "", fmt.Errorf("invalid arguments")
+	}
+	if opts.Limit < 1 {
+		return opts, "", fmt.Errorf("-limit must be at least 1")
+	}
+	return opts, query, nil
+}
+
+// chatIndexTokens splits s into lower-case words of two or more letters or
+// digits.
+func chatIndexTokens(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	tokens := words[:0]
+	for _, w := range words {
+		if utf8.RuneCountInString(w) >= 2 {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+func chatIndexPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(profileStateDir(home), "chat-search", "index.json"), nil
+}
+
+// loadChatIndex reads the index, starting afresh if it is missing or was
+// written by another version.
+func loadChatIndex(path string) *chatIndex {
+	idx := &chatIndex{path: path}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, idx); err != nil || idx.Version != chatIndexVersion {
+			idx = &chatIndex{path: path}
+		}
+	}
+	idx.Version = chatIndexVersion
+	if idx.Conversations == nil {
+		idx.Conversations = make(map[string]*indexedConversation)
+	}
+	if idx.Titles == nil {
+		idx.Titles = make(map[string]string)
+	}
+	if idx.Listed == nil {
+		idx.Listed = make(map[string]time.Time)
+	}
+	return idx
+}
+
+func (idx *chatIndex) save() error {
+	if !idx.dirty {
+		return nil
+	}
+	idx.rebuild()
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(idx.path, data, 0o600); err != nil {
+		return fmt.Errorf("write chat index: %w", err)
+	}
+	idx.dirty = false
+	return nil
+}
+
+func (idx *chatIndex) set(key string, conv *indexedConversation) {
+	idx.Conversations[key] = conv
+	idx.dirty = true
+}
+
+// indexLocal indexes the chat session files in dir, dropping sessions whose
+// files are gone.
+func (idx *chatIndex) indexLocal(dir string) {
+	seen := make(map[string]bool)
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, "chat-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		key := "local:" + name
+		seen[key] = true
+		if conv := idx.Conversations[key]; conv != nil && conv.Updated.Equal(info.ModTime()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var session ChatSession
+		if err := json.Unmarshal(data, &session); err != nil || session.NotebookID == "" {
+			continue
+		}
+		idx.set(key, &indexedConversation{
+			NotebookID:     session.NotebookID,
+			ConversationID: session.ConversationID,
+			Origin:         "local",
+			Updated:        info.ModTime(),
+			Messages:       indexedMessages(session.Messages),
+		})
+	}
+	for key, conv := range idx.Conversations {
+		if conv.Origin == "local" && !seen[key] {
+			delete(idx.Conversations, key)
+			idx.dirty = true
+		}
+	}
+}
+
+// indexedMessages keeps only the searchable part of messages.
+func indexedMessages(msgs []ChatMessage) []ChatMessage {
+	out := make([]ChatMessage, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, ChatMessage{Role: m.Role, Content: m.Content, Timestamp: m.Timestamp})
+	}
+	return out
+}
+
+// indexServer fetches the server conversations of notebook, or of every
+// notebook when it is empty. Listings and transcripts fetched within
+// chatIndexServerTTL are reused unless refresh is set, so repeated searches
+// make no requests. Transcripts of conversations or notebooks that no
+// longer exist are dropped.
+func (idx *chatIndex) indexServer(c *api.Client, notebook string, refresh bool) error {
+	fresh := func(key string) bool {
+		listed, ok := idx.Listed[key]
+		return !refresh && ok && time.Since(listed) < chatIndexServerTTL
+	}
+	notebookIDs := []string{notebook}
+	if notebook == "" && fresh("") {
+		notebookIDs = idx.listedNotebooks()
+	} else if notebook == "" || !fresh(notebook) {
+		projects, err := c.ListRecentlyViewedProjects()
+		switch {
+		case err == nil:
+			listed := make(map[string]bool)
+			for _, p := range projects {
+				listed[p.GetProjectId()] = true
+				if title := strings.TrimSpace(p.GetTitle()); title != "" && idx.Titles[p.GetProjectId()] != title {
+					idx.Titles[p.GetProjectId()] = title
+					idx.dirty = true
+				}
+			}
+			if notebook == "" {
+				notebookIDs = notebookIDs[:0]
+				for _, p := range projects {
+					notebookIDs = append(notebookIDs, p.GetProjectId())
+				}
+				idx.dropServer(func(conv *indexedConversation) bool { return listed[conv.NotebookID] })
+				for nb := range idx.Listed {
+					if nb != "" && !listed[nb] {
+						delete(idx.Listed, nb)
+					}
+				}
+				idx.Listed[""] = time.Now()
+				idx.dirty = true
+			}
+		case isAuthenticationError(err):
+			return err
+		case notebook == "":
+			notebookIDs = idx.notebooks()
+		}
+	}
+	for _, nb := range notebookIDs {
+		if fresh(nb) {
+			continue
+		}
+		convIDs, err := c.GetConversations(nb)
+		if isAuthenticationError(err) {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "nlm: list conversations for %s: %v\n", nb, err)
+			continue
+		}
+		live := make(map[string]bool)
+		for _, id := range convIDs {
+			live[id] = true
+		}
+		idx.dropServer(func(conv *indexedConversation) bool {
+			return conv.NotebookID != nb || live[conv.ConversationID]
+		})
+		idx.Listed[nb] = time.Now()
+		idx.dirty = true
+		var stale []string
+		for _, id := range convIDs {
+			conv := idx.Conversations["server:"+nb+"/"+id]
+			if refresh || conv == nil || time.Since(conv.Updated) > chatIndexServerTTL {
+				stale = append(stale, id)
+			}
+		}
+		if len(stale) > 0 {
+			fmt.Fprintf(os.Stderr, "Indexing %d conversations in %s...\n", len(stale), firstNonEmpty(idx.Titles[nb], nb))
+		}
+		for _, id := range stale {
+			session, err := fetchServerChatSession(c, nb, id)
+			if isAuthenticationError(err) {
+				return err
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "nlm: conversation %s: %v\n", id, err)
+				continue
+			}
+			idx.set("server:"+nb+"/"+id, &indexedConversation{
+				NotebookID:     nb,
+				ConversationID: id,
+				Origin:         "server",
+				Updated:        time.Now(),
+				Messages:       session.Messages,
+			})
+		}
+	}
+	return nil
+}
+
+// dropServer removes the server transcripts for which keep returns false.
+func (idx *chatIndex) dropServer(keep func(*indexedConversation) bool) {
+	for key, conv := range idx.Conversations {
+		if conv.Origin == "server" && !keep(conv) {
+			delete(idx.Conversations, key)
+			idx.dirty = true
+		}
+	}
+}
+
+// listedNotebooks returns the notebooks whose conversations have been
+// listed.
+func (idx *chatIndex) listedNotebooks() []string {
+	var ids []string
+	for nb := range idx.Listed {
+		if nb != "" {
+			ids = append(ids, nb)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// notebooks returns the notebooks that have indexed conversations.
+func (idx *chatIndex) notebooks() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, conv := range idx.Conversations {
+		if !seen[conv.NotebookID] {
+			seen[conv.NotebookID] = true
+			ids = append(ids, conv.NotebookID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// rebuild recomputes the postings. Server transcripts of conversations
+// that are also stored locally are dropped; the local copy has more detail.
+func (idx *chatIndex) rebuild() {
+	local := make(map[string]bool)
+	for _, conv := range idx.Conversations {
+		if conv.Origin == "local" && conv.ConversationID != "" {
+			local[conv.ConversationID] = true
+		}
+	}
+	keys := make([]string, 0, len(idx.Conversations))
+	for key, conv := range idx.Conversations {
+		if conv.Origin == "server" && local[conv.ConversationID] {
+			delete(idx.Conversations, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	idx.Terms = make(map[string][]chatIndexRef)
+	for _, key := range keys {
+		for i, m := range idx.Conversations[key].Messages {
+			counts := make(map[string]int)
+			for _, tok := range chatIndexTokens(m.Content) {
+				counts[tok]++
+			}
+			for tok, n := range counts {
+				idx.Terms[tok] = append(idx.Terms[tok], chatIndexRef{Conv: key, Msg: i, Count: n})
+			}
+		}
+	}
+}
+
+// search returns the messages containing every word of query, as a word or
+// word prefix, best match first.
+func (idx *chatIndex) search(query, notebookID string) []chatSearchHit {
+	if idx.Terms == nil || idx.dirty {
+		idx.rebuild()
+	}
+	type msgRef struct {
+		conv string
+		msg  int
+	}
+	docs := 0
+	for _, conv := range idx.Conversations {
+		docs += len(conv.Messages)
+	}
+	words := chatIndexTokens(query)
+	var scores map[msgRef]float64
+	for _, word := range words {
+		wordScores := make(map[msgRef]float64)
+		for tok, refs := range idx.Terms {
+			if !strings.HasPrefix(tok, word) {
+				continue
+			}
+			idf := math.Log(1 + float64(docs)/float64(len(refs)))
+			for _, r := range refs {
+				ref := msgRef{r.Conv, r.Msg}
+				wordScores[ref] = max(wordScores[ref], float64(r.Count)*idf)
+			}
+		}
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+		for ref, s := range scores {
+			if ws, ok := wordScores[ref]; ok {
+				scores[ref] = s + ws
+			} else {
+				delete(scores, ref)
+			}
+		}
+	}
+
+	var hits []chatSearchHit
+	for ref, score := range scores {
+		conv := idx.Conversations[ref.conv]
+		if conv == nil || ref.msg >= len(conv.Messages) || notebookID != "" && conv.NotebookID != notebookID {
+			continue
+		}
+		m := conv.Messages[ref.msg]
+		hit := chatSearchHit{
+			NotebookID:     conv.NotebookID,
+			NotebookTitle:  idx.Titles[conv.NotebookID],
+			ConversationID: conv.ConversationID,
+			Origin:         conv.Origin,
+			Role:           m.Role,
+			Snippet:        chatSnippet(m.Content, words),
+			Command:        strings.TrimSpace("nlm chat " + conv.NotebookID + " " + conv.ConversationID),
+			Score:          math.Round(score*1000) / 1000,
+		}
+		if !m.Timestamp.IsZero() {
+			ts := m.Timestamp
+			hit.Timestamp = &ts
+		}
+		hits = append(hits, hit)
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		ti, tj := hits[i].Timestamp, hits[j].Timestamp
+		if (ti == nil) != (tj == nil) {
+			return ti != nil
+		}
+		if ti != nil && !ti.Equal(*tj) {
+			return ti.After(*tj)
+		}
+		return hits[i].Snippet < hits[j].Snippet
+	})
+	return hits
+}
+
+// chatSnippet returns the part of text around the first word that starts
+// with one of words, on a single line.
+func chatSnippet(text string, words []string) string {
+	const before, after = 40, 80
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	lower := []rune(strings.ToLower(string(runes)))
+	at := -1
+	for i := range lower {
+		if i > 0 && (unicode.IsLetter(lower[i-1]) || unicode.IsNumber(lower[i-1])) {
+			continue
+		}
+		for _, w := range words {
+			if strings.HasPrefix(string(lower[i:]), w) {
+				at = i
+				break
+			}
+		}
+		if at >= 0 {
+			break
+		}
+	}
+	start := max(at-before, 0)
+	end := min(max(at, 0)+after, len(runes))
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// highlightWords wraps the words of s that start with one of words in
+// bold escape codes.
+func highlightWords(s string, words []string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if i > 0 && (unicode.IsLetter(runes[i-1]) || unicode.IsNumber(runes[i-1])) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsNumber(runes[j])) {
+			j++
+		}
+		word := strings.ToLower(string(runes[i:j]))
+		match := false
+		for _, w := range words {
+			if j > i && strings.HasPrefix(word, w) {
+				match = true
+			}
+		}
+		if !match {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		b.WriteString("\033[1m" + string(runes[i:j]) + "\033[0m")
+		i = j
+	}
+	return b.String()
+}
+
+// chatSearch updates the index and prints the messages matching query.
+// With a nil client, or -offline, only local sessions are re-indexed.
+func chatSearch(c *api.Client, opts chatSearchOptions, query string) error {
+	path, err := chatIndexPath()
+	if err != nil {
+		return err
+	}
+	idx := loadChatIndex(path)
+	if home, err := os.UserHomeDir(); err == nil {
+		idx.indexLocal(filepath.Join(home, ".nlm"))
+	}
+	if c != nil && !opts.Offline {
+		if err := idx.indexServer(c, opts.Notebook, opts.Refresh); err != nil {
+			return err
+		}
+	}
+	if err := idx.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "nlm: %v\n", err)
+	}
+
+	hits := idx.search(query, opts.Notebook)
+	if len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+	}
+	if opts.JSON {
+		if hits == nil {
+			hits = []chatSearchHit{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(hits)
+	}
+	var words []string
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		words = chatIndexTokens(query)
+	}
+	writeChatSearchHits(os.Stdout, hits, words)
+	return nil
+}
+
+// writeChatSearchHits prints hits, highlighting words if any are given.
+func writeChatSearchHits(w io.Writer, hits []chatSearchHit, words []string) {
+	if len(hits) == 0 {
+		fmt.Fprintln(w, "No matching messages.")
+		return
+	}
+	for i, h := range hits {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		header := firstNonEmpty(h.NotebookTitle, h.NotebookID)
+		if h.ConversationID != "" {
+			conv := h.ConversationID
+			if len(conv) > 8 {
+				conv = conv[:8]
+			}
+			header += " · conversation " + conv
+		}
+		header += " (" + h.Origin + ")"
+		if h.Timestamp != nil {
+			header += " · " + h.Timestamp.Local().Format("Jan 2 15:04")
+		}
+		speaker := "NotebookLM"
+		if h.Role == "user" {
+			speaker = "You"
+		}
+		snippet := h.Snippet
+		if len(words) > 0 {
+			snippet = highlightWords(snippet, words)
+		}
+		fmt.Fprintf(w, "%s\n  %s: %s\n  → %s\n", header, speaker, snippet, h.Command)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestSession(t *testing.T, dir, name string, s ChatSession) {
+	t.Helper()
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testChatIndex(t *testing.T) (*chatIndex, string) {
+	t.Helper()
+	dir := t.TempDir()
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	writeTestSession(t, dir, "chat-nb-1.json", ChatSession{
+		NotebookID:     "nb-1",
+		ConversationID: "conv-aaaa-1111",
+		Messages: []ChatMessage{
+			{Role: "user", Content: "When do deploys run?", Timestamp: at},
+			{Role: "assistant", Content: "Deploys run nightly at 02:00 UTC, after the backup job.", Timestamp: at.Add(time.Minute)},
+		},
+	})
+	writeTestSession(t, dir, "chat-nb-2-conv-bbb.json", ChatSession{
+		NotebookID:     "nb-2",
+		ConversationID: "conv-bbbb-2222",
+		Messages: []ChatMessage{
+			{Role: "assistant", Content: "Rollbacks are manual; a deployment is never reverted automatically.", Timestamp: at},
+		},
+	})
+	writeTestSession(t, dir, "not-a-session.json", ChatSession{NotebookID: "nb-3"})
+	idx := loadChatIndex(filepath.Join(dir, "chat-search", "index.json"))
+	idx.indexLocal(dir)
+	return idx, dir
+}
+
+func TestChatIndexTokens(t *testing.T) {
+	got := strings.Join(chatIndexTokens("Deploys run at 02:00, a Q3 café!"), " ")
+	if want := "deploys run at 02 00 q3 café"; got != want {
+		t.Errorf("tokens = %q, want %q", got, want)
+	}
+}
+
+func TestChatIndexSearch(t *testing.T) {
+	idx, _ := testChatIndex(t)
+	if len(idx.Conversations) != 2 {
+		t.Fatalf("indexed %d conversations, want 2", len(idx.Conversations))
+	}
+
+	hits := idx.search("deploy", "")
+	if len(hits) != 3 {
+		t.Fatalf("prefix search found %d hits, want 3: %+v", len(hits), hits)
+	}
+	if hits := idx.search("deploys nightly", ""); len(hits) != 1 || hits[0].Role != "assistant" {
+		t.Fatalf("AND search = %+v", hits)
+	}
+	h := idx.search("rollbacks", "")[0]
+	if h.NotebookID != "nb-2" || h.Command != "nlm chat nb-2 conv-bbbb-2222" || h.Origin != "local" {
+		t.Errorf("hit = %+v", h)
+	}
+	if hits := idx.search("rollbacks", "nb-1"); len(hits) != 0 {
+		t.Errorf("notebook filter returned %+v", hits)
+	}
+	if hits := idx.search("kubernetes", ""); len(hits) != 0 {
+		t.Errorf("unmatched search returned %+v", hits)
+	}
+}
+
+func TestChatIndexSaveAndReload(t *testing.T) {
+	idx, dir := testChatIndex(t)
+	idx.set("server:nb-1/conv-aaaa-1111", &indexedConversation{
+		NotebookID: "nb-1", ConversationID: "conv-aaaa-1111", Origin: "server", Updated: time.Now(),
+		Messages: []ChatMessage{{Role: "assistant", Content: "Deploys run nightly."}},
+	})
+	idx.set("server:nb-1/conv-cccc-3333", &indexedConversation{
+		NotebookID: "nb-1", ConversationID: "conv-cccc-3333", Origin: "server", Updated: time.Now(),
+		Messages: []ChatMessage{{Role: "user", Content: "Who owns the backup job?"}},
+	})
+	if err := idx.save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.Conversations["server:nb-1/conv-aaaa-1111"]; ok {
+		t.Error("server copy of a local conversation was kept")
+	}
+
+	again := loadChatIndex(idx.path)
+	if hits := again.search("backup", ""); len(hits) != 2 {
+		t.Fatalf("reloaded search = %+v", hits)
+	}
+	if err := os.Remove(filepath.Join(dir, "chat-nb-1.json")); err != nil {
+		t.Fatal(err)
+	}
+	again.indexLocal(dir)
+	hits := again.search("backup", "")
+	if len(hits) != 1 || hits[0].Origin != "server" {
+		t.Errorf("after removing the session, hits = %+v", hits)
+	}
+}
+
+func TestChatIndexDropServer(t *testing.T) {
+	idx, _ := testChatIndex(t)
+	for _, key := range []string{"nb-1/conv-live", "nb-1/conv-gone", "nb-3/conv-other"} {
+		nb, id, _ := strings.Cut(key, "/")
+		idx.set("server:"+key, &indexedConversation{NotebookID: nb, ConversationID: id, Origin: "server", Updated: time.Now()})
+	}
+	live := map[string]bool{"conv-live": true}
+	idx.dropServer(func(conv *indexedConversation) bool { return conv.NotebookID != "nb-1" || live[conv.ConversationID] })
+	for key, want := range map[string]bool{"server:nb-1/conv-live": true, "server:nb-1/conv-gone": false, "server:nb-3/conv-other": true} {
+		if _, ok := idx.Conversations[key]; ok != want {
+			t.Errorf("%s kept = %v, want %v", key, ok, want)
+		}
+	}
+	if n := len(idx.Conversations); n != 4 {
+		t.Errorf("%d conversations left, want the 2 local and 2 server ones", n)
+	}
+}
+
+func TestChatIndexServerListingTTL(t *testing.T) {
+	idx, _ := testChatIndex(t)
+	now := time.Now()
+	idx.Listed = map[string]time.Time{"": now, "nb-1": now, "nb-2": now.Add(-time.Minute)}
+	// Fresh listings are reused: a nil client would panic on any request.
+	if err := idx.indexServer(nil, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.indexServer(nil, "nb-2", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := idx.listedNotebooks(); strings.Join(got, ",") != "nb-1,nb-2" {
+		t.Errorf("listedNotebooks = %q", got)
+	}
+
+	idx.Listed["nb-2"] = now.Add(-2 * chatIndexServerTTL)
+	defer func() {
+		if recover() == nil {
+			t.Error("stale listing was not fetched again")
+		}
+	}()
+	idx.indexServer(nil, "", false)
+}
+
+func TestChatSnippet(t *testing.T) {
+	text := strings.Repeat("filler words here ", 5) + "the deploy\nruns nightly " + strings.Repeat("and more ", 15)
+	got := chatSnippet(text, []string{"deploy"})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "the deploy runs nightly") {
+		t.Errorf("snippet = %q", got)
+	}
+	if got := chatSnippet("Short answer.", []string{"missing"}); got != "Short answer." {
+		t.Errorf("snippet without match = %q", got)
+	}
+	if got := highlightWords("Redeploy, then deploys.", []string{"deploy"}); got != "Redeploy, then \033[1mdeploys\033[0m." {
+		t.Errorf("highlight = %q", got)
+	}
+}
+
+func TestWriteChatSearchHits(t *testing.T) {
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	var buf bytes.Buffer
+	writeChatSearchHits(&buf, []chatSearchHit{{
+		NotebookID: "nb-1", NotebookTitle: "Ops", ConversationID: "conv-aaaa-1111", Origin: "local",
+		Role: "user", Timestamp: &at, Snippet: "When do deploys run?", Command: "nlm chat nb-1 conv-aaaa-1111",
+	}}, nil)
+	want := "Ops · conversation conv-aaa (local) · Mar 1 09:30\n  You: When do deploys run?\n  → nlm chat nb-1 conv-aaaa-1111\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// nlm chat-server speaks JSON-RPC 2.0 over stdin and stdout, one message
+// per line, for editor integrations. Requests:
+//
+//	send           {"text": "..."}            ask in the current conversation
+//	cancel         {"id": <send request id>}  stop a running send (id optional)
+//	new                                       start a new conversation
+//	fork                                      new conversation with the current history
+//	switch         {"conversation": "..."}    resume another conversation
+//	conversations                             list server-side conversations
+//	history                                   messages of the current conversation
+//	save                                      write the session to disk
+//	shutdown                                  save and exit
+//
+// While a send runs, the server streams notifications carrying the send's
+// request ID: chat/thinking, chat/delta (answer text) and chat/citations.
+// The response to the send marks completion. Only one send runs at a time;
+// requests that would change the conversation fail while it does.
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcChatFailed     = -32000
+	rpcBusy           = -32001
+	rpcAuthRequired   = -32002
+	rpcCanceled       = -32800
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// chatServerState is the result of requests that change or report the
+// current conversation, and the params of the initial ready notification.
+type chatServerState struct {
+	NotebookID     string `json:"notebook_id"`
+	ConversationID string `json:"conversation_id"`
+	Messages       int    `json:"messages"`
+	Parent         string `json:"parent,omitempty"` // for fork: the conversation forked from
+}
+
+// chatTurn is the result of a completed send.
+type chatTurn struct {
+	ConversationID string         `json:"conversation_id"`
+	Answer         string         `json:"answer"`
+	Thinking       string         `json:"thinking,omitempty"`
+	Citations      []api.Citation `json:"citations,omitempty"`
+}
+
+// chatServer holds the session shared by the request loop and a running
+// send.
+type chatServer struct {
+	client *api.Client
+	// stream sends a chat request; it is c.StreamChatWithContext, replaced
+	// in tests.
+	stream func(context.Context, api.ChatRequest, func(api.ChatChunk) bool) error
+	// history fetches a conversation's server-side history; it is
+	// fetchServerChatSession, replaced in tests.
+	history func(notebookID, conversationID string) (*ChatSession, error)
+
+	wmu sync.Mutex // serializes writes to out
+	out io.Writer
+
+	mu      sync.Mutex // guards session and running
+	session *ChatSession
+	running *runningSend
+}
+
+type runningSend struct {
+	id     json.RawMessage
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newChatServer(c *api.Client, session *ChatSession, out io.Writer) *chatServer {
+	return &chatServer{
+		client: c,
+		stream: c.StreamChatWithContext,
+		history: func(notebookID, conversationID string) (*ChatSession, error) {
+			return fetchServerChatSession(c, notebookID, conversationID)
+		},
+		out:     out,
+		session: session,
+	}
+}
+
+// resume returns the session for a conversation stored locally or, failing
+// that, one with history on the server. Unknown conversations are an error.
+func (s *chatServer) resume(notebookID, conversationID string) (*ChatSession, error) {
+	if session, err := loadChatSessionForConv(notebookID, conversationID); err == nil {
+		session.ConversationID = conversationID
+		return session, nil
+	}
+	server, err := s.history(notebookID, conversationID)
+	if isAuthenticationError(err) {
+		return nil, err
+	}
+	if err != nil || len(server.Messages) == 0 {
+		return nil, fmt.Errorf("unknown conversation %s", conversationID)
+	}
+	session := newChatSession(notebookID)
+	session.ConversationID = conversationID
+	session.Messages = server.Messages
+	return session, nil
+}
+
+// runChatServer serves requests on stdin until it is closed or a shutdown
+// request arrives.
+func runChatServer(c *api.Client, notebookID, conversationID string) error {
+	s := newChatServer(c, currentChatSession(notebookID), os.Stdout)
+	if conversationID != "" {
+		session, err := s.resume(notebookID, conversationID)
+		if err != nil {
+			return err
+		}
+		s.session = session
+	}
+	if err := applySourceFilter(c, s.session); err != nil {
+		return err
+	}
+	return s.serve(os.Stdin)
+}
+
+func (s *chatServer) serve(in io.Reader) error {
+	s.notify("ready", s.state())
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	var shutdown *rpcRequest
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.reply(json.RawMessage("null"), nil, &rpcError{Code: rpcParseError, Message: "parse error: " + err.Error()})
+			continue
+		}
+		if req.Method == "" {
+			s.reply(req.ID, nil, &rpcError{Code: rpcInvalidRequest, Message: "missing method"})
+			continue
+		}
+		if req.Method == "shutdown" {
+			shutdown = &req
+			break
+		}
+		s.handle(req)
+	}
+
+	s.mu.Lock()
+	running := s.running
+	if running != nil {
+		running.cancel()
+	}
+	s.mu.Unlock()
+	if running != nil {
+		<-running.done
+	}
+	err := s.save()
+	if shutdown != nil {
+		if err != nil {
+			s.reply(shutdown.ID, nil, &rpcError{Code: rpcChatFailed, Message: err.Error()})
+		} else {
+			s.reply(shutdown.ID, s.state(), nil)
+		}
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return scanErr
+	}
+	return err
+}
+
+func (s *chatServer) handle(req rpcRequest) {
+	switch req.Method {
+	case "send":
+		var p struct {
+			Text string `json:"text"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil || strings.TrimSpace(p.Text) == "" {
+			s.reply(req.ID, nil, &rpcError{Code: rpcInvalidParams, Message: "send needs params.text"})
+			return
+		}
+		s.send(req.ID, strings.TrimSpace(p.Text))
+
+	case "cancel":
+		var p struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			s.reply(req.ID, nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()})
+			return
+		}
+		s.mu.Lock()
+		canceled := s.running != nil && (len(p.ID) == 0 || bytes.Equal(p.ID, s.running.id))
+		if canceled {
+			s.running.cancel()
+		}
+		s.mu.Unlock()
+		s.reply(req.ID, map[string]bool{"canceled": canceled}, nil)
+
+	case "new", "fork", "switch":
+		var p struct {
+			Conversation string `json:"conversation"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			s.reply(req.ID, nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()})
+			return
+		}
+		if req.Method == "switch" && p.Conversation == "" {
+			s.reply(req.ID, nil, &rpcError{Code: rpcInvalidParams, Message: "switch needs params.conversation"})
+			return
+		}
+		s.mu.Lock()
+		if s.running != nil {
+			s.mu.Unlock()
+			s.reply(req.ID, nil, &rpcError{Code: rpcBusy, Message: "a send is in progress"})
+			return
+		}
+		old := s.session
+		s.mu.Unlock()
+		if err := saveChatSession(old); err != nil {
+			fmt.Fprintf(os.Stderr, "nlm: save session: %v\n", err)
+		}
+		var next *ChatSession
+		switch req.Method {
+		case "new":
+			next = newChatSession(old.NotebookID)
+		case "fork":
+			next = forkChatSession(old)
+		case "switch":
+			var err error
+			next, err = s.resume(old.NotebookID, p.Conversation)
+			switch {
+			case isAuthenticationError(err):
+				s.reply(req.ID, nil, chatServerError(err))
+				return
+			case err != nil:
+				s.reply(req.ID, nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()})
+				return
+			}
+		}
+		s.mu.Lock()
+		s.session = next
+		s.mu.Unlock()
+		state := s.state()
+		if req.Method == "fork" {
+			state.Parent = old.ConversationID
+		}
+		s.reply(req.ID, state, nil)
+
+	case "conversations":
+		ids, err := s.client.GetConversations(s.state().NotebookID)
+		if err != nil {
+			s.reply(req.ID, nil, chatServerError(err))
+			return
+		}
+		current := s.state().ConversationID
+		type conversation struct {
+			ID      string `json:"id"`
+			Current bool   `json:"current,omitempty"`
+		}
+		convs := []conversation{}
+		for _, id := range ids {
+			convs = append(convs, conversation{ID: id, Current: id == current})
+		}
+		s.reply(req.ID, map[string]any{"conversations": convs}, nil)
+
+	case "history":
+		s.mu.Lock()
+		result := map[string]any{
+			"conversation_id": s.session.ConversationID,
+			"messages":        append([]ChatMessage{}, s.session.Messages...),
+		}
+		s.mu.Unlock()
+		s.reply(req.ID, result, nil)
+
+	case "save":
+		if err := s.save(); err != nil {
+			s.reply(req.ID, nil, &rpcError{Code: rpcChatFailed, Message: err.Error()})
+			return
+		}
+		s.reply(req.ID, s.state(), nil)
+
+	default:
+		s.reply(req.ID, nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)})
+	}
+}
+
+// send starts a chat turn in the background. Its events and response are
+// written as they arrive.
+func (s *chatServer) send(id json.RawMessage, text string) {
+	s.mu.Lock()
+	if s.running != nil {
+		s.mu.Unlock()
+		s.reply(id, nil, &rpcError{Code: rpcBusy, Message: "a send is in progress"})
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &runningSend{id: id, cancel: cancel, done: make(chan struct{})}
+	s.running = run
+	session := s.session
+	session.Messages = append(session.Messages, ChatMessage{Role: "user", Content: text, Timestamp: time.Now()})
+	req := nextChatRequest(session)
+	s.mu.Unlock()
+
+	go func() {
+		defer close(run.done)
+		defer cancel()
+
+		var answer strings.Builder
+		var thinking string
+		var citations []api.Citation
+		err := s.stream(ctx, req, func(chunk api.ChatChunk) bool {
+			switch chunk.Phase {
+			case api.ChatChunkThinking:
+				thinking = chunk.Text
+				s.notify("chat/thinking", map[string]any{"id": id, "header": strings.Trim(chunk.Header, "* "), "text": chunk.Text})
+			case api.ChatChunkAnswer:
+				answer.WriteString(chunk.Text)
+				s.notify("chat/delta", map[string]any{"id": id, "text": chunk.Text})
+			case api.ChatChunkCitations:
+				citations = chunk.Citations
+				s.notify("chat/citations", map[string]any{"id": id, "citations": chunk.Citations})
+			}
+			return ctx.Err() == nil
+		})
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		text := strings.TrimSpace(answer.String())
+		if err == nil && text == "" {
+			err = fmt.Errorf("empty response")
+		}
+
+		s.mu.Lock()
+		s.running = nil
+		if err != nil {
+			// Drop the unanswered prompt so the history stays in turns.
+			session.Messages = session.Messages[:len(session.Messages)-1]
+			s.mu.Unlock()
+			rerr := chatServerError(err)
+			rerr.Data = map[string]string{"answer": text}
+			s.reply(id, nil, rerr)
+			return
+		}
+		session.Messages = append(session.Messages, ChatMessage{
+			Role: "assistant", Content: text, Timestamp: time.Now(),
+			Thinking: thinking, Citations: citations,
+		})
+		session.UpdatedAt = time.Now()
+		s.mu.Unlock()
+		if err := s.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "nlm: save session: %v\n", err)
+		}
+		s.reply(id, chatTurn{ConversationID: req.ConversationID, Answer: text, Thinking: thinking, Citations: citations}, nil)
+	}()
+}
+
+func chatServerError(err error) *rpcError {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &rpcError{Code: rpcCanceled, Message: "request canceled"}
+	case isAuthenticationError(err):
+		return &rpcError{Code: rpcAuthRequired, Message: err.Error() + " (run 'nlm auth')"}
+	}
+	return &rpcError{Code: rpcChatFailed, Message: err.Error()}
+}
+
+func (s *chatServer) state() chatServerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return chatServerState{
+		NotebookID:     s.session.NotebookID,
+		ConversationID: s.session.ConversationID,
+		Messages:       len(s.session.Messages),
+	}
+}
+
+func (s *chatServer) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return saveChatSession(s.session)
+}
+
+// decodeParams unmarshals params into v; missing params leave v unchanged.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	return json.Unmarshal(params, v)
+}
+
+// reply writes a response. Requests without an ID are notifications and
+// get none.
+func (s *chatServer) reply(id json.RawMessage, result any, rerr *rpcError) {
+	if len(id) == 0 {
+		return
+	}
+	if result == nil && rerr == nil {
+		result = struct{}{}
+	}
+	s.write(rpcResponse{JSONRPC: "2.0", ID: id, Result: result, Error: rerr})
+}
+
+func (s *chatServer) notify(method string, params any) {
+	s.write(rpcNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *chatServer) write(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nlm: encode message: %v\n", err)
+		return
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.out.Write(append(data, '\n'))
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+type chatServerHarness struct {
+	in    *io.PipeWriter
+	lines chan map[string]any
+	done  chan error
+	srv   *chatServer
+}
+
+func startChatServer(t *testing.T, stream func(context.Context, api.ChatRequest, func(api.ChatChunk) bool) error) *chatServerHarness {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	h := &chatServerHarness{in: inW, lines: make(chan map[string]any, 100), done: make(chan error, 1)}
+	h.srv = newChatServer(nil, newChatSession("nb-1"), outW)
+	h.srv.stream = stream
+	h.srv.history = func(notebookID, conversationID string) (*ChatSession, error) {
+		switch conversationID {
+		case "conv-server":
+		case "conv-expired":
+			return nil, errors.New("unauthorized")
+		default:
+			return nil, errors.New("not found")
+		}
+		return &ChatSession{Messages: []ChatMessage{{Role: "user", Content: "Asked on the web"}}}, nil
+	}
+	go func() {
+		h.done <- h.srv.serve(inR)
+		outW.Close()
+	}()
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			var m map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+				m = map[string]any{"invalid": scanner.Text()}
+			}
+			h.lines <- m
+		}
+		close(h.lines)
+	}()
+	if m := h.next(t); m["method"] != "ready" {
+		t.Fatalf("first message = %v, want ready", m)
+	}
+	t.Cleanup(func() { inW.Close() })
+	return h
+}
+
+func (h *chatServerHarness) send(t *testing.T, line string) {
+	t.Helper()
+	if _, err := io.WriteString(h.in, line+"\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (h *chatServerHarness) next(t *testing.T) map[string]any {
+	t.Helper()
+	select {
+	case m, ok := <-h.lines:
+		if !ok {
+			t.Fatal("server closed its output")
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the server")
+	}
+	return nil
+}
+
+func errorCode(m map[string]any) float64 {
+	e, _ := m["error"].(map[string]any)
+	code, _ := e["code"].(float64)
+	return code
+}
+
+func TestChatServerSend(t *testing.T) {
+	var got api.ChatRequest
+	h := startChatServer(t, func(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error {
+		got = req
+		cb(api.ChatChunk{Phase: api.ChatChunkThinking, Header: "**Reading**", Text: "**Reading**\nsources"})
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "Deploys run "})
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "nightly [1]."})
+		cb(api.ChatChunk{Phase: api.ChatChunkCitations, Citations: []api.Citation{{Number: 1, SourceID: "src-1"}}})
+		return nil
+	})
+	h.send(t, `{"jsonrpc":"2.0","id":1,"method":"send","params":{"text":"When do deploys run?"}}`)
+
+	var methods []string
+	var resp map[string]any
+	for resp == nil {
+		m := h.next(t)
+		if method, ok := m["method"].(string); ok {
+			if params := m["params"].(map[string]any); params["id"] != 1.0 {
+				t.Errorf("%s notification id = %v, want 1", method, params["id"])
+			}
+			methods = append(methods, method)
+			continue
+		}
+		resp = m
+	}
+	if want := "chat/thinking chat/delta chat/delta chat/citations"; strings.Join(methods, " ") != want {
+		t.Errorf("notifications = %s, want %s", strings.Join(methods, " "), want)
+	}
+	result, _ := resp["result"].(map[string]any)
+	if resp["id"] != 1.0 || result["answer"] != "Deploys run nightly [1]." || result["conversation_id"] != got.ConversationID {
+		t.Fatalf("response = %v", resp)
+	}
+	if got.Prompt != "When do deploys run?" || got.ProjectID != "nb-1" || got.SeqNum != 1 {
+		t.Errorf("request = %+v", got)
+	}
+
+	h.send(t, `{"jsonrpc":"2.0","id":2,"method":"history"}`)
+	msgs := h.next(t)["result"].(map[string]any)["messages"].([]any)
+	if len(msgs) != 2 {
+		t.Fatalf("history has %d messages, want 2", len(msgs))
+	}
+
+	h.send(t, `{"jsonrpc":"2.0","id":3,"method":"shutdown"}`)
+	if m := h.next(t); m["id"] != 3.0 || m["error"] != nil {
+		t.Errorf("shutdown response = %v", m)
+	}
+	if err := <-h.done; err != nil {
+		t.Fatal(err)
+	}
+	saved, err := loadChatSession("nb-1")
+	if err != nil || len(saved.Messages) != 2 || saved.Messages[1].Citations[0].SourceID != "src-1" {
+		t.Errorf("saved session = %+v, %v", saved, err)
+	}
+}
+
+func TestChatServerCancel(t *testing.T) {
+	h := startChatServer(t, func(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error {
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "Partial"})
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h.send(t, `{"jsonrpc":"2.0","id":"a","method":"send","params":{"text":"Long question"}}`)
+	if m := h.next(t); m["method"] != "chat/delta" {
+		t.Fatalf("got %v, want chat/delta", m)
+	}
+
+	h.send(t, `{"jsonrpc":"2.0","id":"b","method":"fork"}`)
+	if m := h.next(t); errorCode(m) != rpcBusy {
+		t.Errorf("fork during send = %v, want busy error", m)
+	}
+	h.send(t, `{"jsonrpc":"2.0","id":"c","method":"cancel","params":{"id":"a"}}`)
+
+	seen := map[string]map[string]any{}
+	for len(seen) < 2 {
+		m := h.next(t)
+		seen[m["id"].(string)] = m
+	}
+	if r, _ := seen["c"]["result"].(map[string]any); r["canceled"] != true {
+		t.Errorf("cancel response = %v", seen["c"])
+	}
+	if errorCode(seen["a"]) != rpcCanceled {
+		t.Errorf("send response = %v, want canceled error", seen["a"])
+	}
+	if data := seen["a"]["error"].(map[string]any)["data"].(map[string]any); data["answer"] != "Partial" {
+		t.Errorf("partial answer = %v", data)
+	}
+
+	h.send(t, `{"jsonrpc":"2.0","id":"d","method":"history"}`)
+	if msgs := h.next(t)["result"].(map[string]any)["messages"].([]any); len(msgs) != 0 {
+		t.Errorf("canceled prompt kept in history: %v", msgs)
+	}
+}
+
+func TestChatServerRequests(t *testing.T) {
+	h := startChatServer(t, func(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error {
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "Yes."})
+		return nil
+	})
+	for _, tt := range []struct {
+		line string
+		code float64
+	}{
+		{`not json`, rpcParseError},
+		{`{"jsonrpc":"2.0","id":1}`, rpcInvalidRequest},
+		{`{"jsonrpc":"2.0","id":2,"method":"frob"}`, rpcMethodNotFound},
+		{`{"jsonrpc":"2.0","id":3,"method":"send","params":{"text":"  "}}`, rpcInvalidParams},
+		{`{"jsonrpc":"2.0","id":4,"method":"switch"}`, rpcInvalidParams},
+	} {
+		h.send(t, tt.line)
+		if m := h.next(t); errorCode(m) != tt.code {
+			t.Errorf("%s: response = %v, want error %v", tt.line, m, tt.code)
+		}
+	}
+
+	// A notification gets no response.
+	h.send(t, `{"jsonrpc":"2.0","method":"cancel"}`)
+
+	h.send(t, `{"jsonrpc":"2.0","id":5,"method":"send","params":{"text":"Is it on?"}}`)
+	for m := h.next(t); m["id"] != 5.0; m = h.next(t) {
+	}
+	before := h.srv.state().ConversationID
+	h.send(t, `{"jsonrpc":"2.0","id":6,"method":"fork"}`)
+	r := h.next(t)["result"].(map[string]any)
+	if r["parent"] != before || r["conversation_id"] == before || r["messages"] != 2.0 {
+		t.Errorf("fork = %v", r)
+	}
+	h.send(t, `{"jsonrpc":"2.0","id":7,"method":"new"}`)
+	if r := h.next(t)["result"].(map[string]any); r["messages"] != 0.0 {
+		t.Errorf("new = %v", r)
+	}
+
+	current := h.srv.state().ConversationID
+	h.send(t, `{"jsonrpc":"2.0","id":8,"method":"switch","params":{"conversation":"conv-unknown"}}`)
+	if m := h.next(t); errorCode(m) != rpcInvalidParams || h.srv.state().ConversationID != current {
+		t.Errorf("switch to an unknown conversation = %v", m)
+	}
+	h.send(t, `{"jsonrpc":"2.0","id":"auth","method":"switch","params":{"conversation":"conv-expired"}}`)
+	if m := h.next(t); errorCode(m) != rpcAuthRequired {
+		t.Errorf("switch with expired credentials = %v, want error %v", m, rpcAuthRequired)
+	}
+	h.send(t, `{"jsonrpc":"2.0","id":9,"method":"switch","params":{"conversation":"conv-server"}}`)
+	if r := h.next(t)["result"].(map[string]any); r["conversation_id"] != "conv-server" || r["messages"] != 1.0 {
+		t.Errorf("switch to a server conversation = %v", r)
+	}
+	h.send(t, `{"jsonrpc":"2.0","id":10,"method":"switch","params":{"conversation":"`+before+`"}}`)
+	if r := h.next(t)["result"].(map[string]any); r["conversation_id"] != before || r["messages"] != 2.0 {
+		t.Errorf("switch to a local conversation = %v", r)
+	}
+}
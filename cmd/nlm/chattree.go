@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// chatTreeNode is a stored conversation and the conversations forked from it.
+type chatTreeNode struct {
+	session  *ChatSession
+	children []*chatTreeNode
+}
+
+// buildChatTree arranges sessions into their fork graph. Conversations whose
+// parent is not stored locally are shown as roots. Siblings are ordered by
+// creation time.
+func buildChatTree(sessions []ChatSession) []*chatTreeNode {
+	byID := make(map[string]*chatTreeNode)
+	var nodes []*chatTreeNode
+	for i := range sessions {
+		s := &sessions[i]
+		if prev, ok := byID[s.ConversationID]; ok && s.ConversationID != "" {
+			// A legacy session file may duplicate a per-conversation one.
+			if s.UpdatedAt.After(prev.session.UpdatedAt) {
+				prev.session = s
+			}
+			continue
+		}
+		n := &chatTreeNode{session: s}
+		if s.ConversationID != "" {
+			byID[s.ConversationID] = n
+		}
+		nodes = append(nodes, n)
+	}
+	var roots []*chatTreeNode
+	for _, n := range nodes {
+		parent, ok := byID[n.session.ParentID]
+		if !ok || parent == n {
+			roots = append(roots, n)
+			continue
+		}
+		parent.children = append(parent.children, n)
+	}
+	var order func([]*chatTreeNode)
+	order = func(ns []*chatTreeNode) {
+		sort.SliceStable(ns, func(i, j int) bool {
+			return ns[i].session.CreatedAt.Before(ns[j].session.CreatedAt)
+		})
+		for _, n := range ns {
+			order(n.children)
+		}
+	}
+	order(roots)
+	return roots
+}
+
+// writeChatTree draws the fork graph, marking the current conversation
+// with an asterisk. Each fork shows the parent message it branched at, as
+// @n; passing the parent's conversation ID with that suffix as the
+// argument to "nlm chat-tree <notebook-id> <conversation-id>@n" continues
+// from the same point.
+func writeChatTree(w io.Writer, roots []*chatTreeNode, current string) {
+	var walk func(n *chatTreeNode, prefix, branch string)
+	walk = func(n *chatTreeNode, prefix, branch string) {
+		s := n.session
+		marker := " "
+		if s.ConversationID != "" && s.ConversationID == current {
+			marker = "*"
+		}
+		line := fmt.Sprintf("%s %s%s%s", marker, prefix, branch, shortConversationID(s.ConversationID))
+		if s.ParentID != "" {
+			line += fmt.Sprintf(" @%d", s.ForkPoint)
+		}
+		line += fmt.Sprintf("  %d msgs  %s", len(s.Messages), s.UpdatedAt.Format("Jan 2 15:04"))
+		if p := chatTreeLabel(s); p != "" {
+			line += "  " + strconv.Quote(p)
+		}
+		fmt.Fprintln(w, line)
+
+		childPrefix := prefix
+		switch branch {
+		case "├─ ":
+			childPrefix += "│  "
+		case "└─ ":
+			childPrefix += "   "
+		}
+		for i, c := range n.children {
+			b := "├─ "
+			if i == len(n.children)-1 {
+				b = "└─ "
+			}
+			walk(c, childPrefix, b)
+		}
+	}
+	for _, r := range roots {
+		walk(r, "", "")
+	}
+}
+
+// chatTreeLabel returns the first prompt the conversation added after its
+// fork point, shortened for display.
+func chatTreeLabel(s *ChatSession) string {
+	start := 0
+	if s.ParentID != "" {
+		start = s.ForkPoint
+	}
+	for _, m := range s.Messages[min(start, len(s.Messages)):] {
+		if m.Role != "user" {
+			continue
+		}
+		label := strings.Join(strings.Fields(m.Content), " ")
+		if r := []rune(label); len(r) > 50 {
+			label = string(r[:49]) + "…"
+		}
+		return label
+	}
+	return ""
+}
+
+func shortConversationID(id string) string {
+	if id == "" {
+		return "(none)"
+	}
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// showChatTree prints the fork graph of a notebook's local conversations.
+func showChatTree(w io.Writer, notebookID, current string) error {
+	sessions, err := listLocalChatSessions(notebookID)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Fprintf(w, "No local conversations for notebook %s.\n", notebookID)
+		return nil
+	}
+	if current == "" {
+		if latest, err := loadChatSession(notebookID); err == nil {
+			current = latest.ConversationID
+		}
+	}
+	writeChatTree(w, buildChatTree(sessions), current)
+	return nil
+}
+
+// checkoutChatNode returns the session to continue from ref, which is a
+// full or shortened conversation ID optionally followed by @n. Without @n
+// the conversation itself is resumed; with it, a new conversation is forked
+// carrying over the first n messages, so the next request sends exactly
+// that history.
+func checkoutChatNode(notebookID, ref string) (*ChatSession, error) {
+	conv, at, hasAt := strings.Cut(ref, "@")
+	if conv == "" {
+		return nil, fmt.Errorf("checkout: missing conversation ID in %q", ref)
+	}
+	session := findLocalChatSession(notebookID, conv)
+	if session == nil {
+		return nil, fmt.Errorf("checkout: no local conversation %q in notebook %s", conv, notebookID)
+	}
+	if !hasAt {
+		return session, nil
+	}
+	n, err := strconv.Atoi(at)
+	if err != nil || n < 0 || n > len(session.Messages) {
+		return nil, fmt.Errorf("checkout: invalid message number %q (conversation %s has %d messages)",
+			at, shortConversationID(session.ConversationID), len(session.Messages))
+	}
+	if n > 0 && session.Messages[n-1].Role != "assistant" {
+		return nil, fmt.Errorf("checkout: message %d is a question; check out after an answer", n)
+	}
+	return forkChatSessionAt(session, n), nil
+}
+
+// chatTree implements nlm chat-tree: it draws the notebook's fork graph,
+// or checks out a node and continues chatting from it.
+func chatTree(c *api.Client, notebookID, ref string) error {
+	if ref == "" {
+		return showChatTree(os.Stdout, notebookID, "")
+	}
+	session, err := checkoutChatNode(notebookID, ref)
+	if err != nil {
+		return err
+	}
+	if session.ParentID != "" && strings.Contains(ref, "@") {
+		fmt.Printf("Checked out %s at message %d -> %s\n",
+			shortConversationID(session.ParentID), session.ForkPoint, shortConversationID(session.ConversationID))
+	}
+	return runInteractiveChat(c, session)
+}
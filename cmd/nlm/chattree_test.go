@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// saveTestChatTree stores a root conversation with two forks, one of which
+// is forked again, and returns the root.
+func saveTestChatTree(t *testing.T) *ChatSession {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	msg := func(role, content string) ChatMessage {
+		at = at.Add(time.Minute)
+		return ChatMessage{Role: role, Content: content, Timestamp: at}
+	}
+	root := &ChatSession{
+		NotebookID:     "nb-1",
+		ConversationID: "aaaaaaaa-root",
+		Messages: []ChatMessage{
+			msg("user", "What runs nightly?"), msg("assistant", "Deploys."),
+			msg("user", "Who owns them?"), msg("assistant", "The infra team."),
+		},
+		CreatedAt: at, UpdatedAt: at,
+	}
+	early := forkChatSessionAt(root, 2)
+	early.ConversationID = "bbbbbbbb-early"
+	early.Messages = append(early.Messages, msg("user", "How long do they take?"), msg("assistant", "An hour."))
+	early.CreatedAt, early.UpdatedAt = at, at
+	late := forkChatSession(root)
+	late.ConversationID = "cccccccc-late"
+	late.CreatedAt, late.UpdatedAt = at.Add(time.Minute), at.Add(time.Minute)
+	nested := forkChatSessionAt(early, 4)
+	nested.ConversationID = "dddddddd-nested"
+	nested.Messages = append(nested.Messages, msg("user", "Can they   run\nfaster?"))
+	nested.CreatedAt, nested.UpdatedAt = at, at.Add(time.Hour)
+	for _, s := range []*ChatSession{root, early, late, nested} {
+		if err := saveChatSession(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestChatTree(t *testing.T) {
+	saveTestChatTree(t)
+	var buf bytes.Buffer
+	if err := showChatTree(&buf, "nb-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	want := `  aaaaaaaa  4 msgs  Mar 1 09:04  "What runs nightly?"
+  ├─ bbbbbbbb @2  4 msgs  Mar 1 09:06  "How long do they take?"
+* │  └─ dddddddd @4  5 msgs  Mar 1 10:07  "Can they run faster?"
+  └─ cccccccc @4  4 msgs  Mar 1 09:07
+`
+	if got := buf.String(); got != want {
+		t.Errorf("chat tree:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestChatTreeOrphanIsRoot(t *testing.T) {
+	roots := buildChatTree([]ChatSession{
+		{ConversationID: "child", ParentID: "gone", ForkPoint: 2},
+		{ConversationID: "other"},
+	})
+	if len(roots) != 2 {
+		t.Fatalf("got %d roots, want 2", len(roots))
+	}
+}
+
+func TestCheckoutChatNode(t *testing.T) {
+	root := saveTestChatTree(t)
+
+	s, err := checkoutChatNode("nb-1", "aaaaaaaa@2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ParentID != root.ConversationID || s.ForkPoint != 2 || len(s.Messages) != 2 || s.ConversationID == root.ConversationID {
+		t.Errorf("checkout @2 = %+v", s)
+	}
+	s.Messages = append(s.Messages, ChatMessage{Role: "user", Content: "Why nightly?"})
+	req := nextChatRequest(s)
+	if req.Prompt != "Why nightly?" || len(req.History) != 2 || req.SeqNum != 2 {
+		t.Errorf("request after checkout = %+v", req)
+	}
+
+	s, err = checkoutChatNode("nb-1", "bbbb")
+	if err != nil || s.ConversationID != "bbbbbbbb-early" || s.ParentID != root.ConversationID {
+		t.Errorf("checkout bbbb = %+v, %v", s, err)
+	}
+
+	for _, ref := range []string{"aaaaaaaa@1", "aaaaaaaa@9", "aaaaaaaa@x", "zzzz", "@2"} {
+		if _, err := checkoutChatNode("nb-1", ref); err == nil {
+			t.Errorf("checkoutChatNode(%q) succeeded, want error", ref)
+		}
+	}
+}
+
+func TestSaveChatSessionPerConversation(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	legacy := &ChatSession{NotebookID: "nb-1", ConversationID: "11111111-legacy"}
+	if err := os.MkdirAll(filepath.Join(home, ".nlm"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`{"notebook_id":"nb-1","conversation_id":"11111111-legacy","messages":[]}`)
+	if err := os.WriteFile(getChatSessionPath("nb-1"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	legacy.UpdatedAt = time.Now()
+	if err := saveChatSession(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(getChatSessionPath("nb-1")); !os.IsNotExist(err) {
+		t.Errorf("legacy session file still present: %v", err)
+	}
+	newer := newChatSession("nb-1")
+	newer.UpdatedAt = legacy.UpdatedAt.Add(time.Second)
+	if err := saveChatSession(newer); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadChatSession("nb-1")
+	if err != nil || got.ConversationID != newer.ConversationID {
+		t.Errorf("loadChatSession = %+v, %v; want conversation %s", got, err, newer.ConversationID)
+	}
+	if _, err := loadChatSessionForConv("nb-1", legacy.ConversationID); err != nil {
+		t.Errorf("loadChatSessionForConv(legacy): %v", err)
+	}
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// completeCommand is the hidden command invoked by the shell completion
+// scripts. It receives the words of the command line after "nlm", the last
+// being the (possibly empty) word under the cursor, and prints one candidate
+// per line as "value" or "value\tdescription".
+const completeCommand = "__complete"
+
+// completionCacheTTL bounds how long notebook, source, note and artifact IDs
+// are reused before completion asks the server again.
+const completionCacheTTL = 2 * time.Minute
+
+// completionFetchTimeout bounds a single server round trip during completion,
+// so a slow network never blocks the shell for long.
+const completionFetchTimeout = 3 * time.Second
+
+// Argument kinds understood by completion.
+const (
+	argNotebook = "notebook"
+	argSource   = "source"
+	argNote     = "note"
+	argArtifact = "artifact"
+	argShell    = "shell"
+)
+
+// completionArgs describes the positional arguments of commands that take
+// IDs. A trailing "..." repeats the last kind for all further arguments.
+var completionArgs = map[string][]string{
+	"rm":                {argNotebook},
+	"analytics":         {argNotebook},
+	"use":               {argNotebook},
+	"sources":           {argNotebook},
+	"add":               {argNotebook},
+	"rm-source":         {argNotebook, argSource},
+	"rename-source":     {argSource},
+	"refresh-source":    {argNotebook, argSource},
+	"check-source":      {argSource},
+	"discover-sources":  {argNotebook},
+	"notes":             {argNotebook},
+	"read-note":         {argNotebook, argNote},
+	"new-note":          {argNotebook},
+	"update-note":       {argNotebook, argNote},
+	"rm-note":           {argNotebook, argNote},
+	"create-audio":      {argNotebook},
+	"create-video":      {argNotebook},
+	"create-slides":     {argNotebook},
+	"audio-get":         {argNotebook},
+	"audio-rm":          {argNotebook},
+	"audio-share":       {argNotebook},
+	"audio-list":        {argNotebook},
+	"audio-download":    {argNotebook},
+	"audio-interactive": {argNotebook},
+	"video-list":        {argNotebook},
+	"video-download":    {argNotebook},
+	"get-artifact":      {argArtifact},
+	"list-artifacts":    {argNotebook},
+	"artifacts":         {argNotebook},
+	"rename-artifact":   {argArtifact},
+	"delete-artifact":   {argArtifact},
+	"generate-guide":    {argNotebook},
+	"generate-magic":    {argNotebook, argSource + "..."},
+	"generate-mindmap":  {argNotebook, argSource + "..."},
+	"generate-chat":     {argNotebook},
+	"chat":              {argNotebook},
+	"chat-list":         {argNotebook},
+	"chat-export":       {argNotebook},
+	"chat-tree":         {argNotebook},
+	"chat-server":       {argNotebook},
+	"delete-chat":       {argNotebook},
+	"chat-config":       {argNotebook},
+	"set-instructions":  {argNotebook},
+	"get-instructions":  {argNotebook},
+	"research":          {argNotebook},
+	"eval":              {argNotebook},
+	"watch":             {argNotebook},
+	"wait":              {argNotebook, argArtifact + "..."},
+	"share":             {argNotebook},
+	"share-private":     {argNotebook},
+	"completion":        {argShell},
+}
+
+func init() {
+	for _, cmd := range []string{"rephrase", "expand", "summarize", "critique", "brainstorm", "verify", "explain", "outline", "study-guide", "faq", "briefing-doc", "mindmap", "timeline", "toc"} {
+		completionArgs[cmd] = []string{argNotebook, argSource + "..."}
+	}
+}
+
+// completionItem is a single completion candidate.
+type completionItem struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// completionSource supplies the IDs offered for ID arguments.
+// An empty notebookID asks for items of every notebook known without
+// contacting the server.
+type completionSource interface {
+	Notebooks() []completionItem
+	Sources(notebookID string) []completionItem
+	Notes(notebookID string) []completionItem
+	Artifacts(notebookID string) []completionItem
+}
+
+// runComplete prints completion candidates for words to w.
+// Errors are swallowed: completion must never print noise into the shell.
+func runComplete(w io.Writer, words []string) {
+	cache := loadCompletionCache()
+	for _, item := range complete(words, cache) {
+		if item.Description != "" {
+			fmt.Fprintf(w, "%s\t%s\n", item.Value, item.Description)
+		} else {
+			fmt.Fprintln(w, item.Value)
+		}
+	}
+	cache.save()
+}
+
+// complete returns the candidates for the last word in words.
+func complete(words []string, src completionSource) []completionItem {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+	prev := words[:len(words)-1]
+
+	if strings.HasPrefix(cur, "-") {
+		return filterPrefix(flagItems(), cur)
+	}
+
+	// Find the command and its positional arguments, skipping global flags
+	// and the values of non-boolean flags.
+	var positional []string
+	for i := 0; i < len(prev); i++ {
+		w := prev[i]
+		if strings.HasPrefix(w, "-") && w != "-" {
+			if !strings.Contains(w, "=") && flagTakesValue(strings.TrimLeft(w, "-")) {
+				i++
+			}
+			continue
+		}
+		positional = append(positional, w)
+	}
+	if len(positional) == 0 {
+		return filterPrefix(commandItems(), cur)
+	}
+
+	cmd, args := positional[0], positional[1:]
+	kind := completionArgKind(cmd, len(args))
+	notebookID := ""
+	if specs := completionArgs[cmd]; len(specs) > 0 && specs[0] == argNotebook && len(args) > 0 {
+		notebookID = args[0]
+	}
+
+	var items []completionItem
+	switch kind {
+	case argNotebook:
+		items = src.Notebooks()
+	case argSource:
+		items = src.Sources(notebookID)
+	case argNote:
+		items = src.Notes(notebookID)
+	case argArtifact:
+		items = src.Artifacts(notebookID)
+	case argShell:
+		items = []completionItem{{Value: "bash"}, {Value: "zsh"}, {Value: "fish"}}
+	}
+	return filterPrefix(items, cur)
+}
+
+// completionArgKind returns the kind of the positional argument at index
+// pos of cmd, or "" if it is not an ID.
+func completionArgKind(cmd string, pos int) string {
+	specs := completionArgs[cmd]
+	if len(specs) == 0 {
+		return ""
+	}
+	if pos < len(specs) {
+		return strings.TrimSuffix(specs[pos], "...")
+	}
+	if last := specs[len(specs)-1]; strings.HasSuffix(last, "...") {
+		return strings.TrimSuffix(last, "...")
+	}
+	return ""
+}
+
+// commandItems returns every command name, without the help aliases.
+func commandItems() []completionItem {
+	var items []completionItem
+	for _, cmd := range validCommands {
+		if strings.HasPrefix(cmd, "-") {
+			continue
+		}
+		items = append(items, completionItem{Value: cmd})
+	}
+	return items
+}
+
+// flagItems returns every global flag registered in init.
+func flagItems() []completionItem {
+	var items []completionItem
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		items = append(items, completionItem{Value: "-" + f.Name, Description: f.Usage})
+	})
+	return items
+}
+
+// flagTakesValue reports whether the named global flag consumes the next word.
+func flagTakesValue(name string) bool {
+	f := flag.CommandLine.Lookup(name)
+	if f == nil {
+		return false
+	}
+	if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+		return false
+	}
+	return true
+}
+
+func filterPrefix(items []completionItem, prefix string) []completionItem {
+	var out []completionItem
+	for _, item := range items {
+		if strings.HasPrefix(item.Value, prefix) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// completionCache is a completionSource backed by a JSON file under ~/.nlm.
+// Entries older than completionCacheTTL are refreshed from the server when
+// credentials are available; otherwise stale entries are still offered.
+type completionCache struct {
+	path   string
+	client *api.Client
+	dirty  bool
+
+	NotebooksAt   time.Time                          `json:"notebooks_at"`
+	NotebookItems []completionItem                   `json:"notebooks"`
+	Projects      map[string]*completionProjectCache `json:"projects"`
+}
+
+// completionProjectCache holds the per-notebook IDs.
+type completionProjectCache struct {
+	SourcesAt   time.Time        `json:"sources_at"`
+	Sources     []completionItem `json:"sources"`
+	NotesAt     time.Time        `json:"notes_at"`
+	Notes       []completionItem `json:"notes"`
+	ArtifactsAt time.Time        `json:"artifacts_at"`
+	Artifacts   []completionItem `json:"artifacts"`
+}
+
+func completionCachePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".nlm", "completion-cache.json")
+}
+
+func loadCompletionCache() *completionCache {
+	c := &completionCache{path: completionCachePath()}
+	if c.path != "" {
+		if data, err := os.ReadFile(c.path); err == nil {
+			json.Unmarshal(data, c)
+		}
+	}
+	if c.Projects == nil {
+		c.Projects = make(map[string]*completionProjectCache)
+	}
+	token, cookie := os.Getenv("NLM_AUTH_TOKEN"), os.Getenv("NLM_COOKIES")
+	if token != "" && cookie != "" {
+		c.client = api.New(token, cookie)
+		if v := os.Getenv("NLM_AUTHUSER"); v != "" {
+			c.client.SetAuthUser(v)
+		}
+	}
+	return c
+}
+
+func (c *completionCache) save() {
+	if !c.dirty || c.path == "" {
+		return
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(c.path), 0700)
+	os.WriteFile(c.path, data, 0600)
+}
+
+func (c *completionCache) project(notebookID string) *completionProjectCache {
+	p := c.Projects[notebookID]
+	if p == nil {
+		p = &completionProjectCache{}
+		c.Projects[notebookID] = p
+	}
+	return p
+}
+
+// stale reports whether data fetched at t should be refreshed.
+func (c *completionCache) stale(t time.Time) bool {
+	return c.client != nil && time.Since(t) > completionCacheTTL
+}
+
+func (c *completionCache) Notebooks() []completionItem {
+	if c.stale(c.NotebooksAt) {
+		if nbs, ok := fetchWithTimeout(c.client.ListRecentlyViewedProjects); ok {
+			c.NotebookItems = nil
+			for _, nb := range nbs {
+				c.NotebookItems = append(c.NotebookItems, completionItem{
+					Value:       nb.GetProjectId(),
+					Description: strings.TrimSpace(strings.TrimSpace(nb.GetEmoji()) + " " + nb.GetTitle()),
+				})
+			}
+			c.NotebooksAt = time.Now()
+			c.dirty = true
+		}
+	}
+	return c.NotebookItems
+}
+
+func (c *completionCache) Sources(notebookID string) []completionItem {
+	if notebookID == "" {
+		return c.all(func(p *completionProjectCache) []completionItem { return p.Sources })
+	}
+	p := c.project(notebookID)
+	if c.stale(p.SourcesAt) {
+		fetch := func() (*api.Notebook, error) { return c.client.GetProject(notebookID) }
+		if nb, ok := fetchWithTimeout(fetch); ok {
+			p.Sources = nil
+			for _, src := range nb.GetSources() {
+				p.Sources = append(p.Sources, completionItem{
+					Value:       src.GetSourceId().GetSourceId(),
+					Description: strings.TrimSpace(src.GetTitle()),
+				})
+			}
+			p.SourcesAt = time.Now()
+			c.dirty = true
+		}
+	}
+	return p.Sources
+}
+
+func (c *completionCache) Notes(notebookID string) []completionItem {
+	if notebookID == "" {
+		return c.all(func(p *completionProjectCache) []completionItem { return p.Notes })
+	}
+	p := c.project(notebookID)
+	if c.stale(p.NotesAt) {
+		fetch := func() ([]*api.Note, error) { return c.client.GetNotes(notebookID) }
+		if notes, ok := fetchWithTimeout(fetch); ok {
+			p.Notes = nil
+			for _, n := range notes {
+				p.Notes = append(p.Notes, completionItem{Value: n.GetNoteId(), Description: n.GetTitle()})
+			}
+			p.NotesAt = time.Now()
+			c.dirty = true
+		}
+	}
+	return p.Notes
+}
+
+func (c *completionCache) Artifacts(notebookID string) []completionItem {
+	if notebookID == "" {
+		return c.all(func(p *completionProjectCache) []completionItem { return p.Artifacts })
+	}
+	p := c.project(notebookID)
+	if c.stale(p.ArtifactsAt) {
+		fetch := func() ([]*pb.Artifact, error) { return c.client.ListArtifacts(notebookID) }
+		if artifacts, ok := fetchWithTimeout(fetch); ok {
+			p.Artifacts = nil
+			for _, a := range artifacts {
+				desc := strings.TrimPrefix(a.GetType().String(), "ARTIFACT_TYPE_") + " " +
+					strings.TrimPrefix(a.GetState().String(), "ARTIFACT_STATE_")
+				p.Artifacts = append(p.Artifacts, completionItem{Value: a.GetArtifactId(), Description: desc})
+			}
+			p.ArtifactsAt = time.Now()
+			c.dirty = true
+		}
+	}
+	return p.Artifacts
+}
+
+// all returns the cached items of every notebook, without fetching.
+func (c *completionCache) all(items func(*completionProjectCache) []completionItem) []completionItem {
+	ids := make([]string, 0, len(c.Projects))
+	for id := range c.Projects {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	var out []completionItem
+	for _, id := range ids {
+		out = append(out, items(c.Projects[id])...)
+	}
+	return out
+}
+
+// fetchWithTimeout runs fn, giving up after completionFetchTimeout.
+func fetchWithTimeout[T any](fn func() (T, error)) (T, bool) {
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err == nil
+	case <-time.After(completionFetchTimeout):
+		var zero T
+		return zero, false
+	}
+}
+
+// writeCompletionScript writes the completion script for shell to w.
+func writeCompletionScript(w io.Writer, shell string) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return fmt.Errorf("unsupported shell %q (use bash, zsh or fish)", shell)
+	}
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+const bashCompletion = `# bash completion for nlm
+# Install: source <(nlm completion bash)
+_nlm() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    local candidates
+    candidates=$(nlm __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1)
+    COMPREPLY=($(compgen -W "$candidates" -- "$cur"))
+}
+complete -o default -F _nlm nlm
+`
+
+const zshCompletion = `#compdef nlm
+# zsh completion for nlm
+# Install: source <(nlm completion zsh)
+_nlm() {
+    local -a candidates
+    local line value desc
+    for line in "${(@f)$(nlm __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -z $line ]] && continue
+        value=${line%%$'\t'*}
+        value=${value//:/\\:}
+        if [[ $line == *$'\t'* ]]; then
+            desc=${line#*$'\t'}
+            candidates+=("$value:$desc")
+        else
+            candidates+=("$value")
+        fi
+    done
+    _describe 'nlm' candidates
+}
+compdef _nlm nlm
+`
+
+const fishCompletion = `# fish completion for nlm
+# Install: nlm completion fish > ~/.config/fish/completions/nlm.fish
+function __nlm_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    nlm __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c nlm -f -a '(__nlm_complete)'
+`
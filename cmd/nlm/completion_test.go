@@ -0,0 +1,74 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+type fakeCompletionSource struct{}
+
+func (fakeCompletionSource) Notebooks() []completionItem {
+	return []completionItem{{Value: "nb-1", Description: "First"}, {Value: "nb-2", Description: "Second"}}
+}
+
+func (fakeCompletionSource) Sources(nb string) []completionItem {
+	if nb != "nb-1" {
+		return nil
+	}
+	return []completionItem{{Value: "src-a", Description: "Paper"}, {Value: "src-b", Description: "Notes"}}
+}
+
+func (fakeCompletionSource) Notes(nb string) []completionItem {
+	if nb != "nb-1" {
+		return nil
+	}
+	return []completionItem{{Value: "note-1", Description: "Summary"}}
+}
+
+func (fakeCompletionSource) Artifacts(nb string) []completionItem {
+	return []completionItem{{Value: "art-1", Description: "AUDIO_OVERVIEW READY"}}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+	}{
+		{"command prefix", []string{"rm-"}, []string{"rm-source", "rm-note"}},
+		{"notebook", []string{"sources", ""}, []string{"nb-1", "nb-2"}},
+		{"notebook prefix", []string{"chat", "nb-2"}, []string{"nb-2"}},
+		{"after flags", []string{"-debug", "-auth", "tok", "notes", ""}, []string{"nb-1", "nb-2"}},
+		{"source", []string{"rm-source", "nb-1", ""}, []string{"src-a", "src-b"}},
+		{"repeated sources", []string{"summarize", "nb-1", "src-a", "src-"}, []string{"src-a", "src-b"}},
+		{"note", []string{"read-note", "nb-1", "n"}, []string{"note-1"}},
+		{"artifact", []string{"get-artifact", ""}, []string{"art-1"}},
+		{"too many args", []string{"read-note", "nb-1", "note-1", ""}, nil},
+		{"no ids", []string{"create", ""}, nil},
+		{"shell", []string{"completion", "f"}, []string{"fish"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, item := range complete(tt.words, fakeCompletionSource{}) {
+				got = append(got, item.Value)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("complete(%q) = %q, want %q", tt.words, got, tt.want)
+			}
+		})
+	}
+
+	var commands []string
+	for _, item := range complete(nil, fakeCompletionSource{}) {
+		commands = append(commands, item.Value)
+	}
+	for _, want := range []string{"list", "sources", "completion"} {
+		if !slices.Contains(commands, want) {
+			t.Errorf("complete(nil) missing command %q", want)
+		}
+	}
+	if slices.Contains(commands, "--help") {
+		t.Errorf("complete(nil) includes help alias --help")
+	}
+}
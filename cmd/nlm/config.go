@@ -0,0 +1,437 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// Config is the contents of ~/.config/nlm/config.toml:
+//
+//	default_profile = "work"
+//
+//	[profiles.work]
+//	account = "me@example.com"
+//	authuser = "1"
+//	browser_profile = "Profile 2"
+//	default_notebook = "6f2c..."
+//	format = "plain"
+//	chat_goal = "custom"
+//	chat_prompt = "Answer as a patent attorney."
+//	response_length = "shorter"
+//	timeout = "90s"
+//	webhook_url = "https://hooks.example.com/nlm"
+//	webhook_secret = "s3cret"
+//
+//	[tags]
+//	research = ["6f2c...", "a91d..."]
+//
+// A tag may also name a single notebook as a lone string, and authuser
+// may be a bare integer.
+type Config struct {
+	DefaultProfile string              `toml:"default_profile"`
+	Profiles       map[string]*Profile `toml:"profiles"`
+	Tags           map[string]tagList  `toml:"tags"` // notebook IDs by tag, for ask-all -tag
+}
+
+// Profile holds the per-profile defaults.
+// Empty fields leave the built-in defaults in place.
+type Profile struct {
+	Account         string       `toml:"account"`          // Google account email; used as authuser when AuthUser is empty
+	AuthUser        accountIndex `toml:"authuser"`         // Google account index for multi-account browsers
+	BrowserProfile  string       `toml:"browser_profile"`  // Chrome profile used by nlm auth
+	DefaultNotebook string       `toml:"default_notebook"` // Notebook used when a command omits its notebook ID
+	Format          string       `toml:"format"`           // Output format for generate-chat
+	ChatGoal        string       `toml:"chat_goal"`        // "default" or "custom"
+	ChatPrompt      string       `toml:"chat_prompt"`      // Custom chat goal prompt
+	ResponseLength  string       `toml:"response_length"`  // "default", "longer" or "shorter"
+	Timeout         string       `toml:"timeout"`          // HTTP request timeout, as a Go duration
+	WebhookURL      string       `toml:"webhook_url"`      // URL notified when long-running generation finishes
+	WebhookSecret   string       `toml:"webhook_secret"`   // HMAC key for webhook signatures
+}
+
+// Setting sources, in order of decreasing precedence.
+const (
+	sourceFlag    = "flag"
+	sourceEnv     = "env"
+	sourceProfile = "profile"
+	sourceDefault = "default"
+)
+
+// profileSetting describes how one Profile field is resolved.
+// Each setting is taken from the first of its flag, its environment
+// variable and the active profile that is set.
+type profileSetting struct {
+	name string
+	flag string // global flag name, if any
+	env  string // environment variable, if any
+	get  func(*Profile) string
+}
+
+var profileSettings = []profileSetting{
+	{"account", "", "", func(p *Profile) string { return p.Account }},
+	{"authuser", "", "NLM_AUTHUSER", func(p *Profile) string { return firstNonEmpty(string(p.AuthUser), p.Account) }},
+	{"browser_profile", "profile", "NLM_BROWSER_PROFILE", func(p *Profile) string { return p.BrowserProfile }},
+	{"default_notebook", "", "NLM_NOTEBOOK", func(p *Profile) string { return p.DefaultNotebook }},
+	{"format", "format", "", func(p *Profile) string { return p.Format }},
+	{"chat_goal", "", "", func(p *Profile) string { return p.ChatGoal }},
+	{"chat_prompt", "", "", func(p *Profile) string { return p.ChatPrompt }},
+	{"response_length", "", "", func(p *Profile) string { return p.ResponseLength }},
+	{"timeout", "", "NLM_TIMEOUT", func(p *Profile) string { return p.Timeout }},
+	{"webhook_url", "", "NLM_WEBHOOK_URL", func(p *Profile) string { return p.WebhookURL }},
+	{"webhook_secret", "", "NLM_WEBHOOK_SECRET", func(p *Profile) string { return p.WebhookSecret }},
+}
+
+// resolvedSetting is the effective value of a setting and where it came from.
+type resolvedSetting struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Active configuration, set by loadConfig.
+var (
+	configFlag          string // -config
+	configFile          string // path of the config file, if one was read
+	activeProfileName   string
+	activeProfileSource string // how the active profile was selected
+	activeProfile       = &Profile{}
+	activeSettings      []resolvedSetting
+	notebookTags        map[string][]string
+	defaultNotebook     string
+	requestTimeout      time.Duration
+)
+
+// defaultConfigPath returns $NLM_CONFIG, or config.toml in
+// $XDG_CONFIG_HOME/nlm or ~/.config/nlm.
+func defaultConfigPath() string {
+	if p := os.Getenv("NLM_CONFIG"); p != "" {
+		return p
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "nlm", "config.toml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "nlm", "config.toml")
+}
+
+// readConfig reads the config file at path. A missing file yields an empty
+// config.
+func readConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: make(map[string]*Profile), Tags: make(map[string]tagList)}
+	if path == "" {
+		return cfg, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := parseConfig(f, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// parseConfig decodes the TOML config described on Config into cfg.
+// Keys the config does not define are errors.
+func parseConfig(r io.Reader, cfg *Config) error {
+	md, err := toml.NewDecoder(r).Decode(cfg)
+	var perr toml.ParseError
+	if errors.As(err, &perr) {
+		return fmt.Errorf("line %d: %s", perr.Position.Line, perr.Message)
+	}
+	if err != nil {
+		return err
+	}
+	if keys := md.Undecoded(); len(keys) > 0 {
+		return fmt.Errorf("unknown key %q", keys[0].String())
+	}
+	return nil
+}
+
+// accountIndex is an authuser setting, which may be written as a string
+// or as a bare integer such as authuser = 1.
+type accountIndex string
+
+func (a *accountIndex) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		*a = accountIndex(v)
+	case int64:
+		*a = accountIndex(strconv.FormatInt(v, 10))
+	default:
+		return fmt.Errorf("authuser: want a string or an integer, got %T", v)
+	}
+	return nil
+}
+
+// tagList is the notebook IDs of a tag: an array of strings, or a lone
+// string for a single notebook.
+type tagList []string
+
+func (l *tagList) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		*l = tagList{v}
+		return nil
+	case []any:
+		ids := make(tagList, 0, len(v))
+		for _, e := range v {
+			id, ok := e.(string)
+			if !ok {
+				return fmt.Errorf("array elements must be strings, got %T", e)
+			}
+			ids = append(ids, id)
+		}
+		*l = ids
+		return nil
+	}
+	return fmt.Errorf("want a string or an array of strings, got %T", v)
+}
+
+// selectProfile picks the active profile: the -profile flag when it names
+// a configured profile, then $NLM_PROFILE, then default_profile. A -profile
+// value that names no configured profile keeps its older meaning of a
+// Chrome profile name; fromFlag reports which meaning applied.
+func selectProfile(cfg *Config, profileFlag string, profileFlagSet bool) (name, source string, fromFlag bool, err error) {
+	if profileFlagSet && cfg.Profiles[profileFlag] != nil {
+		return profileFlag, "-profile", true, nil
+	}
+	if name := os.Getenv("NLM_PROFILE"); name != "" {
+		if cfg.Profiles[name] == nil {
+			return "", "", false, fmt.Errorf("NLM_PROFILE: no profile %q in %s", name, configFile)
+		}
+		return name, "NLM_PROFILE", false, nil
+	}
+	if name := cfg.DefaultProfile; name != "" {
+		if cfg.Profiles[name] == nil {
+			return "", "", false, fmt.Errorf("default_profile: no profile %q in %s", name, configFile)
+		}
+		return name, "default_profile", false, nil
+	}
+	return "", "", false, nil
+}
+
+// resolveSettings resolves every profile setting against the explicitly set
+// flags and the environment.
+func resolveSettings(p *Profile, setFlags map[string]string, lookupEnv func(string) (string, bool)) []resolvedSetting {
+	var out []resolvedSetting
+	for _, s := range profileSettings {
+		r := resolvedSetting{Name: s.name, Source: sourceDefault}
+		if v, ok := setFlags[s.flag]; ok && s.flag != "" {
+			r.Value, r.Source = v, sourceFlag
+		} else if v, ok := lookupEnv(s.env); ok && s.env != "" && v != "" {
+			r.Value, r.Source = v, sourceEnv
+		} else if v := s.get(p); v != "" {
+			r.Value, r.Source = v, sourceProfile
+		} else if f := flag.Lookup(s.flag); f != nil && s.flag != "" {
+			r.Value = f.DefValue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func settingValue(settings []resolvedSetting, name string) string {
+	for _, s := range settings {
+		if s.Name == name {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// loadConfig reads the config file, selects the active profile and applies
+// its settings to the global flags. Precedence, highest first: command-line
+// flags, environment variables, the active profile, ~/.nlm/env, built-in
+// defaults. It must run after flag.Parse and before loadStoredEnv.
+func loadConfig() error {
+	setFlags := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = f.Value.String() })
+
+	configFile = firstNonEmpty(configFlag, defaultConfigPath())
+	cfg, err := readConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	name, source, fromFlag, err := selectProfile(cfg, chromeProfile, setFlags["profile"] != "")
+	if err != nil {
+		return err
+	}
+	if fromFlag {
+		// -profile named a config profile, not a Chrome profile.
+		delete(setFlags, "profile")
+		chromeProfile = os.Getenv("NLM_BROWSER_PROFILE")
+	}
+	activeProfileName, activeProfileSource = name, source
+	notebookTags = make(map[string][]string, len(cfg.Tags))
+	for tag, ids := range cfg.Tags {
+		notebookTags[tag] = ids
+	}
+	if name != "" {
+		activeProfile = cfg.Profiles[name]
+	}
+
+	activeSettings = resolveSettings(activeProfile, setFlags, os.LookupEnv)
+	for _, s := range activeSettings {
+		if s.Source != sourceProfile {
+			continue
+		}
+		switch s.Name {
+		case "authuser":
+			// Set before loadStoredEnv so the profile wins over ~/.nlm/env.
+			os.Setenv("NLM_AUTHUSER", s.Value)
+		case "browser_profile":
+			chromeProfile = s.Value
+		case "format":
+			outputFormat = s.Value
+		}
+	}
+	defaultNotebook = settingValue(activeSettings, "default_notebook")
+	if t := settingValue(activeSettings, "timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return fmt.Errorf("timeout: %w", err)
+		}
+		requestTimeout = d
+	}
+	// Receivers can only trust deliveries they can verify.
+	if settingValue(activeSettings, "webhook_url") != "" && settingValue(activeSettings, "webhook_secret") == "" {
+		return fmt.Errorf("webhook_url is set without webhook_secret; webhooks must be signed")
+	}
+	return nil
+}
+
+// applyProfileChatConfig sets the notebook's chat goal and response length
+// from the active profile. Chat settings are stored on the notebook and
+// shared with everyone using it, so this only runs on an explicit
+// "chat-config <id> -from-profile"; a setting the profile leaves unset
+// keeps the notebook's current value.
+func applyProfileChatConfig(c *api.Client, notebookID string) error {
+	goalName := settingValue(activeSettings, "chat_goal")
+	lengthName := settingValue(activeSettings, "response_length")
+	if goalName == "" && lengthName == "" {
+		return fmt.Errorf("profile %q sets neither chat_goal nor response_length", activeProfileName)
+	}
+
+	var current *pb.ChatbotConfig
+	if goalName == "" || lengthName == "" {
+		project, err := c.GetProject(notebookID)
+		if err != nil {
+			return fmt.Errorf("get project: %w", err)
+		}
+		current = project.GetChatbotConfig()
+	}
+
+	goal := api.ChatGoal(current.GetGoal().GetGoal())
+	prompt := current.GetGoal().GetCustomPrompt()
+	switch goalName {
+	case "":
+	case "default":
+		goal, prompt = api.ChatGoalDefault, ""
+	case "custom":
+		prompt = settingValue(activeSettings, "chat_prompt")
+		if prompt == "" {
+			return fmt.Errorf("profile %q: chat_goal custom requires chat_prompt", activeProfileName)
+		}
+		goal = api.ChatGoalCustom
+	default:
+		return fmt.Errorf("profile %q: unknown chat_goal %q (use 'default' or 'custom')", activeProfileName, goalName)
+	}
+
+	length := api.ResponseLength(current.GetResponseLength().GetValue())
+	switch lengthName {
+	case "":
+	case "default":
+		length = api.ResponseLengthDefault
+	case "longer":
+		length = api.ResponseLengthLonger
+	case "shorter":
+		length = api.ResponseLengthShorter
+	default:
+		return fmt.Errorf("profile %q: unknown response_length %q (use 'default', 'longer', or 'shorter')", activeProfileName, lengthName)
+	}
+
+	if err := c.SetChatConfig(notebookID, goal, prompt, length); err != nil {
+		return fmt.Errorf("apply profile chat config: %w", err)
+	}
+	return nil
+}
+
+// showConfig prints the config file, the active profile and the effective
+// value and source of each setting.
+func showConfig(w io.Writer, args []string) error {
+	if len(args) > 0 && args[0] == "profiles" {
+		cfg, err := readConfig(configFile)
+		if err != nil {
+			return fmt.Errorf("read config: %w", err)
+		}
+		names := make([]string, 0, len(cfg.Profiles))
+		for name := range cfg.Profiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			marker := " "
+			if name == activeProfileName {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%s %s\n", marker, name)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(w, "Config:  %s\n", configFile)
+	if activeProfileName != "" {
+		fmt.Fprintf(w, "Profile: %s (from %s)\n\n", activeProfileName, activeProfileSource)
+	} else {
+		fmt.Fprintf(w, "Profile: (none)\n\n")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, s := range activeSettings {
+		value := s.Value
+		switch {
+		case value == "":
+			value = "-"
+		case s.Name == "webhook_secret":
+			value = "(set)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, value, s.Source)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(notebookTags) > 0 {
+		tags := make([]string, 0, len(notebookTags))
+		for tag := range notebookTags {
+			tags = append(tags, tag)
+		}
+		slices.Sort(tags)
+		fmt.Fprintf(w, "\nTags:\n")
+		for _, tag := range tags {
+			fmt.Fprintf(w, "  %s: %s\n", tag, strings.Join(notebookTags[tag], ", "))
+		}
+	}
+	return nil
+}
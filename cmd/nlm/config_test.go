@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testConfig = `# nlm config
+default_profile = "work"
+
+[profiles.work]
+account = "me@example.com"
+browser_profile = "Profile 2" # trailing comment
+format = 'plain'
+timeout = "90s"
+
+[profiles."side project"]
+authuser = 2
+default_notebook = "nb-123"
+response_length = "shorter"
+
+[tags]
+research = ["nb-1", 'nb-2',] # trailing comma
+solo = "nb-3"
+`
+
+func TestParseConfig(t *testing.T) {
+	cfg := &Config{Profiles: make(map[string]*Profile)}
+	if err := parseConfig(strings.NewReader(testConfig), cfg); err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+	if cfg.DefaultProfile != "work" {
+		t.Errorf("DefaultProfile = %q, want work", cfg.DefaultProfile)
+	}
+	work := cfg.Profiles["work"]
+	if work == nil {
+		t.Fatal("missing profile work")
+	}
+	if work.BrowserProfile != "Profile 2" || work.Format != "plain" || work.Timeout != "90s" {
+		t.Errorf("work = %+v", work)
+	}
+	side := cfg.Profiles["side project"]
+	if side == nil {
+		t.Fatal("missing profile \"side project\"")
+	}
+	if side.AuthUser != "2" || side.DefaultNotebook != "nb-123" || side.ResponseLength != "shorter" {
+		t.Errorf("side project = %+v", side)
+	}
+	if got := strings.Join(cfg.Tags["research"], ","); got != "nb-1,nb-2" {
+		t.Errorf("tags research = %q, want nb-1,nb-2", got)
+	}
+	if got := strings.Join(cfg.Tags["solo"], ","); got != "nb-3" {
+		t.Errorf("tags solo = %q, want nb-3", got)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"format = \"plain\"", `unknown key "format"`},
+		{"[accounts.x]", `unknown key "accounts.x"`},
+		{"[profiles.a]\ncolour = \"red\"", `unknown key "profiles.a.colour"`},
+		{"[profiles.a]\nformat = plain", "line 2: expected value"},
+		{"[profiles.a]\n[profiles.a]", "line 2: Key 'profiles.a' has already been defined"},
+		{"[profiles.a]\nformat", "line 2: unexpected EOF"},
+		{"[profiles.a]\nauthuser = true", "line 2: authuser: want a string or an integer"},
+		{"[tags]\nx = [1, 2]", "line 2: array elements must be strings"},
+		{"[tags]\nx = [\"a\" \"b\"]", "line 2: expected a comma"},
+		{"[tags]\nx = [\"a", "line 2: unexpected EOF"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Profiles: make(map[string]*Profile)}
+		err := parseConfig(strings.NewReader(tt.in), cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseConfig(%q) = %v, want error containing %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestResolveSettings(t *testing.T) {
+	p := &Profile{Account: "me@example.com", BrowserProfile: "Work", Format: "plain", Timeout: "90s"}
+	flags := map[string]string{"format": "stream"}
+	env := map[string]string{"NLM_BROWSER_PROFILE": "Personal", "NLM_TIMEOUT": ""}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	got := make(map[string]resolvedSetting)
+	for _, s := range resolveSettings(p, flags, lookupEnv) {
+		got[s.Name] = s
+	}
+	want := map[string]resolvedSetting{
+		"account":          {"account", "me@example.com", sourceProfile},
+		"authuser":         {"authuser", "me@example.com", sourceProfile},
+		"browser_profile":  {"browser_profile", "Personal", sourceEnv},
+		"format":           {"format", "stream", sourceFlag},
+		"timeout":          {"timeout", "90s", sourceProfile},
+		"default_notebook": {"default_notebook", "", sourceDefault},
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s = %+v, want %+v", name, got[name], w)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// currentNotebook is the notebook selected with nlm use, stored per profile
+// in current.json next to the profile's credentials.
+type currentNotebook struct {
+	NotebookID string    `json:"notebook_id"`
+	Title      string    `json:"title,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// notebookArgRange is the number of arguments a notebook-scoped command
+// accepts, counting the notebook ID. max < 0 means unbounded.
+type notebookArgRange struct{ min, max int }
+
+// notebookScopedCommands lists the commands whose first argument is a
+// notebook ID that may be omitted in favor of the current notebook.
+// Destructive commands (rm, rm-source, rm-note, audio-rm, delete-chat) are
+// deliberately absent: they always take an explicit notebook ID.
+var notebookScopedCommands = map[string]notebookArgRange{
+	"analytics":        {1, 1},
+	"sources":          {1, 1},
+	"add":              {2, 2},
+	"refresh-source":   {2, 2},
+	"discover-sources": {2, 2},
+	"notes":            {1, 1},
+	"read-note":        {2, 2},
+	"new-note":         {2, 3},
+	"update-note":      {4, 4},
+	"create-audio":     {2, 2},
+	"create-video":     {2, 2},
+	"create-slides":    {2, -1},
+	"audio-get":        {1, 1},
+	"audio-share":      {1, 1},
+	"audio-list":       {1, 1},
+	"audio-download":   {1, 2},
+	"video-list":       {1, 1},
+	"video-download":   {1, 2},
+	"list-artifacts":   {1, 1},
+	"artifacts":        {1, 1},
+	"generate-guide":   {1, 1},
+	"generate-magic":   {2, -1},
+	"generate-mindmap": {2, -1},
+	"generate-chat":    {2, -1},
+	"chat":             {1, -1},
+	"chat-export":      {1, 2},
+	"chat-tree":        {1, 2},
+	"chat-server":      {1, 2},
+	"chat-config":      {2, -1},
+	"set-instructions": {2, -1},
+	"get-instructions": {1, 1},
+	"research":         {2, -1},
+	"eval":             {2, -1},
+	"watch":            {1, -1},
+	"wait":             {1, -1},
+	"share":            {1, 1},
+	"share-private":    {1, 1},
+}
+
+// transformCommands run a transformation over some of a notebook's sources.
+var transformCommands = []string{"rephrase", "expand", "summarize", "critique", "brainstorm", "verify", "explain", "outline", "study-guide", "faq", "briefing-doc", "mindmap", "timeline", "toc"}
+
+func init() {
+	for _, cmd := range transformCommands {
+		notebookScopedCommands[cmd] = notebookArgRange{2, -1}
+	}
+}
+
+// profileStateDir returns the directory holding per-profile state:
+// ~/.nlm/profiles/<name> for the active config profile, else ~/.nlm.
+func profileStateDir(home string) string {
+	if activeProfileName != "" {
+		return filepath.Join(home, ".nlm", "profiles", activeProfileName)
+	}
+	return filepath.Join(home, ".nlm")
+}
+
+func currentNotebookPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(profileStateDir(home), "current.json"), nil
+}
+
+// readCurrentNotebook returns the notebook stored by nlm use, or nil.
+func readCurrentNotebook() (*currentNotebook, error) {
+	path, err := currentNotebookPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cur currentNotebook
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if cur.NotebookID == "" {
+		return nil, nil
+	}
+	return &cur, nil
+}
+
+// resolveCurrentNotebook returns the notebook used when a command omits its
+// notebook ID, and where it came from. NLM_NOTEBOOK takes precedence over
+// nlm use, which takes precedence over the profile's default_notebook.
+func resolveCurrentNotebook() (cur *currentNotebook, source string) {
+	for _, s := range activeSettings {
+		if s.Name == "default_notebook" && s.Source == sourceEnv {
+			return &currentNotebook{NotebookID: s.Value}, "NLM_NOTEBOOK"
+		}
+	}
+	if cur, err := readCurrentNotebook(); err == nil && cur != nil {
+		return cur, "nlm use"
+	}
+	if defaultNotebook != "" {
+		return &currentNotebook{NotebookID: defaultNotebook}, fmt.Sprintf("profile %q", activeProfileName)
+	}
+	return nil, ""
+}
+
+// withCurrentNotebook prepends the current notebook ID to args when cmd is
+// notebook-scoped and the ID appears to have been omitted: either there are
+// too few arguments, or the first argument is not a notebook ID and there
+// is room for one more.
+func withCurrentNotebook(cmd string, args []string) []string {
+	r, ok := notebookScopedCommands[cmd]
+	if !ok {
+		return args
+	}
+	if sourceFilter != "" && slices.Contains(transformCommands, cmd) {
+		r.min = 1 // -sources stands in for the source IDs
+	}
+	omitted := len(args) < r.min ||
+		len(args) > 0 && !looksLikeNotebookID(args[0]) && (r.max < 0 || len(args) < r.max)
+	if !omitted {
+		return args
+	}
+	cur, source := resolveCurrentNotebook()
+	if cur == nil {
+		return args
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "nlm: using current notebook %s (from %s)\n", cur.NotebookID, source)
+	}
+	return append([]string{cur.NotebookID}, args...)
+}
+
+// looksLikeNotebookID reports whether s has the UUID form of a NotebookLM
+// project ID.
+func looksLikeNotebookID(s string) bool {
+	return len(s) == 36 && uuid.Validate(s) == nil
+}
+
+// useNotebook stores notebookID as the current notebook of the active
+// profile. "none" clears it.
+func useNotebook(c *api.Client, notebookID string) error {
+	path, err := currentNotebookPath()
+	if err != nil {
+		return err
+	}
+	if notebookID == "none" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("clear current notebook: %w", err)
+		}
+		fmt.Println("Current notebook cleared.")
+		return nil
+	}
+
+	nb, err := c.GetProject(notebookID)
+	if err != nil {
+		return fmt.Errorf("get notebook: %w", err)
+	}
+	cur := currentNotebook{
+		NotebookID: notebookID,
+		Title:      nb.GetTitle(),
+		UpdatedAt:  time.Now(),
+	}
+	data, err := json.MarshalIndent(cur, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("write current notebook: %w", err)
+	}
+	if activeProfileName != "" {
+		fmt.Printf("Now using notebook %s (%s) in profile %q.\n", notebookID, cur.Title, activeProfileName)
+	} else {
+		fmt.Printf("Now using notebook %s (%s).\n", notebookID, cur.Title)
+	}
+	return nil
+}
+
+// showCurrentNotebook prints the current notebook and where it came from.
+func showCurrentNotebook(w io.Writer) error {
+	cur, source := resolveCurrentNotebook()
+	if cur == nil {
+		return fmt.Errorf("no current notebook (set one with 'nlm use <notebook-id>')")
+	}
+	if cur.Title != "" {
+		fmt.Fprintf(w, "%s\t%s\t(from %s)\n", cur.NotebookID, cur.Title, source)
+	} else {
+		fmt.Fprintf(w, "%s\t(from %s)\n", cur.NotebookID, source)
+	}
+	return nil
+}
+
+// notebookLabel returns a short label for notebookID for use in prompts:
+// the current notebook's title when it matches, else the ID prefix.
+func notebookLabel(notebookID string) string {
+	if cur, _ := resolveCurrentNotebook(); cur != nil && cur.NotebookID == notebookID && cur.Title != "" {
+		title := []rune(cur.Title)
+		if len(title) > 24 {
+			return string(title[:23]) + "…"
+		}
+		return string(title)
+	}
+	if len(notebookID) > 8 {
+		return notebookID[:8]
+	}
+	return notebookID
+}
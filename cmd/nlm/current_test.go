@@ -0,0 +1,45 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWithCurrentNotebook(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	const cur = "11111111-2222-3333-4444-555555555555"
+	const other = "66666666-7777-8888-9999-000000000000"
+	defaultNotebook = cur
+	t.Cleanup(func() { defaultNotebook = "" })
+
+	tests := []struct {
+		cmd  string
+		args []string
+		want []string
+	}{
+		{"sources", nil, []string{cur}},
+		{"sources", []string{other}, []string{other}},
+		{"sources", []string{"typo"}, []string{"typo"}},
+		{"add", []string{"paper.pdf"}, []string{cur, "paper.pdf"}},
+		{"add", []string{other, "paper.pdf"}, []string{other, "paper.pdf"}},
+		{"chat", nil, []string{cur}},
+		{"chat", []string{"what", "is", "this"}, []string{cur, "what", "is", "this"}},
+		{"chat", []string{other, "hello"}, []string{other, "hello"}},
+		{"summarize", []string{"src-1", "src-2"}, []string{cur, "src-1", "src-2"}},
+		{"audio-download", []string{"out.wav"}, []string{cur, "out.wav"}},
+		{"rm", nil, nil},
+		{"rm-source", []string{"src-1"}, []string{"src-1"}},
+		{"audio-rm", nil, nil},
+		{"list", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := withCurrentNotebook(tt.cmd, tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("withCurrentNotebook(%q, %q) = %q, want %q", tt.cmd, tt.args, got, tt.want)
+		}
+	}
+
+	defaultNotebook = ""
+	if got := withCurrentNotebook("sources", nil); len(got) != 0 {
+		t.Errorf("withCurrentNotebook without current notebook = %q, want none", got)
+	}
+}
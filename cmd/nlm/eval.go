@@ -0,0 +1,469 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// An eval suite is a list of questions with checks on the answers, read by
+// nlm eval:
+//
+//	name: Ops runbook
+//	max-latency: 60s
+//	cases:
+//	  - name: deploy-cadence
+//	    question: When do deploys run?
+//	    contains: [nightly, "02:00 UTC"]
+//	    matches: '(?i)roll ?backs? (are|is) manual'
+//	    cites: [0f6b5c1e-2f43-4a9b-9d0e-6c1f8f0b7a21]
+//	    max-latency: 30s
+//
+// contains is matched case-insensitively; matches takes Go regular
+// expressions. cites lists source IDs that must be cited in the answer.
+// max-latency on the suite is the default for every case; it is not
+// checked for answers taken from the cache.
+type evalSuite struct {
+	Name       string        `yaml:"name"`
+	MaxLatency time.Duration `yaml:"max-latency"`
+	Cases      []*evalCase   `yaml:"cases"`
+}
+
+type evalCase struct {
+	Name       string           `yaml:"name"`
+	Question   string           `yaml:"question"`
+	Contains   stringList       `yaml:"contains"`
+	Patterns   stringList       `yaml:"matches"`
+	Matches    []*regexp.Regexp `yaml:"-"` // compiled Patterns
+	Cites      stringList       `yaml:"cites"`
+	MaxLatency time.Duration    `yaml:"max-latency"`
+}
+
+// stringList is a list of strings that may be written as a lone string.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*l = stringList{n.Value}
+		return nil
+	}
+	var s []string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+	*l = s
+	return nil
+}
+
+// evalResult is the outcome of one case.
+type evalResult struct {
+	Name      string         `json:"name"`
+	Question  string         `json:"question"`
+	Answer    string         `json:"answer,omitempty"`
+	Citations []api.Citation `json:"citations,omitempty"`
+	Latency   time.Duration  `json:"latency_ns"`
+	Cached    bool           `json:"cached"`
+	Passed    bool           `json:"passed"`
+	Failures  []string       `json:"failures,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// evalReport is the JSON report written by nlm eval.
+type evalReport struct {
+	Suite       string        `json:"suite"`
+	NotebookID  string        `json:"notebook_id"`
+	Fingerprint string        `json:"fingerprint"`
+	StartedAt   time.Time     `json:"started_at"`
+	Duration    time.Duration `json:"duration_ns"`
+	Passed      int           `json:"passed"`
+	Failed      int           `json:"failed"`
+	Errors      int           `json:"errors"`
+	Results     []*evalResult `json:"results"`
+}
+
+type evalOptions struct {
+	CasesFile string
+	JUnit     string // JUnit XML report path
+	JSON      string // JSON report path
+	NoCache   bool
+}
+
+func parseEvalArgs(args []string) (evalOptions, string, error) {
+	var opts evalOptions
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&opts.JUnit, "junit", "", "JUnit XML report path (default <cases>.junit.xml)")
+	flags.StringVar(&opts.JSON, "json", "", "JSON report path (default <cases>.report.json)")
+	flags.BoolVar(&opts.NoCache, "no-cache", false, "ask every question even if a cached answer exists")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nlm eval <notebook-id> <cases.yaml> [-junit file] [-json file] [-no-cache]\n")
+	}
+
+	flagArgs, positional := splitFlagArgs(args, "no-cache")
+	if err := flags.Parse(flagArgs); err != nil {
+		return opts, "", fmt.Errorf("invalid arguments")
+	}
+	if len(positional) != 2 {
+		flags.Usage()
+		return opts, "", fmt.Errorf("invalid arguments")
+	}
+	opts.CasesFile = positional[1]
+	base := strings.TrimSuffix(opts.CasesFile, filepath.Ext(opts.CasesFile))
+	if opts.JUnit == "" {
+		opts.JUnit = base + ".junit.xml"
+	}
+	if opts.JSON == "" {
+		opts.JSON = base + ".report.json"
+	}
+	return opts, positional[0], nil
+}
+
+func loadEvalSuite(path string) (*evalSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseEvalSuite(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return s, nil
+}
+
+// parseEvalSuite decodes and checks the eval suite document data.
+func parseEvalSuite(data []byte) (*evalSuite, error) {
+	s := &evalSuite{}
+	if err := decodeYAML(data, s); err != nil {
+		return nil, err
+	}
+	if len(s.Cases) == 0 {
+		return nil, fmt.Errorf("eval suite has no cases")
+	}
+	names := map[string]bool{}
+	for i, c := range s.Cases {
+		if c == nil || strings.TrimSpace(c.Question) == "" {
+			return nil, fmt.Errorf("case %d: case needs a question", i+1)
+		}
+		for _, p := range c.Patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("case %d: matches: %v", i+1, err)
+			}
+			c.Matches = append(c.Matches, re)
+		}
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case-%d", i+1)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("case %d: duplicate name %q", i+1, c.Name)
+		}
+		names[c.Name] = true
+		if c.MaxLatency == 0 {
+			c.MaxLatency = s.MaxLatency
+		}
+	}
+	return s, nil
+}
+
+// check returns the ways in which r fails the case.
+func (c *evalCase) check(r *evalResult) []string {
+	var failures []string
+	lower := strings.ToLower(r.Answer)
+	for _, s := range c.Contains {
+		if !strings.Contains(lower, strings.ToLower(s)) {
+			failures = append(failures, fmt.Sprintf("answer does not contain %q", s))
+		}
+	}
+	for _, re := range c.Matches {
+		if !re.MatchString(r.Answer) {
+			failures = append(failures, fmt.Sprintf("answer does not match /%s/", re))
+		}
+	}
+	attributed := slices.ContainsFunc(r.Citations, func(cit api.Citation) bool { return cit.SourceID != "" })
+	for _, id := range c.Cites {
+		switch {
+		case slices.ContainsFunc(r.Citations, func(cit api.Citation) bool { return cit.SourceID == id }):
+		case len(r.Citations) > 0 && !attributed:
+			failures = append(failures, fmt.Sprintf("cannot tell whether source %s is cited: the citations name no sources", id))
+		default:
+			failures = append(failures, fmt.Sprintf("source %s is not cited", id))
+		}
+	}
+	// A cached answer's latency is that of the original request.
+	if c.MaxLatency > 0 && !r.Cached && r.Latency > c.MaxLatency {
+		failures = append(failures, fmt.Sprintf("latency %v exceeds %v", r.Latency.Round(time.Millisecond), c.MaxLatency))
+	}
+	return failures
+}
+
+// evalAnswer is a chat answer as stored in the eval cache.
+type evalAnswer struct {
+	Answer    string         `json:"answer"`
+	Citations []api.Citation `json:"citations,omitempty"`
+	Latency   time.Duration  `json:"latency_ns"`
+	At        time.Time      `json:"at"`
+}
+
+// evalCache stores answers by notebook fingerprint and question, so that
+// re-running a suite against an unchanged notebook makes no chat requests.
+type evalCache struct {
+	dir string // empty disables the cache
+}
+
+func newEvalCache(disabled bool) *evalCache {
+	home, err := os.UserHomeDir()
+	if disabled || err != nil {
+		return &evalCache{}
+	}
+	return &evalCache{dir: filepath.Join(profileStateDir(home), "eval-cache")}
+}
+
+func (c *evalCache) path(fingerprint, question string) string {
+	sum := sha256.Sum256([]byte(fingerprint + "\x00" + question))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *evalCache) get(fingerprint, question string) (*evalAnswer, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(fingerprint, question))
+	if err != nil {
+		return nil, false
+	}
+	var a evalAnswer
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, false
+	}
+	return &a, true
+}
+
+func (c *evalCache) put(fingerprint, question string, a *evalAnswer) {
+	if c.dir == "" {
+		return
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return
+	}
+	if err := os.WriteFile(c.path(fingerprint, question), data, 0o600); err != nil && debug {
+		fmt.Fprintf(os.Stderr, "nlm: write eval cache: %v\n", err)
+	}
+}
+
+// notebookFingerprint identifies the state of a notebook that answers
+// depend on: its sources, their status and modification times, and its
+// chat configuration.
+func notebookFingerprint(p *pb.Project) string {
+	var lines []string
+	for _, src := range p.GetSources() {
+		md := src.GetMetadata()
+		lines = append(lines, fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%d",
+			src.GetSourceId().GetSourceId(), src.GetTitle(), md.GetStatus(),
+			md.GetLastModifiedTime().AsTime().Unix(), md.GetLastUpdateTimeSeconds().GetValue()))
+	}
+	sort.Strings(lines)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", p.GetProjectId())
+	for _, l := range lines {
+		fmt.Fprintf(h, "%s\n", l)
+	}
+	if cfg := p.GetChatbotConfig(); cfg != nil {
+		data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(cfg)
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// runEval runs every case of the suite against the notebook, prints a
+// summary and writes the reports. It fails if any case fails.
+func runEval(c *api.Client, notebookID string, opts evalOptions) error {
+	suite, err := loadEvalSuite(opts.CasesFile)
+	if err != nil {
+		return err
+	}
+	p, err := c.GetProject(notebookID)
+	if err != nil {
+		return fmt.Errorf("get notebook: %w", err)
+	}
+	report := &evalReport{
+		Suite:       suite.Name,
+		NotebookID:  notebookID,
+		Fingerprint: notebookFingerprint(p),
+		StartedAt:   time.Now(),
+	}
+	cache := newEvalCache(opts.NoCache)
+	for _, ec := range suite.Cases {
+		r := &evalResult{Name: ec.Name, Question: ec.Question}
+		a, cached := cache.get(report.Fingerprint, ec.Question)
+		if !cached {
+			start := time.Now()
+			answer, citations, err := askNotebook(c, notebookID, ec.Question)
+			if isAuthenticationError(err) {
+				return err
+			}
+			if err == nil && answer == "" {
+				err = fmt.Errorf("empty answer")
+			}
+			if err != nil {
+				r.Error = err.Error()
+				report.Results = append(report.Results, r)
+				report.Errors++
+				fmt.Printf("ERROR %s: %v\n", ec.Name, err)
+				continue
+			}
+			a = &evalAnswer{Answer: answer, Citations: citations, Latency: time.Since(start), At: time.Now()}
+			cache.put(report.Fingerprint, ec.Question, a)
+		}
+		r.Answer, r.Citations, r.Latency, r.Cached = a.Answer, a.Citations, a.Latency, cached
+		r.Failures = ec.check(r)
+		r.Passed = len(r.Failures) == 0
+		report.Results = append(report.Results, r)
+		writeEvalResult(os.Stdout, r)
+		if r.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+	report.Duration = time.Since(report.StartedAt)
+
+	if err := writeEvalReportFile(opts.JUnit, report, writeEvalJUnit); err != nil {
+		return err
+	}
+	if err := writeEvalReportFile(opts.JSON, report, writeEvalJSON); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d passed, %d failed, %d errors (%s, %s)\n", report.Passed, report.Failed, report.Errors, opts.JUnit, opts.JSON)
+	if n := report.Failed + report.Errors; n > 0 {
+		return fmt.Errorf("%d of %d eval cases failed", n, len(suite.Cases))
+	}
+	return nil
+}
+
+func writeEvalResult(w io.Writer, r *evalResult) {
+	status := "PASS"
+	if !r.Passed {
+		status = "FAIL"
+	}
+	var cached string
+	if r.Cached {
+		cached = ", cached"
+	}
+	fmt.Fprintf(w, "%s  %s (%v%s)\n", status, r.Name, r.Latency.Round(time.Millisecond), cached)
+	for _, f := range r.Failures {
+		fmt.Fprintf(w, "      %s\n", f)
+	}
+}
+
+func writeEvalReportFile(path string, r *evalReport, write func(io.Writer, *evalReport) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	if err := write(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("write report: %w", err)
+	}
+	return f.Close()
+}
+
+func writeEvalJSON(w io.Writer, r *evalReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func writeEvalJUnit(w io.Writer, r *evalReport) error {
+	suite := junitTestSuite{
+		Name:      r.Suite,
+		Tests:     len(r.Results),
+		Failures:  r.Failed,
+		Errors:    r.Errors,
+		Time:      junitSeconds(r.Duration),
+		Timestamp: r.StartedAt.UTC().Format("2006-01-02T15:04:05"),
+	}
+	for _, res := range r.Results {
+		tc := junitTestCase{
+			Name:      res.Name,
+			ClassName: "nlm.eval." + r.NotebookID,
+			Time:      junitSeconds(res.Latency),
+			SystemOut: res.Answer,
+		}
+		switch {
+		case res.Error != "":
+			tc.Error = &junitMessage{Message: res.Error, Text: res.Question}
+		case !res.Passed:
+			tc.Failure = &junitMessage{
+				Message: res.Failures[0],
+				Text:    "Question: " + res.Question + "\n" + strings.Join(res.Failures, "\n"),
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+func TestParseEvalArgs(t *testing.T) {
+	opts, nb, err := parseEvalArgs([]string{"nb-1", "evals/ops.yaml", "-no-cache", "-json", "out.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nb != "nb-1" || opts.CasesFile != "evals/ops.yaml" || !opts.NoCache {
+		t.Errorf("nb = %q, opts = %+v", nb, opts)
+	}
+	if opts.JUnit != "evals/ops.junit.xml" || opts.JSON != "out.json" {
+		t.Errorf("reports = %q, %q", opts.JUnit, opts.JSON)
+	}
+}
+
+func TestLoadEvalSuite(t *testing.T) {
+	s, err := loadEvalSuite("testdata/eval/cases.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "Ops runbook" || len(s.Cases) != 2 {
+		t.Fatalf("suite = %+v", s)
+	}
+	c := s.Cases[0]
+	if c.Name != "deploy-cadence" || c.Contains[0] != "nightly" || len(c.Matches) != 1 || c.MaxLatency != time.Minute {
+		t.Errorf("case 1 = %+v", c)
+	}
+	c = s.Cases[1]
+	if c.Name != "case-2" || len(c.Cites) != 1 || c.MaxLatency != 30*time.Second {
+		t.Errorf("case 2 = %+v", c)
+	}
+}
+
+func TestParseEvalSuiteErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, doc, want string
+	}{
+		{"no cases", "name: x\n", "no cases"},
+		{"no question", "cases:\n  - name: a\n", "case 1: case needs a question"},
+		{"duplicate", "cases:\n  - {name: a, question: q}\n  - {name: a, question: r}\n", `case 2: duplicate name "a"`},
+		{"latency", "cases:\n  - {question: q, max-latency: soon}\n", "line 2: cannot unmarshal !!str `soon` into time.Duration"},
+		{"unknown", "cases:\n  - {question: q, expect: x}\n", `line 2: unknown key "expect"`},
+		{"suite key", "name: x\ntimeout: 1m\n", `line 2: unknown key "timeout"`},
+		{"regexp", "cases:\n  - {question: q, matches: '('}\n", "case 1: matches: error parsing regexp"},
+	} {
+		if _, err := parseEvalSuite([]byte(tt.doc)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestEvalCaseCheck(t *testing.T) {
+	s, err := loadEvalSuite("testdata/eval/cases.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass := &evalResult{Answer: "Deploys run Nightly at 02:00.", Latency: time.Second}
+	if f := s.Cases[0].check(pass); len(f) != 0 {
+		t.Errorf("failures = %q, want none", f)
+	}
+	fail := &evalResult{
+		Answer:    "The on-call lead approves them.",
+		Citations: []api.Citation{{Number: 1, SourceID: "other"}},
+		Latency:   45 * time.Second,
+	}
+	want := []string{
+		"source 0f6b5c1e-2f43-4a9b-9d0e-6c1f8f0b7a21 is not cited",
+		"latency 45s exceeds 30s",
+	}
+	if f := s.Cases[1].check(fail); strings.Join(f, "\n") != strings.Join(want, "\n") {
+		t.Errorf("failures = %q, want %q", f, want)
+	}
+
+	// A cached answer keeps the original latency, which says nothing
+	// about this run.
+	cached := &evalResult{Answer: "x", Latency: 45 * time.Second, Cached: true}
+	if f := s.Cases[1].check(cached); len(f) != 1 || !strings.Contains(f[0], "is not cited") {
+		t.Errorf("cached failures = %q, want only the citation", f)
+	}
+	unattributed := &evalResult{Answer: "x", Citations: []api.Citation{{Number: 1}}}
+	if f := s.Cases[1].check(unattributed); len(f) != 1 || !strings.Contains(f[0], "cannot tell whether") {
+		t.Errorf("unattributed failures = %q", f)
+	}
+}
+
+func TestNotebookFingerprint(t *testing.T) {
+	project := func(title string, ids ...string) *pb.Project {
+		p := &pb.Project{ProjectId: "nb-1"}
+		for _, id := range ids {
+			p.Sources = append(p.Sources, &pb.Source{SourceId: &pb.SourceId{SourceId: id}, Title: title})
+		}
+		return p
+	}
+	a := notebookFingerprint(project("Doc", "s1", "s2"))
+	if b := notebookFingerprint(project("Doc", "s2", "s1")); a != b {
+		t.Errorf("source order changed the fingerprint: %s != %s", a, b)
+	}
+	if b := notebookFingerprint(project("Doc v2", "s1", "s2")); a == b {
+		t.Error("renamed sources kept the fingerprint")
+	}
+	if b := notebookFingerprint(project("Doc", "s1")); a == b {
+		t.Error("removed source kept the fingerprint")
+	}
+}
+
+func TestEvalCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	c := newEvalCache(false)
+	if _, ok := c.get("fp", "q"); ok {
+		t.Fatal("empty cache returned an answer")
+	}
+	c.put("fp", "q", &evalAnswer{Answer: "a", Latency: time.Second})
+	if a, ok := c.get("fp", "q"); !ok || a.Answer != "a" || a.Latency != time.Second {
+		t.Errorf("get = %+v, %v", a, ok)
+	}
+	if _, ok := c.get("fp2", "q"); ok {
+		t.Error("changed fingerprint hit the cache")
+	}
+	if _, ok := newEvalCache(true).get("fp", "q"); ok {
+		t.Error("-no-cache read the cache")
+	}
+}
+
+func TestWriteEvalJUnit(t *testing.T) {
+	r := &evalReport{
+		Suite:      "Ops",
+		NotebookID: "nb-1",
+		StartedAt:  time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+		Duration:   3 * time.Second,
+		Passed:     1, Failed: 1, Errors: 1,
+		Results: []*evalResult{
+			{Name: "ok", Question: "q1", Answer: "yes", Latency: 1500 * time.Millisecond, Passed: true},
+			{Name: "bad", Question: "q2", Answer: "no", Failures: []string{`answer does not contain "yes"`}},
+			{Name: "err", Question: "q3", Error: "chat request failed"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeEvalJUnit(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var got junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	s := got.Suites[0]
+	if s.Name != "Ops" || s.Tests != 3 || s.Failures != 1 || s.Errors != 1 || s.Timestamp != "2026-03-01T09:30:00" {
+		t.Errorf("suite = %+v", s)
+	}
+	if tc := s.Cases[0]; tc.Time != "1.500" || tc.Failure != nil || tc.SystemOut != "yes" {
+		t.Errorf("passing case = %+v", tc)
+	}
+	if tc := s.Cases[1]; tc.Failure == nil || tc.Failure.Message != `answer does not contain "yes"` {
+		t.Errorf("failing case = %+v", tc)
+	}
+	if tc := s.Cases[2]; tc.Error == nil || tc.Error.Message != "chat request failed" {
+		t.Errorf("error case = %+v", tc)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/tmc/nlm/internal/nlmgrpc"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// nlm grpc-serve implements the generated orchestration, sharing and
+// guidebooks services, proxying each call to NotebookLM. Server reflection
+// is enabled so clients can be generated or exercised with grpcurl.
+
+type grpcServeOptions struct {
+	Addr  string
+	Token string
+}
+
+func parseGRPCServeArgs(args []string) (grpcServeOptions, error) {
+	opts := grpcServeOptions{Addr: "localhost:50051", Token: os.Getenv("NLM_GRPC_TOKEN")}
+	flags := flag.NewFlagSet("grpc-serve", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&opts.Addr, "addr", opts.Addr, "address to listen on")
+	flags.StringVar(&opts.Token, "token", opts.Token, "bearer token clients must send (or NLM_GRPC_TOKEN; default: the serve token)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nlm grpc-serve [-addr localhost:50051] [-token token]\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		return opts, fmt.Errorf("invalid arguments")
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return opts, fmt.Errorf("invalid arguments")
+	}
+	return opts, nil
+}
+
+func runGRPCServe(c *api.Client, opts grpcServeOptions) error {
+	if opts.Token == "" {
+		token, path, err := loadServeToken()
+		if err != nil {
+			return err
+		}
+		opts.Token = token
+		fmt.Fprintf(os.Stderr, "nlm: bearer token in %s\n", path)
+	}
+	srv := nlmgrpc.New(c, nlmgrpc.BearerToken(opts.Token)...)
+	ln, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+	fmt.Fprintf(os.Stderr, "nlm: serving gRPC on %s\n", ln.Addr())
+	return srv.Serve(ln)
+}
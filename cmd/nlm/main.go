@@ -10,20 +10,17 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"text/tabwriter"
 	"time"
 
-	runtimedebug "runtime/debug"
-
 	"github.com/google/uuid"
-	"github.com/modelcontextprotocol/go-sdk/mcp"
 	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
 	"github.com/tmc/nlm/gen/service"
 	"github.com/tmc/nlm/internal/auth"
 	"github.com/tmc/nlm/internal/batchexecute"
 	"github.com/tmc/nlm/internal/beprotojson"
-	"github.com/tmc/nlm/internal/nlmmcp"
 	"github.com/tmc/nlm/internal/notebooklm/api"
 	"github.com/tmc/nlm/internal/notebooklm/rpc"
 	"golang.org/x/term"
@@ -42,6 +39,7 @@ var (
 	chunkedResponse   bool   // Control rt=c parameter for chunked vs JSON array response
 	useDirectRPC      bool   // Use direct RPC calls instead of orchestration service
 	skipSources       bool   // Skip fetching sources for chat (useful when project is inaccessible)
+	sourceFilter      string // Limit chat and transformations to matching sources
 	yes               bool   // Skip confirmation prompts
 	sourceName        string // Custom name for added sources
 	showChatHistory   bool   // Show previous chat conversation on start
@@ -57,6 +55,14 @@ type ChatSession struct {
 	Messages       []ChatMessage `json:"messages"`
 	CreatedAt      time.Time     `json:"created_at"`
 	UpdatedAt      time.Time     `json:"updated_at"`
+
+	// Fork graph: the conversation this one was forked from, and how many
+	// of the parent's messages it carried over.
+	ParentID  string `json:"parent_conversation_id,omitempty"`
+	ForkPoint int    `json:"fork_point,omitempty"`
+
+	// SourceIDs limits the conversation to some sources; empty means all.
+	SourceIDs []string `json:"source_ids,omitempty"`
 }
 
 // ChatMessage represents a single message in the conversation.
@@ -68,8 +74,8 @@ type ChatMessage struct {
 	Timestamp time.Time `json:"timestamp"`
 
 	// Transient stream data — only available locally, not from server history.
-	Thinking  string   `json:"thinking,omitempty"`  // Reasoning traces from intermediate chunks
-	Citations []string `json:"citations,omitempty"` // Source references from the response
+	Thinking  string         `json:"thinking,omitempty"`  // Reasoning traces from intermediate chunks
+	Citations []api.Citation `json:"citations,omitempty"` // Source references from the response
 }
 
 func init() {
@@ -80,9 +86,11 @@ func init() {
 	flag.BoolVar(&chunkedResponse, "chunked", false, "use chunked response format (rt=c)")
 	flag.BoolVar(&useDirectRPC, "direct-rpc", false, "use direct RPC calls for audio/video (bypasses orchestration service)")
 	flag.BoolVar(&skipSources, "skip-sources", false, "skip fetching sources for chat (useful for testing)")
+	flag.StringVar(&sourceFilter, "sources", "", "limit chat to sources matching IDs, title globs or type:youtube|pdf|web (comma-separated)")
 	flag.BoolVar(&yes, "yes", false, "skip confirmation prompts")
 	flag.BoolVar(&yes, "y", false, "skip confirmation prompts")
-	flag.StringVar(&chromeProfile, "profile", os.Getenv("NLM_BROWSER_PROFILE"), "Chrome profile to use")
+	flag.StringVar(&chromeProfile, "profile", os.Getenv("NLM_BROWSER_PROFILE"), "config profile (or NLM_PROFILE); otherwise the Chrome profile to use")
+	flag.StringVar(&configFlag, "config", "", "config file (default ~/.config/nlm/config.toml, or NLM_CONFIG)")
 	flag.StringVar(&authToken, "auth", os.Getenv("NLM_AUTH_TOKEN"), "auth token (or set NLM_AUTH_TOKEN)")
 	flag.StringVar(&cookies, "cookies", os.Getenv("NLM_COOKIES"), "cookies for authentication (or set NLM_COOKIES)")
 	flag.StringVar(&mimeType, "mime", "", "specify MIME type for content (e.g. 'application/pdf', 'text/plain')")
@@ -103,7 +111,10 @@ func init() {
 		fmt.Fprintf(os.Stderr, "  create <title>    Create a new notebook\n")
 		fmt.Fprintf(os.Stderr, "  rm <id>           Delete a notebook\n")
 		fmt.Fprintf(os.Stderr, "  analytics <id>    Show notebook analytics\n")
-		fmt.Fprintf(os.Stderr, "  list-featured     List featured notebooks\n\n")
+		fmt.Fprintf(os.Stderr, "  list-featured     List featured notebooks\n")
+		fmt.Fprintf(os.Stderr, "  use <id|none>     Set the current notebook (used when <id> is omitted)\n")
+		fmt.Fprintf(os.Stderr, "  current           Show the current notebook\n")
+		fmt.Fprintf(os.Stderr, "  tui               Browse notebooks in a full-screen terminal UI\n\n")
 
 		fmt.Fprintf(os.Stderr, "Source Commands:\n")
 		fmt.Fprintf(os.Stderr, "  sources <id>      List sources in notebook\n")
@@ -161,6 +172,12 @@ func init() {
 		fmt.Fprintf(os.Stderr, "  generate-magic <id> <source-ids...>  Generate magic view from sources\n")
 		fmt.Fprintf(os.Stderr, "  chat <id> [conv|prompt]  Interactive chat (or one-shot with prompt)\n")
 		fmt.Fprintf(os.Stderr, "  chat-list [id]          List chat sessions (server-side if notebook given)\n")
+		fmt.Fprintf(os.Stderr, "  chat-export <id> [conv] Export a conversation (-format md|html|json)\n")
+		fmt.Fprintf(os.Stderr, "  chat-search \"term\" [-notebook id]  Search local and server chat history\n")
+		fmt.Fprintf(os.Stderr, "  chat-tree <id> [conv[@n]]  Show the conversation fork graph, or continue from a node\n")
+		fmt.Fprintf(os.Stderr, "  chat-server <id> [conv]  Serve chat as JSON-RPC over stdin/stdout (for editors)\n")
+		fmt.Fprintf(os.Stderr, "  chat <id> @name [-var k=v]  Send a prompt template from ~/.nlm/prompts\n")
+		fmt.Fprintf(os.Stderr, "  prompts [list|show|edit|rm] [name]  Manage prompt templates\n")
 		fmt.Fprintf(os.Stderr, "  delete-chat <id>        Delete server-side chat history\n")
 		fmt.Fprintf(os.Stderr, "  chat-config <id> <setting> [value]  Configure chat settings\n")
 		fmt.Fprintf(os.Stderr, "  set-instructions <id> \"prompt\"      Set system instructions\n")
@@ -188,16 +205,28 @@ func init() {
 		fmt.Fprintf(os.Stderr, "  share-details <share-id>  Get details of shared project\n\n")
 
 		fmt.Fprintf(os.Stderr, "Research Commands:\n")
-		fmt.Fprintf(os.Stderr, "  research <id> \"query\"   Start deep research and poll for results\n\n")
+		fmt.Fprintf(os.Stderr, "  research <id> \"query\"   Start deep research and poll for results\n")
+		fmt.Fprintf(os.Stderr, "  ask-all \"question\" -notebooks a,b | -tag x  Ask several notebooks and merge the answers\n")
+		fmt.Fprintf(os.Stderr, "  eval <id> <cases.yaml>  Check answers to known questions (JUnit and JSON reports)\n")
+		fmt.Fprintf(os.Stderr, "  watch <id> [-json] [-exec cmd]  Report source, note and artifact changes\n")
+		fmt.Fprintf(os.Stderr, "  wait <id> [artifact-ids...]  Wait for generation to finish and send the webhook\n")
+		fmt.Fprintf(os.Stderr, "  webhook-test      Send a test ping to the configured webhook\n\n")
 
 		fmt.Fprintf(os.Stderr, "Other Commands:\n")
-		fmt.Fprintf(os.Stderr, "  mcp               Start MCP server (stdin/stdout)\n")
+		fmt.Fprintf(os.Stderr, "  mcp [-http host:port]  Start MCP server (stdin/stdout, or streamable HTTP and SSE)\n")
+		fmt.Fprintf(os.Stderr, "  serve [-addr host:port]  Serve a REST API with an OpenAPI spec (-openapi prints it)\n")
+		fmt.Fprintf(os.Stderr, "  serve-openai [-addr host:port]  Serve notebook chat as an OpenAI-compatible API\n")
+		fmt.Fprintf(os.Stderr, "  grpc-serve [-addr host:port]  Serve the NotebookLM gRPC services, with reflection\n")
 		fmt.Fprintf(os.Stderr, "  auth [profile]    Setup authentication\n")
 		fmt.Fprintf(os.Stderr, "  refresh           Refresh authentication credentials\n")
 		fmt.Fprintf(os.Stderr, "  feedback <msg>    Submit feedback\n")
+		fmt.Fprintf(os.Stderr, "  completion <shell>  Print shell completion script (bash, zsh, fish)\n")
+		fmt.Fprintf(os.Stderr, "  config [profiles]  Show effective configuration (or list profiles)\n")
+		fmt.Fprintf(os.Stderr, "  run <recipe.yaml> [name=value...]  Run a recipe of nlm commands\n")
 		fmt.Fprintf(os.Stderr, "  hb                Send heartbeat\n\n")
 
 		fmt.Fprintf(os.Stderr, "Global Flags:\n")
+		fmt.Fprintf(os.Stderr, "  --profile <name>       Use a profile from ~/.config/nlm/config.toml (or NLM_PROFILE)\n")
 		fmt.Fprintf(os.Stderr, "  --format stream|plain  Output format for generate-chat (plain: clean text, no progress messages)\n")
 		fmt.Fprintf(os.Stderr, "  --debug                Enable debug output\n\n")
 	}
@@ -260,11 +289,26 @@ func reorderArgs() {
 }
 
 func main() {
+	// Shell completion passes the partial command line verbatim; handle it
+	// before flag reordering and parsing can consume the words being completed.
+	if len(os.Args) > 1 && os.Args[1] == completeCommand {
+		loadConfig()
+		loadStoredEnv()
+		runComplete(os.Stdout, os.Args[2:])
+		return
+	}
+
 	lockInteractiveAudioAppThreadIfNeeded(os.Args[1:])
 
 	reorderArgs()
 	flag.Parse()
 
+	// Apply the config profile before anything reads the flags it sets.
+	if err := loadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "nlm: %v\n", err)
+		os.Exit(1)
+	}
+
 	if debug {
 		fmt.Fprintf(os.Stderr, "nlm: debug mode enabled\n")
 		if chromeProfile != "" {
@@ -307,6 +351,11 @@ func main() {
 // isAuthCommand returns true if the command requires authentication
 // validateArgs validates command arguments without requiring authentication
 func validateArgs(cmd string, args []string) error {
+	if sourceFilter != "" {
+		if err := checkSourceFilter(sourceFilter); err != nil {
+			return err
+		}
+	}
 	switch cmd {
 	case "create":
 		if len(args) != 1 {
@@ -442,7 +491,7 @@ func validateArgs(cmd string, args []string) error {
 			return fmt.Errorf("invalid arguments")
 		}
 	case "rephrase", "expand", "summarize", "critique", "brainstorm", "verify", "explain", "outline", "study-guide", "faq", "briefing-doc", "mindmap", "timeline", "toc":
-		if len(args) < 2 {
+		if len(args) < 2 && (sourceFilter == "" || len(args) < 1) {
 			fmt.Fprintf(os.Stderr, "usage: nlm %s <notebook-id> <source-id> [source-id...]\n", cmd)
 			return fmt.Errorf("invalid arguments")
 		}
@@ -471,11 +520,39 @@ func validateArgs(cmd string, args []string) error {
 			fmt.Fprintf(os.Stderr, "usage: nlm chat <notebook-id> [conversation-id | prompt]\n")
 			return fmt.Errorf("invalid arguments")
 		}
+		if len(args) > 1 && strings.HasPrefix(args[1], "@") {
+			return checkPromptArgs(args[1:])
+		}
+	case "prompts":
+		if len(args) > 2 || len(args) == 1 && args[0] != "list" ||
+			len(args) == 2 && !slices.Contains([]string{"show", "edit", "rm"}, args[0]) {
+			fmt.Fprintf(os.Stderr, "usage: nlm prompts [list | show <name> | edit <name> | rm <name>]\n")
+			return fmt.Errorf("invalid arguments")
+		}
 	case "chat-list":
 		if len(args) > 1 {
 			fmt.Fprintf(os.Stderr, "usage: nlm chat-list [notebook-id]\n")
 			return fmt.Errorf("invalid arguments")
 		}
+	case "chat-search":
+		if _, _, err := parseChatSearchArgs(args); err != nil {
+			return err
+		}
+	case "chat-tree":
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Fprintf(os.Stderr, "usage: nlm chat-tree <notebook-id> [conversation-id[@n]]\n")
+			return fmt.Errorf("invalid arguments")
+		}
+	case "chat-server":
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Fprintf(os.Stderr, "usage: nlm chat-server <notebook-id> [conversation-id]\n")
+			return fmt.Errorf("invalid arguments")
+		}
+	case "chat-export":
+		if len(args) < 1 || len(args) > 2 {
+			fmt.Fprintf(os.Stderr, "usage: nlm chat-export <notebook-id> [conversation-id] [-format md|html|json]\n")
+			return fmt.Errorf("invalid arguments")
+		}
 	case "delete-chat":
 		if len(args) != 1 {
 			fmt.Fprintf(os.Stderr, "usage: nlm delete-chat <notebook-id>\n")
@@ -570,28 +647,106 @@ func validateArgs(cmd string, args []string) error {
 			fmt.Fprintf(os.Stderr, "usage: nlm feedback <message>\n")
 			return fmt.Errorf("invalid arguments")
 		}
+	case "watch":
+		if _, _, err := parseWatchArgs(args); err != nil {
+			return err
+		}
+	case "mcp":
+		if _, err := parseMCPArgs(args); err != nil {
+			return err
+		}
+	case "serve":
+		if _, err := parseServeArgs(args); err != nil {
+			return err
+		}
+	case "serve-openai":
+		if _, err := parseServeOpenAIArgs(args); err != nil {
+			return err
+		}
+	case "grpc-serve":
+		if _, err := parseGRPCServeArgs(args); err != nil {
+			return err
+		}
+	case "ask-all":
+		opts, _, err := parseAskAllArgs(args)
+		if err != nil {
+			return err
+		}
+		if _, err := askAllNotebooks(opts, notebookTags); err != nil {
+			return err
+		}
+	case "eval":
+		opts, _, err := parseEvalArgs(args)
+		if err != nil {
+			return err
+		}
+		if _, err := loadEvalSuite(opts.CasesFile); err != nil {
+			return err
+		}
+	case "wait":
+		if len(args) < 1 {
+			fmt.Fprintf(os.Stderr, "usage: nlm wait <notebook-id> [artifact-id...]\n")
+			return fmt.Errorf("invalid arguments")
+		}
+	case "webhook-test":
+		if len(args) != 0 {
+			fmt.Fprintf(os.Stderr, "usage: nlm webhook-test\n")
+			return fmt.Errorf("invalid arguments")
+		}
+	case "run":
+		if len(args) < 1 {
+			fmt.Fprintf(os.Stderr, "usage: nlm run <recipe.yaml> [name=value...]\n")
+			return fmt.Errorf("invalid arguments")
+		}
+	case "completion":
+		if len(args) != 1 {
+			fmt.Fprintf(os.Stderr, "usage: nlm completion <bash|zsh|fish>\n")
+			return fmt.Errorf("invalid arguments")
+		}
+	case "use":
+		if len(args) != 1 {
+			fmt.Fprintf(os.Stderr, "usage: nlm use <notebook-id|none>\n")
+			return fmt.Errorf("invalid arguments")
+		}
+	case "tui":
+		if len(args) != 0 {
+			fmt.Fprintf(os.Stderr, "usage: nlm tui\n")
+			return fmt.Errorf("invalid arguments")
+		}
+	case "current":
+		if len(args) != 0 {
+			fmt.Fprintf(os.Stderr, "usage: nlm current\n")
+			return fmt.Errorf("invalid arguments")
+		}
+	case "config":
+		if len(args) > 1 || len(args) == 1 && args[0] != "profiles" {
+			fmt.Fprintf(os.Stderr, "usage: nlm config [profiles]\n")
+			return fmt.Errorf("invalid arguments")
+		}
 	}
 	return nil
 }
 
+// validCommands lists every command accepted by runCmd, including aliases.
+var validCommands = []string{
+	"help", "-h", "--help",
+	"list", "ls", "create", "rm", "analytics", "list-featured", "use", "current", "tui",
+	"sources", "add", "rm-source", "rename-source", "refresh-source", "check-source", "discover-sources",
+	"notes", "read-note", "new-note", "update-note", "rm-note",
+	"create-audio", "create-video", "create-slides",
+	"audio-get", "audio-rm", "audio-share", "audio-list", "audio-download", "audio-interactive",
+	"video-list", "video-download",
+	"get-artifact", "list-artifacts", "artifacts", "rename-artifact", "delete-artifact",
+	"guidebooks", "guidebook", "guidebook-publish", "guidebook-share", "guidebook-ask", "guidebook-rm",
+	"generate-guide", "generate-magic", "generate-mindmap", "generate-chat", "chat", "chat-list", "chat-export", "chat-search", "chat-tree", "chat-server", "delete-chat", "chat-config", "prompts", "set-instructions", "get-instructions",
+	"rephrase", "expand", "summarize", "critique", "brainstorm", "verify", "explain", "outline", "study-guide", "faq", "briefing-doc", "mindmap", "timeline", "toc",
+	"research", "ask-all", "eval", "watch", "wait", "webhook-test",
+	"auth", "refresh", "hb", "share", "share-private", "share-details", "feedback", "mcp", "serve", "serve-openai", "grpc-serve",
+	"completion", "config", "run",
+}
+
 // isValidCommand checks if a command is valid
 func isValidCommand(cmd string) bool {
-	validCommands := []string{
-		"help", "-h", "--help",
-		"list", "ls", "create", "rm", "analytics", "list-featured",
-		"sources", "add", "rm-source", "rename-source", "refresh-source", "check-source", "discover-sources",
-		"notes", "read-note", "new-note", "update-note", "rm-note",
-		"create-audio", "create-video", "create-slides",
-		"audio-get", "audio-rm", "audio-share", "audio-list", "audio-download", "audio-interactive",
-		"video-list", "video-download",
-		"get-artifact", "list-artifacts", "artifacts", "rename-artifact", "delete-artifact",
-		"guidebooks", "guidebook", "guidebook-publish", "guidebook-share", "guidebook-ask", "guidebook-rm",
-		"generate-guide", "generate-magic", "generate-mindmap", "generate-chat", "chat", "chat-list", "delete-chat", "chat-config", "set-instructions", "get-instructions",
-		"rephrase", "expand", "summarize", "critique", "brainstorm", "verify", "explain", "outline", "study-guide", "faq", "briefing-doc", "mindmap", "timeline", "toc",
-		"research",
-		"auth", "refresh", "hb", "share", "share-private", "share-details", "feedback", "mcp",
-	}
-
 	for _, valid := range validCommands {
 		if cmd == valid {
 			return true
@@ -617,6 +772,15 @@ func isAuthCommand(cmd string) bool {
 	if cmd == "chat-list" {
 		return false
 	}
+	// Completion scripts are static
+	if cmd == "completion" {
+		return false
+	}
+	// Config, current and prompts only use local files; webhook-test
+	// only talks to the webhook receiver
+	if cmd == "config" || cmd == "current" || cmd == "prompts" || cmd == "webhook-test" {
+		return false
+	}
 	return true
 }
 
@@ -659,6 +823,9 @@ func run() error {
 		os.Exit(1)
 	}
 
+	// Fill in the current notebook when a notebook-scoped command omits it
+	args = withCurrentNotebook(cmd, args)
+
 	// Validate arguments first (before authentication check)
 	if err := validateArgs(cmd, args); err != nil {
 		if errors.Is(err, errInteractiveAudioHelp) {
@@ -667,6 +834,30 @@ func run() error {
 		return err
 	}
 
+	// Clearing the current notebook needs no credentials
+	if cmd == "use" && args[0] == "none" {
+		return useNotebook(nil, args[0])
+	}
+
+	// Neither does searching the chat index without fetching server history
+	if cmd == "chat-search" {
+		if opts, query, _ := parseChatSearchArgs(args); opts.Offline {
+			return chatSearch(nil, opts, query)
+		}
+	}
+
+	// Nor does printing the REST API spec
+	if cmd == "serve" {
+		if opts, _ := parseServeArgs(args); opts.OpenAPI {
+			return printOpenAPISpec()
+		}
+	}
+
+	// Nor does drawing the chat tree, which only reads local sessions
+	if cmd == "chat-tree" && len(args) == 1 {
+		return chatTree(nil, args[0], "")
+	}
+
 	// Check if this command needs authentication
 	if isAuthCommand(cmd) && (authToken == "" || cookies == "") {
 		fmt.Fprintf(os.Stderr, "Authentication required for '%s'. Run 'nlm auth' first.\n", cmd)
@@ -690,6 +881,31 @@ func run() error {
 		return refreshCredentials(debug)
 	}
 
+	// Handle completion command
+	if cmd == "completion" {
+		return writeCompletionScript(os.Stdout, args[0])
+	}
+
+	// Handle config command
+	if cmd == "config" {
+		return showConfig(os.Stdout, args)
+	}
+
+	// Handle current command
+	if cmd == "current" {
+		return showCurrentNotebook(os.Stdout)
+	}
+
+	// Handle webhook-test command
+	if cmd == "webhook-test" {
+		return testWebhook()
+	}
+
+	// Handle prompts command
+	if cmd == "prompts" {
+		return runPromptsCommand(os.Stdout, args)
+	}
+
 	var opts []batchexecute.Option
 
 	// Add debug option if enabled
@@ -697,6 +913,11 @@ func run() error {
 		opts = append(opts, batchexecute.WithDebug(true))
 	}
 
+	// Apply the request timeout from the config profile or NLM_TIMEOUT
+	if requestTimeout > 0 {
+		opts = append(opts, batchexecute.WithTimeout(requestTimeout))
+	}
+
 	// Add rt=c parameter if chunked response format is requested
 	if chunkedResponse {
 		opts = append(opts, batchexecute.WithURLParams(map[string]string{
@@ -848,7 +1069,7 @@ func runCmd(client *api.Client, cmd string, args ...string) error {
 	case "notes":
 		err = listNotes(client, args[0])
 	case "read-note":
-		err = readNote(client, args[0], args[1])
+		err = readNote(client, os.Stdout, args[0], args[1])
 	case "new-note":
 		noteContent := ""
 		if len(args) > 2 {
@@ -1018,10 +1239,25 @@ func runCmd(client *api.Client, cmd string, args ...string) error {
 		err = actOnSources(client, args[0], "timeline", args[1:])
 	case "toc":
 		err = actOnSources(client, args[0], "table_of_contents", args[1:])
+	case "use":
+		err = useNotebook(client, args[0])
+	case "tui":
+		err = runTUI(client)
 	case "generate-chat":
-		err = generateFreeFormChat(client, args[0], strings.Join(args[1:], " "), outputFormat)
+		var sourceIDs []string
+		if sourceIDs, err = selectSources(client, args[0]); err != nil {
+			break
+		}
+		err = generateFreeFormChat(client, args[0], strings.Join(args[1:], " "), sourceIDs, outputFormat)
 	case "chat":
-		if len(args) >= 2 {
+		if len(args) >= 2 && strings.HasPrefix(args[1], "@") {
+			// Expand a prompt template and send it as a one-shot prompt
+			var prompt string
+			if prompt, err = expandPrompt(client, args[0], args[1:]); err != nil {
+				break
+			}
+			err = oneShotChat(client, args[0], prompt)
+		} else if len(args) >= 2 {
 			rest := strings.Join(args[1:], " ")
 			// If it looks like a conversation ID (UUID-ish), resume that conversation
 			if isConversationID(rest) {
@@ -1039,6 +1275,27 @@ func runCmd(client *api.Client, cmd string, args ...string) error {
 		} else {
 			err = listChatSessions()
 		}
+	case "chat-search":
+		opts, query, _ := parseChatSearchArgs(args)
+		err = chatSearch(client, opts, query)
+	case "chat-tree":
+		var ref string
+		if len(args) > 1 {
+			ref = args[1]
+		}
+		err = chatTree(client, args[0], ref)
+	case "chat-server":
+		var conv string
+		if len(args) > 1 {
+			conv = args[1]
+		}
+		err = runChatServer(client, args[0], conv)
+	case "chat-export":
+		var conv string
+		if len(args) > 1 {
+			conv = args[1]
+		}
+		err = exportChat(client, args[0], conv, outputFormat)
 	case "delete-chat":
 		err = deleteChatHistory(client, args[0])
 	case "chat-config":
@@ -1064,7 +1321,30 @@ func runCmd(client *api.Client, cmd string, args ...string) error {
 
 	// Other operations
 	case "mcp":
-		err = runMCP(client)
+		opts, _ := parseMCPArgs(args)
+		err = runMCP(client, opts)
+	case "serve":
+		opts, _ := parseServeArgs(args)
+		err = runServe(client, opts)
+	case "serve-openai":
+		opts, _ := parseServeOpenAIArgs(args)
+		err = runServeOpenAI(client, opts)
+	case "grpc-serve":
+		opts, _ := parseGRPCServeArgs(args)
+		err = runGRPCServe(client, opts)
+	case "ask-all":
+		opts, question, _ := parseAskAllArgs(args)
+		err = askAll(client, opts, question)
+	case "eval":
+		opts, notebookID, _ := parseEvalArgs(args)
+		err = runEval(client, notebookID, opts)
+	case "watch":
+		opts, notebookID, _ := parseWatchArgs(args)
+		err = watchNotebook(client, notebookID, opts)
+	case "wait":
+		err = waitForGeneration(client, args[0], args[1:])
+	case "run":
+		err = runRecipe(client, args[0], args[1:])
 	case "feedback":
 		err = submitFeedback(client, args[0])
 	case "hb":
@@ -1077,19 +1357,6 @@ func runCmd(client *api.Client, cmd string, args ...string) error {
 	return err
 }
 
-func runMCP(client *api.Client) error {
-	info, ok := runtimedebug.ReadBuildInfo()
-	version := "devel"
-	if ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
-		version = info.Main.Version
-	}
-	impl := &mcp.Implementation{
-		Name:    "nlm",
-		Version: version,
-	}
-	return nlmmcp.Run(context.Background(), client, impl)
-}
-
 // confirmAction prompts the user for confirmation unless --yes is set.
 func confirmAction(prompt string) bool {
 	if yes {
@@ -1223,16 +1490,21 @@ func addSource(c *api.Client, notebookID, input string) (string, error) {
 	case "": // empty input
 		return "", fmt.Errorf("input required (file, URL, or '-' for stdin)")
 	}
+	return addSourceInput(c, os.Stdout, notebookID, input)
+}
 
+// addSourceInput adds input as a URL, local file or text source, reporting
+// which it chose to w.
+func addSourceInput(c *api.Client, w io.Writer, notebookID, input string) (string, error) {
 	// Check if input is a URL
 	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
-		fmt.Printf("Adding source from URL: %s\n", input)
+		fmt.Fprintf(w, "Adding source from URL: %s\n", input)
 		return c.AddSourceFromURL(notebookID, input)
 	}
 
 	// Try as local file
 	if _, err := os.Stat(input); err == nil {
-		fmt.Printf("Adding source from file: %s\n", input)
+		fmt.Fprintf(w, "Adding source from file: %s\n", input)
 		name := filepath.Base(input)
 		if sourceName != "" {
 			name = sourceName
@@ -1259,7 +1531,7 @@ func addSource(c *api.Client, notebookID, input string) (string, error) {
 	}
 
 	// If it's not a URL or file, treat as direct text content
-	fmt.Println("Adding text content as source...")
+	fmt.Fprintln(w, "Adding text content as source...")
 	textName := "Text Source"
 	if sourceName != "" {
 		textName = sourceName
@@ -1341,14 +1613,14 @@ func listNotes(c *api.Client, notebookID string) error {
 	return w.Flush()
 }
 
-func readNote(c *api.Client, notebookID, noteID string) error {
+func readNote(c *api.Client, w io.Writer, notebookID, noteID string) error {
 	notes, err := c.GetNotes(notebookID)
 	if err != nil {
 		return fmt.Errorf("get notes: %w", err)
 	}
 	for _, note := range notes {
 		if note.GetNoteId() == noteID {
-			fmt.Printf("# %s\n\n%s\n", note.GetTitle(), note.GetContentText())
+			fmt.Fprintf(w, "# %s\n\n%s\n", note.GetTitle(), note.GetContentText())
 			return nil
 		}
 	}
@@ -1468,8 +1740,18 @@ func actOnSources(c *api.Client, notebookID string, action string, sourceIDs []s
 		actionName = "Processing"
 	}
 
+	selected, err := selectSources(c, notebookID)
+	if err != nil {
+		return err
+	}
+	for _, id := range selected {
+		if !slices.Contains(sourceIDs, id) {
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+
 	fmt.Fprintf(os.Stderr, "%s content from sources...\n", actionName)
-	err := c.ActOnSources(notebookID, action, sourceIDs)
+	err = c.ActOnSources(notebookID, action, sourceIDs)
 	if err != nil {
 		return fmt.Errorf("%s: %w", strings.ToLower(actionName), err)
 	}
@@ -1524,6 +1806,9 @@ func createAudioOverview(c *api.Client, projectID string, instructions string) e
 
 	if !result.IsReady {
 		fmt.Println("✅ Audio overview creation started. Use 'nlm audio-get' to check status.")
+		if result.AudioID != "" {
+			fmt.Printf("  ID: %s\n", result.AudioID)
+		}
 		return nil
 	}
 
@@ -1651,7 +1936,7 @@ func discoverSources(c *api.Client, projectID, query string) error {
 	}
 
 	fmt.Fprintf(os.Stderr, "Deep research is unavailable; falling back to notebook suggestions.\n")
-	answer, _, err := streamChatResponse(c, api.ChatRequest{
+	answer, _, _, err := streamChatResponse(c, api.ChatRequest{
 		ProjectID: projectID,
 		Prompt:    fmt.Sprintf("Suggest sources to add for this query: %s. Respond with a short bullet list of specific documents, sites, or search directions.", query),
 	})
@@ -1781,9 +2066,11 @@ type chatStreamRenderer struct {
 	status          io.Writer
 	showThinking    bool
 	verbose         bool
+	footnotes       bool // print citation footnotes after the answer
 	lastThinkingLen int
 	answerBuf       strings.Builder
 	thinkingBuf     strings.Builder
+	citations       []api.Citation
 }
 
 func newChatStreamRenderer(out, status io.Writer, showThinking, verbose bool) *chatStreamRenderer {
@@ -1792,6 +2079,7 @@ func newChatStreamRenderer(out, status io.Writer, showThinking, verbose bool) *c
 		status:       status,
 		showThinking: showThinking,
 		verbose:      verbose,
+		footnotes:    true,
 	}
 }
 
@@ -1816,11 +2104,57 @@ func (r *chatStreamRenderer) WriteChunk(chunk api.ChatChunk) {
 		r.clearThinkingLine()
 		fmt.Fprint(r.out, chunk.Text)
 		r.answerBuf.WriteString(chunk.Text)
+	case api.ChatChunkCitations:
+		r.citations = chunk.Citations
 	}
 }
 
+// Finish clears the thinking line and, unless footnotes are off, prints
+// the answer's citations as numbered footnotes matching the [n] markers in
+// the answer text.
 func (r *chatStreamRenderer) Finish() {
 	r.clearThinkingLine()
+	if r.footnotes && len(r.citations) > 0 && r.answerBuf.Len() > 0 {
+		fmt.Fprint(r.out, "\n\n")
+		writeCitationFootnotes(r.out, r.citations)
+	}
+}
+
+func (r *chatStreamRenderer) Citations() []api.Citation {
+	return r.citations
+}
+
+// writeCitationFootnotes prints one footnote per citation: the source title
+// and ID when known, then the cited fragment. The last line has no trailing
+// newline.
+func writeCitationFootnotes(w io.Writer, citations []api.Citation) {
+	fmt.Fprint(w, "Sources:")
+	for _, c := range citations {
+		title := c.SourceTitle
+		switch {
+		case title != "":
+		case c.SourceID == "":
+			title = "(source unknown)"
+		default:
+			title = "(untitled source)"
+		}
+		fmt.Fprintf(w, "\n  [%d] %s", c.Number, title)
+		if c.SourceID != "" {
+			fmt.Fprintf(w, " (%s)", c.SourceID)
+		}
+		if c.Text != "" {
+			fmt.Fprintf(w, "\n      %q", truncateRunes(c.Text, 100))
+		}
+	}
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with "...".
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
 }
 
 func (r *chatStreamRenderer) Answer() string {
@@ -1843,9 +2177,12 @@ func (r *chatStreamRenderer) clearThinkingLine() {
 // streamChatResponse streams a chat response with phase-aware rendering.
 // Default: thinking headers shown on a single overwriting line in grey.
 // With --verbose: full thinking text streams in grey before the answer.
-// Final answer text streams normally. Returns the full answer and thinking trace.
-func streamChatResponse(c *api.Client, req api.ChatRequest) (answer, thinking string, err error) {
+// Final answer text streams normally, followed by citation footnotes
+// unless the output format is plain, which prints only the answer.
+// Returns the full answer, thinking trace and citations.
+func streamChatResponse(c *api.Client, req api.ChatRequest) (answer, thinking string, citations []api.Citation, err error) {
 	renderer := newChatStreamRenderer(os.Stdout, os.Stderr, showThinking || verbose || isTerminal(os.Stdout), verbose)
+	renderer.footnotes = outputFormat != "plain"
 
 	err = c.StreamChat(req, func(chunk api.ChatChunk) bool {
 		renderer.WriteChunk(chunk)
@@ -1854,7 +2191,7 @@ func streamChatResponse(c *api.Client, req api.ChatRequest) (answer, thinking st
 
 	renderer.Finish()
 
-	return renderer.Answer(), renderer.Thinking(), err
+	return renderer.Answer(), renderer.Thinking(), renderer.Citations(), err
 }
 
 func isTerminal(f *os.File) bool {
@@ -1862,14 +2199,15 @@ func isTerminal(f *os.File) bool {
 }
 
 // Generation operations
-func generateFreeFormChat(c *api.Client, projectID, prompt, format string) error {
+func generateFreeFormChat(c *api.Client, projectID, prompt string, sourceIDs []string, format string) error {
 	if format != "plain" {
 		fmt.Fprintf(os.Stderr, "Generating response for: %s\n", prompt)
 	}
 
-	answer, _, err := streamChatResponse(c, api.ChatRequest{
+	answer, _, _, err := streamChatResponse(c, api.ChatRequest{
 		ProjectID: projectID,
 		Prompt:    prompt,
+		SourceIDs: sourceIDs,
 	})
 	if err != nil {
 		return fmt.Errorf("generate chat: %w", err)
@@ -1903,6 +2241,7 @@ func setChatConfig(c *api.Client, args []string) error {
 		fmt.Fprintf(os.Stderr, "  length default            Reset to default response length\n")
 		fmt.Fprintf(os.Stderr, "  length longer             Set longer responses\n")
 		fmt.Fprintf(os.Stderr, "  length shorter            Set shorter responses\n")
+		fmt.Fprintf(os.Stderr, "  -from-profile             Apply the active profile's chat_goal and response_length\n")
 		return fmt.Errorf("invalid arguments")
 	}
 
@@ -1910,6 +2249,8 @@ func setChatConfig(c *api.Client, args []string) error {
 	setting := args[1]
 
 	switch setting {
+	case "-from-profile", "--from-profile":
+		return applyProfileChatConfig(c, notebookID)
 	case "goal":
 		if len(args) < 3 {
 			return fmt.Errorf("usage: nlm chat-config <id> goal <default|custom \"prompt\">")
@@ -1975,6 +2316,9 @@ func oneShotChat(c *api.Client, notebookID, prompt string) error {
 	if session.ConversationID == "" {
 		session.ConversationID = uuid.New().String()
 	}
+	if err := applySourceFilter(c, session); err != nil {
+		return err
+	}
 
 	// Add user message
 	session.Messages = append(session.Messages, ChatMessage{
@@ -1988,9 +2332,10 @@ func oneShotChat(c *api.Client, notebookID, prompt string) error {
 		ConversationID: session.ConversationID,
 		History:        wireHistory,
 		SeqNum:         len(session.Messages)/2 + 1,
+		SourceIDs:      session.SourceIDs,
 	}
 
-	answer, thinking, err := streamChatResponse(c, chatReq)
+	answer, thinking, citations, err := streamChatResponse(c, chatReq)
 	if err != nil {
 		response, chatErr := c.ChatWithHistory(chatReq)
 		if chatErr != nil {
@@ -2006,7 +2351,7 @@ func oneShotChat(c *api.Client, notebookID, prompt string) error {
 	if response != "" {
 		session.Messages = append(session.Messages, ChatMessage{
 			Role: "assistant", Content: response, Timestamp: time.Now(),
-			Thinking: thinking,
+			Thinking: thinking, Citations: citations,
 		})
 	}
 	session.UpdatedAt = time.Now()
@@ -2015,43 +2360,63 @@ func oneShotChat(c *api.Client, notebookID, prompt string) error {
 
 // interactiveChatWithConv starts or resumes an interactive chat with a specific conversation ID.
 func interactiveChatWithConv(c *api.Client, notebookID, conversationID string) error {
+	session, fromServer := resumeChatSession(c, notebookID, conversationID)
+	if fromServer > 0 {
+		fmt.Printf("Loaded %d messages from server history.\n", fromServer)
+	}
+	return runInteractiveChat(c, session)
+}
+
+// resumeChatSession returns the local session for a conversation, or one
+// populated from server history. fromServer is the number of messages
+// loaded from the server.
+func resumeChatSession(c *api.Client, notebookID, conversationID string) (session *ChatSession, fromServer int) {
 	// Try to load local session for this conversation
 	session, err := loadChatSessionForConv(notebookID, conversationID)
 	if err != nil {
+		session = newChatSession(notebookID)
 		// Try fetching server-side history
-		serverMsgs, fetchErr := c.GetConversationHistory(notebookID, conversationID)
+		serverSession, fetchErr := fetchServerChatSession(c, notebookID, conversationID)
 		if fetchErr != nil && debug {
 			fmt.Fprintf(os.Stderr, "nlm: could not fetch server history: %v\n", fetchErr)
 		}
-
-		session = &ChatSession{
-			NotebookID:     notebookID,
-			ConversationID: conversationID,
-			Messages:       []ChatMessage{},
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
-		}
-
-		// Populate from server history
-		if fetchErr == nil && len(serverMsgs) > 0 {
-			for _, m := range serverMsgs {
-				role := "user"
-				if m.Role == 2 {
-					role = "assistant"
-				}
-				session.Messages = append(session.Messages, ChatMessage{
-					Role:    role,
-					Content: m.Content,
-				})
-			}
-			fmt.Printf("Loaded %d messages from server history.\n", len(serverMsgs))
+		if fetchErr == nil {
+			session.Messages = append(session.Messages, serverSession.Messages...)
+			fromServer = len(serverSession.Messages)
 		}
 	}
 
 	// Override the conversation ID (the loaded session might have an old one)
 	session.ConversationID = conversationID
+	return session, fromServer
+}
 
-	return runInteractiveChat(c, session)
+// newChatSession starts a new conversation in a notebook.
+func newChatSession(notebookID string) *ChatSession {
+	return &ChatSession{
+		NotebookID:     notebookID,
+		ConversationID: uuid.New().String(),
+		Messages:       []ChatMessage{},
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+}
+
+// forkChatSession starts a new conversation that carries over a copy of
+// session's history.
+func forkChatSession(session *ChatSession) *ChatSession {
+	return forkChatSessionAt(session, len(session.Messages))
+}
+
+// forkChatSessionAt starts a new conversation that carries over the first n
+// messages of session and records session as its parent.
+func forkChatSessionAt(session *ChatSession, n int) *ChatSession {
+	fork := newChatSession(session.NotebookID)
+	fork.Messages = append(fork.Messages, session.Messages[:n]...)
+	fork.ParentID = session.ConversationID
+	fork.ForkPoint = n
+	fork.SourceIDs = session.SourceIDs
+	return fork
 }
 
 // listChatConversations lists server-side conversations for a notebook.
@@ -2147,6 +2512,14 @@ func deepResearch(c *api.Client, notebookID, query string) error {
 		fmt.Fprintf(os.Stderr, "Research ID: %s\n", researchID)
 	}
 
+	hook := newWebhookNotifier()
+	research := webhookPayload{NotebookID: notebookID, JobID: researchID, Kind: "deep_research"}
+	failed := func(err error) error {
+		research.Event, research.Status, research.Error = webhookFailed, "failed", err.Error()
+		hook.notify(research)
+		return err
+	}
+
 	// Poll for results
 	fmt.Fprintf(os.Stderr, "Polling for results")
 	for i := 0; i < 120; i++ { // max 10 minutes (120 * 5s)
@@ -2160,7 +2533,7 @@ func deepResearch(c *api.Client, notebookID, query string) error {
 		})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "\n")
-			return fmt.Errorf("poll deep research: %w", err)
+			return failed(fmt.Errorf("poll deep research: %w", err))
 		}
 
 		var pollData []interface{}
@@ -2175,12 +2548,14 @@ func deepResearch(c *api.Client, notebookID, query string) error {
 			// Extract and print the research content
 			// The content is typically a large text blob in the response
 			fmt.Println(string(pollResp))
+			research.Event, research.Status = webhookCompleted, "ready"
+			hook.notify(research)
 			return nil
 		}
 	}
 
 	fmt.Fprintf(os.Stderr, "\nResearch timed out after 10 minutes.\n")
-	return fmt.Errorf("research timed out")
+	return failed(fmt.Errorf("research timed out"))
 }
 
 func setInstructions(c *api.Client, notebookID, prompt string) error {
@@ -2383,10 +2758,13 @@ func getChatSessionPath(notebookID string) string {
 }
 
 func getChatSessionPathForConv(notebookID, conversationID string) string {
+	if len(conversationID) > 8 {
+		conversationID = conversationID[:8]
+	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		if conversationID != "" {
-			return filepath.Join(os.TempDir(), fmt.Sprintf("nlm-chat-%s-%s.json", notebookID, conversationID[:8]))
+			return filepath.Join(os.TempDir(), fmt.Sprintf("nlm-chat-%s-%s.json", notebookID, conversationID))
 		}
 		return filepath.Join(os.TempDir(), fmt.Sprintf("nlm-chat-%s.json", notebookID))
 	}
@@ -2394,7 +2772,7 @@ func getChatSessionPathForConv(notebookID, conversationID string) string {
 	nlmDir := filepath.Join(homeDir, ".nlm")
 	os.MkdirAll(nlmDir, 0700) // Ensure directory exists
 	if conversationID != "" {
-		return filepath.Join(nlmDir, fmt.Sprintf("chat-%s-%s.json", notebookID, conversationID[:8]))
+		return filepath.Join(nlmDir, fmt.Sprintf("chat-%s-%s.json", notebookID, conversationID))
 	}
 	return filepath.Join(nlmDir, fmt.Sprintf("chat-%s.json", notebookID))
 }
@@ -2444,30 +2822,48 @@ func listLocalChatSessions(notebookID string) ([]ChatSession, error) {
 	return sessions, nil
 }
 
+// loadChatSession returns the notebook's most recently updated session.
 func loadChatSession(notebookID string) (*ChatSession, error) {
-	path := getChatSessionPath(notebookID)
-	data, err := os.ReadFile(path)
+	sessions, err := listLocalChatSessions(notebookID)
 	if err != nil {
 		return nil, err
 	}
-
-	var session ChatSession
-	if err := json.Unmarshal(data, &session); err != nil {
-		return nil, err
+	var latest *ChatSession
+	for i := range sessions {
+		if latest == nil || sessions[i].UpdatedAt.After(latest.UpdatedAt) {
+			latest = &sessions[i]
+		}
 	}
-
-	return &session, nil
+	if latest == nil {
+		return nil, fmt.Errorf("no chat session for notebook %s: %w", notebookID, os.ErrNotExist)
+	}
+	return latest, nil
 }
 
+// saveChatSession writes session to its per-conversation file. A session
+// stored in the notebook's legacy single-session file moves out of it.
 func saveChatSession(session *ChatSession) error {
 	path := getChatSessionPath(session.NotebookID)
+	if session.ConversationID != "" {
+		path = getChatSessionPathForConv(session.NotebookID, session.ConversationID)
+	}
 
 	data, err := json.MarshalIndent(session, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0600)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	if legacy := getChatSessionPath(session.NotebookID); legacy != path {
+		var old ChatSession
+		if data, err := os.ReadFile(legacy); err == nil && json.Unmarshal(data, &old) == nil &&
+			old.ConversationID == session.ConversationID {
+			os.Remove(legacy)
+		}
+	}
+	return nil
 }
 
 func listChatSessions() error {
@@ -2550,6 +2946,19 @@ func buildWireHistory(session *ChatSession) []api.ChatMessage {
 	return history
 }
 
+// nextChatRequest builds the request for the session's last message, the
+// user's new prompt, with the earlier messages as history.
+func nextChatRequest(session *ChatSession) api.ChatRequest {
+	return api.ChatRequest{
+		ProjectID:      session.NotebookID,
+		Prompt:         session.Messages[len(session.Messages)-1].Content,
+		ConversationID: session.ConversationID,
+		History:        buildWireHistory(session),
+		SeqNum:         len(session.Messages)/2 + 1,
+		SourceIDs:      session.SourceIDs,
+	}
+}
+
 func getFallbackResponse(input, notebookID string) string {
 	lowerInput := strings.ToLower(input)
 
@@ -2584,34 +2993,40 @@ func getFallbackResponse(input, notebookID string) string {
 
 // interactiveChat starts a new or resumes the default interactive chat session for a notebook.
 func interactiveChat(c *api.Client, notebookID string) error {
+	return runInteractiveChat(c, currentChatSession(notebookID))
+}
+
+// currentChatSession returns the notebook's stored session, or a new one.
+func currentChatSession(notebookID string) *ChatSession {
 	session, err := loadChatSession(notebookID)
 	if err != nil {
-		session = &ChatSession{
-			NotebookID:     notebookID,
-			ConversationID: uuid.New().String(),
-			Messages:       []ChatMessage{},
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
-		}
+		return newChatSession(notebookID)
 	}
 	if session.ConversationID == "" {
 		session.ConversationID = uuid.New().String()
 	}
-	return runInteractiveChat(c, session)
+	return session
 }
 
 // runInteractiveChat runs the interactive chat loop with the given session.
 func runInteractiveChat(c *api.Client, session *ChatSession) error {
 	notebookID := session.NotebookID
+	if err := applySourceFilter(c, session); err != nil {
+		return err
+	}
 
 	fmt.Println("\nNotebookLM Interactive Chat")
 	fmt.Println("================================")
 	fmt.Printf("Notebook: %s\n", notebookID)
+	nbLabel := notebookLabel(notebookID)
 	convShort := session.ConversationID
 	if len(convShort) > 8 {
 		convShort = convShort[:8]
 	}
 	fmt.Printf("Conversation: %s\n", convShort)
+	if len(session.SourceIDs) > 0 {
+		fmt.Printf("Sources: %d selected (use /sources to change)\n", len(session.SourceIDs))
+	}
 
 	if len(session.Messages) > 0 {
 		fmt.Printf("Chat history: %d messages (started %s)\n",
@@ -2622,7 +3037,7 @@ func runInteractiveChat(c *api.Client, session *ChatSession) error {
 		}
 	}
 
-	fmt.Println("\nCommands: /exit /clear /history /reset /new /fork /conversations /save /help /multiline")
+	fmt.Println("\nCommands: /exit /clear /history /reset /new /fork /tree /checkout /sources /conversations /save /prompt /help /multiline")
 	fmt.Println("Type your message and press Enter to send.")
 
 	scanner := bufio.NewScanner(os.Stdin)
@@ -2637,9 +3052,9 @@ func runInteractiveChat(c *api.Client, session *ChatSession) error {
 	for {
 		historyCount := len(session.Messages)
 		if multiline {
-			fmt.Printf("[%s %d msgs] (multiline) > ", convShort, historyCount)
+			fmt.Printf("[%s %s %d msgs] (multiline) > ", nbLabel, convShort, historyCount)
 		} else {
-			fmt.Printf("[%s %d msgs] > ", convShort, historyCount)
+			fmt.Printf("[%s %s %d msgs] > ", nbLabel, convShort, historyCount)
 		}
 
 		var input string
@@ -2666,6 +3081,47 @@ func runInteractiveChat(c *api.Client, session *ChatSession) error {
 			continue
 		}
 
+		if fields := strings.Fields(input); fields[0] == "/prompt" {
+			prompt, err := chatPromptCommand(c, notebookID, fields[1:])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			if prompt == "" {
+				continue
+			}
+			fmt.Printf("%s%s%s\n", ansiGrey, prompt, ansiReset)
+			input = prompt
+		}
+		if fields := strings.Fields(input); fields[0] == "/sources" {
+			if err := chatSourcesCommand(os.Stdout, c, session, fields[1:]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			continue
+		}
+		if fields := strings.Fields(input); fields[0] == "/checkout" {
+			if len(fields) != 2 {
+				fmt.Println("usage: /checkout <conversation-id>[@n]")
+				continue
+			}
+			if err := saveChatSession(session); err != nil && debug {
+				fmt.Fprintf(os.Stderr, "Debug: save failed: %v\n", err)
+			}
+			next, err := checkoutChatNode(notebookID, fields[1])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			session = next
+			convShort = shortConversationID(session.ConversationID)
+			if strings.Contains(fields[1], "@") {
+				fmt.Printf("Checked out %s at message %d -> %s\n",
+					shortConversationID(session.ParentID), session.ForkPoint, convShort)
+			} else {
+				fmt.Printf("Switched to conversation %s (%d messages)\n", convShort, len(session.Messages))
+			}
+			continue
+		}
+
 		switch strings.ToLower(input) {
 		case "/exit", "/quit":
 			fmt.Println("\nSaving session and goodbye!")
@@ -2697,13 +3153,9 @@ func runInteractiveChat(c *api.Client, session *ChatSession) error {
 			if err := saveChatSession(session); err != nil && debug {
 				fmt.Fprintf(os.Stderr, "Debug: save failed: %v\n", err)
 			}
-			session = &ChatSession{
-				NotebookID:     notebookID,
-				ConversationID: uuid.New().String(),
-				Messages:       []ChatMessage{},
-				CreatedAt:      time.Now(),
-				UpdatedAt:      time.Now(),
-			}
+			sourceIDs := session.SourceIDs
+			session = newChatSession(notebookID)
+			session.SourceIDs = sourceIDs
 			convShort = session.ConversationID[:8]
 			fmt.Printf("Started new conversation: %s\n", convShort)
 			continue
@@ -2713,19 +3165,19 @@ func runInteractiveChat(c *api.Client, session *ChatSession) error {
 				fmt.Fprintf(os.Stderr, "Debug: save failed: %v\n", err)
 			}
 			oldShort := convShort
-			// Deep copy messages
-			forkedMsgs := make([]ChatMessage, len(session.Messages))
-			copy(forkedMsgs, session.Messages)
-			session = &ChatSession{
-				NotebookID:     notebookID,
-				ConversationID: uuid.New().String(),
-				Messages:       forkedMsgs,
-				CreatedAt:      time.Now(),
-				UpdatedAt:      time.Now(),
-			}
+			session = forkChatSession(session)
 			convShort = session.ConversationID[:8]
 			fmt.Printf("Forked from %s -> %s (%d messages carried over)\n",
-				oldShort, convShort, len(forkedMsgs))
+				oldShort, convShort, len(session.Messages))
+			continue
+		case "/tree":
+			if err := saveChatSession(session); err != nil && debug {
+				fmt.Fprintf(os.Stderr, "Debug: save failed: %v\n", err)
+			}
+			fmt.Println()
+			if err := showChatTree(os.Stdout, notebookID, session.ConversationID); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
 			continue
 		case "/conversations":
 			convIDs, err := c.GetConversations(notebookID)
@@ -2766,8 +3218,12 @@ func runInteractiveChat(c *api.Client, session *ChatSession) error {
 			fmt.Println("  /reset             - Clear history and start new conversation")
 			fmt.Println("  /new               - Start a new conversation (keeps old one)")
 			fmt.Println("  /fork              - Fork: new conversation with current history")
+			fmt.Println("  /tree              - Show the conversation fork graph")
+			fmt.Println("  /checkout <id>[@n] - Switch conversation, or fork it after message n")
+			fmt.Println("  /sources [n|all]   - List sources, or toggle which ones are active")
 			fmt.Println("  /conversations     - List server-side conversations")
 			fmt.Println("  /save              - Save current session")
+			fmt.Println("  /prompt [name]     - Send a prompt template (list, show, edit)")
 			fmt.Println("  /multiline         - Toggle multiline mode")
 			fmt.Println("  /help              - Show this help")
 			continue
@@ -2788,17 +3244,10 @@ func runInteractiveChat(c *api.Client, session *ChatSession) error {
 		}
 		session.Messages = append(session.Messages, userMsg)
 
-		wireHistory := buildWireHistory(session)
-		chatReq := api.ChatRequest{
-			ProjectID:      notebookID,
-			Prompt:         input,
-			ConversationID: session.ConversationID,
-			History:        wireHistory,
-			SeqNum:         len(session.Messages)/2 + 1,
-		}
+		chatReq := nextChatRequest(session)
 
 		fmt.Println()
-		answer, thinking, err := streamChatResponse(c, chatReq)
+		answer, thinking, citations, err := streamChatResponse(c, chatReq)
 
 		if err != nil {
 			response, chatErr := c.ChatWithHistory(chatReq)
@@ -2820,7 +3269,7 @@ func runInteractiveChat(c *api.Client, session *ChatSession) error {
 			if response != "" {
 				session.Messages = append(session.Messages, ChatMessage{
 					Role: "assistant", Content: response, Timestamp: time.Now(),
-					Thinking: thinking,
+					Thinking: thinking, Citations: citations,
 				})
 			}
 		}
@@ -2910,6 +3359,9 @@ func createVideoOverview(c *api.Client, projectID string, instructions string) e
 	if !result.IsReady {
 		fmt.Println("✅ Video overview creation started. Video generation may take several minutes.")
 		fmt.Printf("  Project ID: %s\n", result.ProjectID)
+		if result.VideoID != "" {
+			fmt.Printf("  Video ID: %s\n", result.VideoID)
+		}
 		return nil
 	}
 
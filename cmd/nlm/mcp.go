@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	runtimedebug "runtime/debug"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tmc/nlm/internal/nlmmcp"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// nlm mcp serves the MCP server on stdin/stdout, or with -http to any
+// number of clients over the streamable HTTP and legacy SSE transports.
+
+type mcpOptions struct {
+	HTTP           string
+	Token          string
+	AllowOrigin    string
+	SessionTimeout time.Duration
+}
+
+func parseMCPArgs(args []string) (mcpOptions, error) {
+	opts := mcpOptions{Token: os.Getenv("NLM_MCP_TOKEN"), SessionTimeout: 30 * time.Minute}
+	flags := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&opts.HTTP, "http", "", "serve streamable HTTP (/mcp) and SSE (/sse) on this address instead of stdio")
+	flags.StringVar(&opts.Token, "token", opts.Token, "bearer token HTTP clients must send (or NLM_MCP_TOKEN; default: the nlm serve token)")
+	flags.StringVar(&opts.AllowOrigin, "allow-origin", "", "comma-separated origins browser clients may connect from, or *")
+	flags.DurationVar(&opts.SessionTimeout, "session-timeout", opts.SessionTimeout, "close HTTP sessions idle this long (0: never)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nlm mcp [-http addr] [-token token] [-allow-origin origins] [-session-timeout 30m]\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		return opts, fmt.Errorf("invalid arguments")
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return opts, fmt.Errorf("invalid arguments")
+	}
+	return opts, nil
+}
+
+func runMCP(client *api.Client, opts mcpOptions) error {
+	info, ok := runtimedebug.ReadBuildInfo()
+	version := "devel"
+	if ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+	impl := &mcp.Implementation{
+		Name:    "nlm",
+		Version: version,
+	}
+	if opts.HTTP == "" {
+		return nlmmcp.Run(context.Background(), client, impl)
+	}
+
+	token := opts.Token
+	if token == "" {
+		var path string
+		var err error
+		if token, path, err = loadServeToken(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "nlm: bearer token in %s\n", path)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	h := nlmmcp.Handler(nlmmcp.New(client, impl), nlmmcp.HTTPOptions{
+		Token:          token,
+		AllowedOrigins: splitList(opts.AllowOrigin),
+		SessionTimeout: opts.SessionTimeout,
+	})
+	return serveHTTP(ctx, opts.HTTP, h, "MCP (streamable HTTP at /mcp, SSE at /sse)")
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// promptTemplate is a reusable chat prompt stored in ~/.nlm/prompts as
+// <name>.tmpl. The file is a Go text/template, optionally preceded by YAML
+// front matter giving a description and default variable values:
+//
+//	---
+//	description: Quarterly risk review
+//	vars:
+//	  quarter: Q1
+//	---
+//	List the risks for {{.Vars.quarter}} raised in "{{.Notebook.Title}}":
+//	{{range .Sources}}- {{.}}
+//	{{end}}
+//
+// Templates see promptData. Referring to a variable that is neither passed
+// nor defaulted is an error.
+type promptTemplate struct {
+	Name        string
+	Description string
+	Vars        map[string]string // defaults
+	Body        string
+	tmpl        *template.Template
+}
+
+// promptFrontMatter is the YAML front matter of a prompt file.
+type promptFrontMatter struct {
+	Description string            `yaml:"description"`
+	Vars        map[string]string `yaml:"vars"` // defaults
+}
+
+// promptData is the data a prompt template is executed with.
+type promptData struct {
+	Notebook struct {
+		ID    string
+		Title string
+	}
+	Sources []string // source titles
+	Notes   []string // note titles; loaded only if the template uses .Notes
+	Vars    map[string]string
+	Date    string // today, as 2006-01-02
+}
+
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+var promptNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// promptDir returns ~/.nlm/prompts.
+func promptDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(home, ".nlm", "prompts"), nil
+}
+
+func promptPath(name string) (string, error) {
+	if !promptNamePattern.MatchString(name) || strings.HasSuffix(name, ".tmpl") {
+		return "", fmt.Errorf("invalid prompt name %q", name)
+	}
+	dir, err := promptDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".tmpl"), nil
+}
+
+// loadPrompt reads and parses the named prompt.
+func loadPrompt(name string) (*promptTemplate, error) {
+	path, err := promptPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no prompt %q in %s (see nlm prompts)", name, filepath.Dir(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := parsePrompt(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// parsePrompt parses a prompt file: optional front matter, then the
+// template.
+func parsePrompt(name string, data []byte) (*promptTemplate, error) {
+	p := &promptTemplate{Name: name, Vars: map[string]string{}}
+	body := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if rest, ok := strings.CutPrefix(body, "---\n"); ok {
+		front, after, ok := strings.Cut(rest, "\n---\n")
+		if !ok {
+			front, ok = strings.CutSuffix(rest, "\n---")
+			after = ""
+		}
+		if !ok {
+			return nil, fmt.Errorf("front matter is not closed with ---")
+		}
+		var fm promptFrontMatter
+		if err := decodeYAML([]byte(front), &fm); err != nil {
+			// Front matter starts on line 2.
+			return nil, offsetYAMLError(err, 1)
+		}
+		p.Description = fm.Description
+		for k, v := range fm.Vars {
+			p.Vars[k] = v
+		}
+		body = after
+	}
+	p.Body = body
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	p.tmpl = tmpl
+	return p, nil
+}
+
+// offsetYAMLError shifts the "line N: msg" line number of a decodeYAML
+// error.
+func offsetYAMLError(err error, offset int) error {
+	var n int
+	if _, scanErr := fmt.Sscanf(err.Error(), "line %d:", &n); scanErr != nil {
+		return err
+	}
+	_, msg, _ := strings.Cut(err.Error(), ":")
+	return fmt.Errorf("line %d:%s", n+offset, msg)
+}
+
+var missingKeyPattern = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
+// render executes the template. vars override the template's defaults.
+func (p *promptTemplate) render(data promptData, vars map[string]string) (string, error) {
+	merged := make(map[string]string, len(p.Vars)+len(vars))
+	for k, v := range p.Vars {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	data.Vars = merged
+	if data.Date == "" {
+		data.Date = time.Now().Format("2006-01-02")
+	}
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		if m := missingKeyPattern.FindStringSubmatch(err.Error()); m != nil {
+			return "", fmt.Errorf("prompt %s: missing variable %q (pass -var %s=...)", p.Name, m[1], m[1])
+		}
+		return "", fmt.Errorf("prompt %s: %w", p.Name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// usesNotes reports whether the template refers to .Notes, which costs an
+// extra request to load.
+func (p *promptTemplate) usesNotes() bool {
+	return strings.Contains(p.Body, ".Notes")
+}
+
+// parsePromptArgs parses "@name [-var k=v | k=v]... [text...]" as typed
+// after a notebook ID or after /prompt. Text that is not a variable is
+// appended to the rendered prompt.
+func parsePromptArgs(args []string) (name string, vars map[string]string, extra string, err error) {
+	if len(args) == 0 {
+		return "", nil, "", fmt.Errorf("missing prompt name")
+	}
+	name = strings.TrimPrefix(args[0], "@")
+	vars = make(map[string]string)
+	var text []string
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		var kv string
+		switch {
+		case arg == "-var" || arg == "--var":
+			if i+1 >= len(args) {
+				return "", nil, "", fmt.Errorf("%s requires name=value", arg)
+			}
+			i++
+			kv = args[i]
+		case strings.HasPrefix(arg, "-var="), strings.HasPrefix(arg, "--var="):
+			_, kv, _ = strings.Cut(arg, "=")
+		case len(text) == 0 && strings.Contains(arg, "=") && !strings.HasPrefix(arg, "="):
+			kv = arg
+		default:
+			text = append(text, arg)
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return "", nil, "", fmt.Errorf("invalid variable %q (want name=value)", kv)
+		}
+		vars[k] = v
+	}
+	return name, vars, strings.Join(text, " "), nil
+}
+
+// fetchPromptData loads the notebook details a template can refer to.
+func fetchPromptData(c *api.Client, notebookID string, withNotes bool) (promptData, error) {
+	var data promptData
+	data.Notebook.ID = notebookID
+	p, err := c.GetProject(notebookID)
+	if err != nil {
+		return data, fmt.Errorf("get notebook: %w", err)
+	}
+	data.Notebook.Title = strings.TrimSpace(p.GetTitle())
+	for _, src := range p.GetSources() {
+		data.Sources = append(data.Sources, strings.TrimSpace(src.GetTitle()))
+	}
+	if withNotes {
+		notes, err := c.GetNotes(notebookID)
+		if err != nil {
+			return data, fmt.Errorf("get notes: %w", err)
+		}
+		for _, n := range notes {
+			data.Notes = append(data.Notes, strings.TrimSpace(n.GetTitle()))
+		}
+	}
+	return data, nil
+}
+
+// expandPrompt renders a prompt invocation ("@name -var k=v ...") for a
+// notebook.
+func expandPrompt(c *api.Client, notebookID string, args []string) (string, error) {
+	name, vars, extra, err := parsePromptArgs(args)
+	if err != nil {
+		return "", err
+	}
+	p, err := loadPrompt(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := fetchPromptData(c, notebookID, p.usesNotes())
+	if err != nil {
+		return "", err
+	}
+	text, err := p.render(data, vars)
+	if err != nil {
+		return "", err
+	}
+	if extra != "" {
+		text += "\n\n" + extra
+	}
+	return text, nil
+}
+
+// chatPromptCommand handles "/prompt ..." in interactive chat. It returns
+// the rendered prompt to send, or "" if the command only managed the
+// library.
+func chatPromptCommand(c *api.Client, notebookID string, args []string) (string, error) {
+	switch {
+	case len(args) == 0 || args[0] == "list":
+		return "", listPrompts(os.Stdout)
+	case args[0] == "show" || args[0] == "edit":
+		if len(args) != 2 {
+			return "", fmt.Errorf("usage: /prompt %s <name>", args[0])
+		}
+		return "", runPromptsCommand(os.Stdout, args)
+	}
+	return expandPrompt(c, notebookID, args)
+}
+
+// checkPromptArgs reports a bad prompt invocation before any request is
+// made.
+func checkPromptArgs(args []string) error {
+	name, _, _, err := parsePromptArgs(args)
+	if err != nil {
+		return err
+	}
+	_, err = loadPrompt(name)
+	return err
+}
+
+// listPrompts prints the prompt library.
+func listPrompts(w io.Writer) error {
+	dir, err := promptDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".tmpl"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Fprintf(w, "No prompts in %s. Create one with: nlm prompts edit <name>\n", dir)
+		return nil
+	}
+	slices.Sort(names)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVARS\tDESCRIPTION")
+	for _, name := range names {
+		p, err := loadPrompt(name)
+		if err != nil {
+			fmt.Fprintf(tw, "%s\t-\t(error: %v)\n", name, err)
+			continue
+		}
+		vars := make([]string, 0, len(p.Vars))
+		for k, v := range p.Vars {
+			vars = append(vars, k+"="+v)
+		}
+		slices.Sort(vars)
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, firstNonEmpty(strings.Join(vars, " "), "-"), p.Description)
+	}
+	return tw.Flush()
+}
+
+const promptSkeleton = `---
+description: Describe what this prompt asks
+vars:
+  focus: key risks
+---
+Using the sources in "{{.Notebook.Title}}", summarize the {{.Vars.focus}}.
+Sources: {{join .Sources ", "}}
+`
+
+// editPrompt opens the named prompt in $VISUAL or $EDITOR, creating it from
+// a skeleton if needed, and checks that the result parses.
+func editPrompt(name string) error {
+	path, err := promptPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(promptSkeleton), 0o644); err != nil {
+			return err
+		}
+	}
+	editor := firstNonEmpty(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi")
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run editor: %w", err)
+	}
+	if _, err := loadPrompt(name); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved prompt %s (%s)\n", name, path)
+	return nil
+}
+
+// runPromptsCommand implements nlm prompts [list | show <name> | edit <name> | rm <name>].
+func runPromptsCommand(w io.Writer, args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		return listPrompts(w)
+	}
+	name := args[1]
+	switch args[0] {
+	case "show":
+		path, err := promptPath(name)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no prompt %q in %s", name, filepath.Dir(path))
+		} else if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case "edit":
+		return editPrompt(name)
+	case "rm":
+		path, err := promptPath(name)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no prompt %q", name)
+		} else if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Removed prompt %s\n", name)
+		return nil
+	}
+	return fmt.Errorf("unknown prompts subcommand %q", args[0])
+}
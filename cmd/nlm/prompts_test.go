@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPromptFile = `---
+description: Quarterly risk review
+vars:
+  quarter: Q1
+  tone: neutral
+---
+Review {{.Notebook.Title}} for {{.Vars.quarter}} risks in a {{.Vars.tone}} tone.
+Sources: {{join .Sources ", "}}
+`
+
+func TestParsePrompt(t *testing.T) {
+	p, err := parsePrompt("risk-review", []byte(testPromptFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Description != "Quarterly risk review" || p.Vars["quarter"] != "Q1" || p.usesNotes() {
+		t.Errorf("prompt = %+v", p)
+	}
+	var data promptData
+	data.Notebook.Title = "Ops"
+	data.Sources = []string{"Runbook", "Postmortems"}
+	got, err := p.render(data, map[string]string{"quarter": "Q3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Review Ops for Q3 risks in a neutral tone.\nSources: Runbook, Postmortems"
+	if got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+func TestParsePromptErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name, file, want string
+	}{
+		{"unclosed", "---\ndescription: x\n", "not closed"},
+		{"unknown key", "---\nmodel: x\n---\nhi", `line 2: unknown key "model"`},
+		{"vars type", "---\nvars: [a, b]\n---\nhi", "line 2: "},
+		{"yaml line", "---\ndescription: x\n\tvars: y\n---\nhi", "line 3:"},
+		{"template", "{{.Vars.x", "unclosed action"},
+	} {
+		if _, err := parsePrompt("p", []byte(tt.file)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPromptMissingVariable(t *testing.T) {
+	p, err := parsePrompt("p", []byte("Report for {{.Vars.quarter}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.render(promptData{}, nil)
+	if err == nil || !strings.Contains(err.Error(), `missing variable "quarter" (pass -var quarter=...)`) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestParsePromptArgs(t *testing.T) {
+	name, vars, extra, err := parsePromptArgs([]string{"@risk-review", "-var", "quarter=Q3", "--var=team=ops", "tone=blunt", "focus", "on", "a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "risk-review" || vars["quarter"] != "Q3" || vars["team"] != "ops" || vars["tone"] != "blunt" {
+		t.Errorf("name = %q, vars = %v", name, vars)
+	}
+	if extra != "focus on a=b" {
+		t.Errorf("extra = %q", extra)
+	}
+	if _, _, _, err := parsePromptArgs([]string{"@p", "-var", "novalue"}); err == nil {
+		t.Error("want error for -var without =")
+	}
+}
+
+func TestPromptLibrary(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".nlm", "prompts")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "risk-review.tmpl"), []byte(testPromptFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte("{{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := runPromptsCommand(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "broken ") || !strings.Contains(lines[1], "(error:") {
+		t.Fatalf("list:\n%s", buf.String())
+	}
+	if f := strings.Fields(lines[2]); f[0] != "risk-review" || f[1] != "quarter=Q1" || f[2] != "tone=neutral" {
+		t.Errorf("list row = %q", lines[2])
+	}
+
+	if _, err := loadPrompt("../risk-review"); err == nil {
+		t.Error("loadPrompt accepted a path")
+	}
+	if err := runPromptsCommand(&buf, []string{"rm", "broken"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPrompt("broken"); err == nil || !strings.Contains(err.Error(), `no prompt "broken"`) {
+		t.Errorf("after rm: %v", err)
+	}
+}
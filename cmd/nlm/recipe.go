@@ -0,0 +1,584 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+	"gopkg.in/yaml.v3"
+)
+
+// A recipe is a declarative list of nlm commands, read by nlm run:
+//
+//	name: Weekly digest
+//	vars:
+//	  title: Weekly digest
+//	steps:
+//	  - id: create
+//	    run: create "${vars.title}"
+//	  - run: add ${steps.create.notebook_id} ${item}
+//	    foreach: [https://example.com/a, https://example.com/b]
+//	  - id: overview
+//	    run: create-audio ${steps.create.notebook_id} "Focus on what changed"
+//	  - id: audio
+//	    wait: {notebook: "${steps.create.notebook_id}", artifact: "${steps.overview.artifact_id}", timeout: 20m}
+//	    continue-on-error: true
+//	  - run: audio-download ${steps.create.notebook_id} digest.mp3
+//	    if: ${steps.audio.state} == READY
+//
+// Each run step is dispatched through runCmd. A step's standard output is
+// captured as ${steps.<id>.output}, with the last ID it printed as
+// ${steps.<id>.id} (and notebook_id, source_id or artifact_id where the
+// command creates one). ${steps.<id>.status} is ok, failed or skipped.
+// A wait on type alone matches any artifact of that type, including one
+// that was ready before the recipe ran, so waits name the artifact where
+// they can.
+type recipe struct {
+	Name  string            `yaml:"name"`
+	Vars  map[string]string `yaml:"vars"`
+	Steps []*recipeStep     `yaml:"steps"`
+}
+
+type recipeStep struct {
+	ID              string      `yaml:"id"`
+	Run             commandLine `yaml:"run"`     // command and arguments, before substitution
+	If              string      `yaml:"if"`      // condition: "a == b", "a != b", or a single value
+	ForEach         []string    `yaml:"foreach"` // run once per item, as ${item}
+	ContinueOnError bool        `yaml:"continue-on-error"`
+	Wait            *recipeWait `yaml:"wait"`
+}
+
+// recipeWait polls a notebook's artifacts until one reaches a state.
+type recipeWait struct {
+	Notebook string        `yaml:"notebook"`
+	Artifact string        `yaml:"artifact"` // artifact ID; empty matches any artifact of Type
+	Type     string        `yaml:"type"`     // artifact type, e.g. audio, video, report
+	State    string        `yaml:"state"`    // target state, default READY
+	Timeout  time.Duration `yaml:"timeout"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// commandLine is a run: command, written either as a list of words or as
+// a single string split like a shell command line.
+type commandLine []string
+
+func (c *commandLine) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		words, err := splitCommandLine(n.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n.Line, err)
+		}
+		*c = words
+		return nil
+	}
+	var words []string
+	if err := n.Decode(&words); err != nil {
+		return err
+	}
+	*c = words
+	return nil
+}
+
+// recipeIDNames lists the commands whose new ID is exposed under a
+// specific name in addition to ${steps.<id>.id}.
+var recipeIDNames = map[string]string{
+	"create":        "notebook_id",
+	"add":           "source_id",
+	"create-audio":  "artifact_id",
+	"create-video":  "artifact_id",
+	"create-slides": "artifact_id",
+}
+
+// recipeForbidden lists commands that cannot run inside a recipe because
+// they are interactive, long-running or handled before runCmd.
+var recipeForbidden = []string{
+	"run", "help", "-h", "--help", "auth", "refresh", "completion", "config", "current",
+	"tui", "mcp", "serve", "serve-openai", "grpc-serve", "chat-server", "audio-interactive", "webhook-test",
+}
+
+func loadRecipe(path string) (*recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parseRecipe(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// parseRecipe decodes and checks the recipe document data.
+func parseRecipe(data []byte) (*recipe, error) {
+	r := &recipe{}
+	if err := decodeYAML(data, r); err != nil {
+		return nil, err
+	}
+	if r.Vars == nil {
+		r.Vars = map[string]string{}
+	}
+	if len(r.Steps) == 0 {
+		return nil, fmt.Errorf("recipe has no steps")
+	}
+	ids := map[string]bool{}
+	for i, step := range r.Steps {
+		if err := step.check(); err != nil {
+			return nil, fmt.Errorf("step %d: %v", i+1, err)
+		}
+		if step.ID == "" {
+			step.ID = fmt.Sprint(i + 1)
+		}
+		if ids[step.ID] {
+			return nil, fmt.Errorf("step %d: duplicate id %q", i+1, step.ID)
+		}
+		ids[step.ID] = true
+	}
+	return r, nil
+}
+
+// check validates a decoded step and fills in the wait defaults.
+func (step *recipeStep) check() error {
+	switch {
+	case step == nil:
+		return fmt.Errorf("step is empty")
+	case len(step.Run) == 0 && step.Wait == nil:
+		return fmt.Errorf("step needs run or wait")
+	case len(step.Run) > 0 && step.Wait != nil:
+		return fmt.Errorf("step has both run and wait")
+	case len(step.Run) > 0 && slices.Contains(recipeForbidden, step.Run[0]):
+		return fmt.Errorf("command %q cannot be used in a recipe", step.Run[0])
+	case len(step.Run) > 0 && !isValidCommand(step.Run[0]):
+		return fmt.Errorf("unknown command %q", step.Run[0])
+	}
+	w := step.Wait
+	if w == nil {
+		return nil
+	}
+	if w.Notebook == "" {
+		return fmt.Errorf("wait needs a notebook")
+	}
+	if w.Artifact == "" && w.Type == "" {
+		return fmt.Errorf("wait needs an artifact or type")
+	}
+	w.State = strings.ToUpper(cmp.Or(w.State, "READY"))
+	w.Timeout = cmp.Or(w.Timeout, 30*time.Minute)
+	w.Interval = cmp.Or(w.Interval, 15*time.Second)
+	return nil
+}
+
+// decodeYAML decodes the YAML document data into v. Keys that v does not
+// define are errors, and errors have the form "line N: msg".
+func decodeYAML(data []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(v)
+	if err == nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+	m := yamlErrorPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	msg := yamlFieldPattern.ReplaceAllString(m[2], `unknown key "$1"`)
+	return fmt.Errorf("line %s: %s", m[1], msg)
+}
+
+var (
+	yamlErrorPattern = regexp.MustCompile(`^yaml: (?:unmarshal errors:\n\s*)?line (\d+): (.*)`)
+	yamlFieldPattern = regexp.MustCompile(`^field (\S+) not found in type .*$`)
+)
+
+// splitCommandLine splits s into words like a shell would, honoring single
+// and double quotes and backslash escapes, but without any expansion.
+func splitCommandLine(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, s)
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// recipeRunner executes a recipe. exec and wait are the side effects, so
+// tests can replace them.
+type recipeRunner struct {
+	out  io.Writer
+	exec func(args []string) (stdout string, err error)
+	wait func(w recipeWait) (map[string]string, error)
+
+	vars  map[string]string
+	steps map[string]map[string]string
+}
+
+// recipeResult is one line of the summary report.
+type recipeResult struct {
+	ID       string
+	Command  string
+	Status   string
+	Duration time.Duration
+	Err      error
+}
+
+// runRecipe runs the recipe at path. overrides are name=value pairs that
+// replace the recipe's vars.
+func runRecipe(c *api.Client, path string, overrides []string) error {
+	r, err := loadRecipe(path)
+	if err != nil {
+		return err
+	}
+	rr := &recipeRunner{
+		out: os.Stdout,
+		exec: func(args []string) (string, error) {
+			return captureStdout(func() error {
+				cmdArgs := withCurrentNotebook(args[0], args[1:])
+				if err := validateArgs(args[0], cmdArgs); err != nil {
+					return err
+				}
+				return runCmd(c, args[0], cmdArgs...)
+			})
+		},
+		wait: func(w recipeWait) (map[string]string, error) {
+			return waitForArtifact(c, w)
+		},
+	}
+	for _, kv := range overrides {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("invalid variable %q (want name=value)", kv)
+		}
+		r.Vars[name] = value
+	}
+	results := rr.run(r)
+	rr.report(r, results)
+
+	var failed []string
+	for _, res := range results {
+		if res.Status == "failed" {
+			failed = append(failed, res.ID)
+		}
+	}
+	// The step errors are in the report. They are not wrapped here, so an
+	// expired session does not make run() retry the whole recipe.
+	if len(failed) > 0 {
+		return fmt.Errorf("recipe failed: step %s failed", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// run executes the steps in order and returns one result per step. A failed
+// step stops the recipe unless it has continue-on-error; the remaining steps
+// are reported as skipped.
+func (rr *recipeRunner) run(r *recipe) []recipeResult {
+	rr.vars = r.Vars
+	rr.steps = map[string]map[string]string{}
+	var results []recipeResult
+	stopped := false
+	for _, step := range r.Steps {
+		res := recipeResult{ID: step.ID, Command: step.describe()}
+		outputs := map[string]string{}
+		rr.steps[step.ID] = outputs
+
+		run := !stopped
+		if run && step.If != "" {
+			cond, err := rr.expand(step.If, "")
+			if err == nil {
+				run = evalRecipeCondition(cond)
+			} else {
+				res.Err = err
+			}
+		}
+		switch {
+		case res.Err != nil:
+			res.Status = "failed"
+		case !run:
+			res.Status = "skipped"
+		default:
+			start := time.Now()
+			res.Err = rr.runStep(step, outputs)
+			res.Duration = time.Since(start)
+			if cmd, ok := outputs["command"]; ok && len(step.ForEach) == 0 {
+				res.Command = cmd
+			}
+			res.Status = "ok"
+			if res.Err != nil {
+				res.Status = "failed"
+			}
+		}
+		outputs["status"] = res.Status
+		if res.Err != nil {
+			outputs["error"] = res.Err.Error()
+			fmt.Fprintf(os.Stderr, "nlm: step %s: %v\n", step.ID, res.Err)
+			if !step.ContinueOnError {
+				stopped = true
+			} else {
+				res.Status = "failed (continued)"
+			}
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func (rr *recipeRunner) runStep(step *recipeStep, outputs map[string]string) error {
+	if step.Wait != nil {
+		w := *step.Wait
+		var err error
+		for _, field := range []*string{&w.Notebook, &w.Artifact, &w.Type, &w.State} {
+			if *field, err = rr.expand(*field, ""); err != nil {
+				return err
+			}
+		}
+		got, err := rr.wait(w)
+		for k, v := range got {
+			outputs[k] = v
+		}
+		return err
+	}
+
+	items := step.ForEach
+	if items == nil {
+		items = []string{""}
+	}
+	var all []string
+	for _, item := range items {
+		args := make([]string, len(step.Run))
+		for i, word := range step.Run {
+			var err error
+			if args[i], err = rr.expand(word, item); err != nil {
+				return err
+			}
+		}
+		if len(step.ForEach) > 0 {
+			fmt.Fprintf(os.Stderr, "nlm: step %s: %s\n", step.ID, strings.Join(args, " "))
+		}
+		outputs["command"] = strings.Join(args, " ")
+		out, err := rr.exec(args)
+		all = append(all, strings.TrimSpace(out))
+		setRecipeIDs(outputs, args, out)
+		if err != nil {
+			outputs["output"] = strings.Join(all, "\n")
+			return err
+		}
+	}
+	outputs["output"] = strings.Join(all, "\n")
+	return nil
+}
+
+var idPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// setRecipeIDs records the last ID printed by the command args. The
+// named ID, such as artifact_id, is only set to an ID that was not one of
+// the arguments, so create-audio echoing its notebook ID does not pass for
+// the new artifact.
+func setRecipeIDs(outputs map[string]string, args []string, out string) {
+	ids := idPattern.FindAllString(out, -1)
+	if len(ids) == 0 {
+		return
+	}
+	outputs["id"] = ids[len(ids)-1]
+	name, ok := recipeIDNames[args[0]]
+	if !ok {
+		return
+	}
+	for _, id := range slices.Backward(ids) {
+		if !slices.Contains(args[1:], id) {
+			outputs[name] = id
+			return
+		}
+	}
+}
+
+var recipeRefPattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// expand replaces ${vars.x}, ${env.X}, ${steps.id.field} and ${item}
+// references in s. A reference to an unknown variable or step is an error.
+func (rr *recipeRunner) expand(s, item string) (string, error) {
+	var err error
+	out := recipeRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := strings.TrimSpace(ref[2 : len(ref)-1])
+		parts := strings.SplitN(name, ".", 3)
+		switch {
+		case name == "item":
+			return item
+		case parts[0] == "env" && len(parts) == 2:
+			return os.Getenv(parts[1])
+		case parts[0] == "vars" && len(parts) == 2:
+			if v, ok := rr.vars[parts[1]]; ok {
+				return v
+			}
+		case parts[0] == "steps" && len(parts) == 3:
+			if outputs, ok := rr.steps[parts[1]]; ok {
+				return outputs[parts[2]]
+			}
+		}
+		if err == nil {
+			err = fmt.Errorf("undefined reference %s", ref)
+		}
+		return ref
+	})
+	return out, err
+}
+
+// evalRecipeCondition evaluates an expanded if: condition. "a == b" and
+// "a != b" compare case-insensitively; a single value is true unless it is
+// empty, false, no, off or 0.
+func evalRecipeCondition(cond string) bool {
+	if a, b, ok := strings.Cut(cond, "!="); ok {
+		return !strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	if a, b, ok := strings.Cut(cond, "=="); ok {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	switch strings.ToLower(strings.TrimSpace(cond)) {
+	case "", "false", "no", "off", "0":
+		return false
+	}
+	return true
+}
+
+func (step *recipeStep) describe() string {
+	if step.Wait != nil {
+		target := step.Wait.Artifact
+		if target == "" {
+			target = step.Wait.Type
+		}
+		return fmt.Sprintf("wait %s %s", target, step.Wait.State)
+	}
+	s := strings.Join(step.Run, " ")
+	if len(step.ForEach) > 0 {
+		s += fmt.Sprintf(" (×%d)", len(step.ForEach))
+	}
+	return s
+}
+
+// report prints the summary table.
+func (rr *recipeRunner) report(r *recipe, results []recipeResult) {
+	title := r.Name
+	if title == "" {
+		title = "recipe"
+	}
+	fmt.Fprintf(rr.out, "\n%s summary:\n", title)
+	w := tabwriter.NewWriter(rr.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tSTATUS\tTIME\tCOMMAND")
+	for _, res := range results {
+		d := "-"
+		if res.Status != "skipped" {
+			d = res.Duration.Round(100 * time.Millisecond).String()
+		}
+		cmd := []rune(res.Command)
+		if len(cmd) > 72 {
+			cmd = append(cmd[:71], '…')
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", res.ID, res.Status, d, string(cmd))
+	}
+	w.Flush()
+}
+
+// captureStdout runs fn with os.Stdout teed into a buffer and returns what
+// fn printed.
+func captureStdout(fn func() error) (string, error) {
+	orig := os.Stdout
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return "", fn()
+	}
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(io.MultiWriter(orig, &buf), pr)
+		close(done)
+	}()
+	os.Stdout = pw
+	err = fn()
+	os.Stdout = orig
+	pw.Close()
+	<-done
+	pr.Close()
+	return buf.String(), err
+}
+
+// waitForArtifact polls the notebook's artifacts until the one selected by
+// w reaches w.State, fails, or w.Timeout passes. It returns the artifact's
+// artifact_id, type and state, and sends the webhook once it finishes.
+func waitForArtifact(c *api.Client, w recipeWait) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.Timeout)
+	defer cancel()
+	outputs := map[string]string{}
+	hook := newWebhookNotifier()
+	list := func() ([]*pb.Artifact, error) { return c.ListArtifacts(w.Notebook) }
+	err := api.WaitArtifacts(ctx, w.Interval, list, func(artifacts []*pb.Artifact) (bool, error) {
+		for _, a := range artifacts {
+			typ := strings.TrimPrefix(a.GetType().String(), "ARTIFACT_TYPE_")
+			if w.Artifact != "" && a.GetArtifactId() != w.Artifact {
+				continue
+			}
+			if w.Artifact == "" && !strings.Contains(typ, strings.ToUpper(w.Type)) {
+				continue
+			}
+			outputs["artifact_id"] = a.GetArtifactId()
+			outputs["type"] = typ
+			outputs["state"] = strings.TrimPrefix(a.GetState().String(), "ARTIFACT_STATE_")
+			if p, done := artifactWebhook(w.Notebook, a); done {
+				hook.notify(p)
+			}
+			break
+		}
+		switch state := outputs["state"]; {
+		case state == w.State:
+			return true, nil
+		case state == "FAILED":
+			return true, fmt.Errorf("artifact %s failed", outputs["artifact_id"])
+		}
+		return false, nil
+	})
+	switch {
+	case !errors.Is(err, context.DeadlineExceeded):
+		return outputs, err
+	case outputs["state"] == "":
+		return outputs, fmt.Errorf("no matching artifact after %v", w.Timeout)
+	}
+	return outputs, fmt.Errorf("artifact %s still %s after %v", outputs["artifact_id"], outputs["state"], w.Timeout)
+}
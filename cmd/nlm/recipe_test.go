@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRecipe = `name: Digest
+vars:
+  title: Weekly digest
+steps:
+  - id: create
+    run: create "${vars.title}"
+  - id: add
+    run: add ${steps.create.notebook_id} ${item}
+    foreach: [https://example.com/a, https://example.com/b]
+  - id: flaky
+    run: [set-instructions, "${steps.create.id}", be brief]
+    continue-on-error: true
+  - id: audio
+    wait: {notebook: "${steps.create.notebook_id}", type: audio}
+  - run: audio-download ${steps.create.notebook_id} out.mp3
+    if: ${steps.audio.state} == READY
+  - run: share ${steps.create.notebook_id}
+    if: ${steps.flaky.status} == ok
+`
+
+func TestRunRecipe(t *testing.T) {
+	r, err := parseRecipe([]byte(testRecipe))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const nb = "11111111-2222-3333-4444-555555555555"
+	var calls []string
+	var out bytes.Buffer
+	rr := &recipeRunner{
+		out: &out,
+		exec: func(args []string) (string, error) {
+			calls = append(calls, strings.Join(args, "|"))
+			switch args[0] {
+			case "create":
+				return nb + "\n", nil
+			case "add":
+				return fmt.Sprintf("Adding source from URL: %s\naaaaaaaa-0000-0000-0000-%012d\n", args[2], len(calls)), nil
+			case "set-instructions":
+				return "", errors.New("boom")
+			}
+			return "", nil
+		},
+		wait: func(w recipeWait) (map[string]string, error) {
+			calls = append(calls, "wait|"+w.Notebook+"|"+w.Type+"|"+w.State)
+			return map[string]string{"state": "READY"}, nil
+		},
+	}
+	results := rr.run(r)
+	want := []string{
+		"create|Weekly digest",
+		"add|" + nb + "|https://example.com/a",
+		"add|" + nb + "|https://example.com/b",
+		"set-instructions|" + nb + "|be brief",
+		"wait|" + nb + "|audio|READY",
+		"audio-download|" + nb + "|out.mp3",
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+	if got := rr.steps["add"]["source_id"]; got != "aaaaaaaa-0000-0000-0000-000000000003" {
+		t.Errorf("add source_id = %q", got)
+	}
+	var statuses []string
+	for _, res := range results {
+		statuses = append(statuses, res.Status)
+	}
+	wantStatus := []string{"ok", "ok", "failed (continued)", "ok", "ok", "skipped"}
+	if !slices.Equal(statuses, wantStatus) {
+		t.Errorf("statuses = %q, want %q", statuses, wantStatus)
+	}
+	rr.report(r, results)
+	if !strings.Contains(out.String(), "Digest summary:") || !strings.Contains(out.String(), "failed (continued)") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
+
+func TestRunRecipeStopsOnError(t *testing.T) {
+	r, err := parseRecipe([]byte("steps:\n  - run: create x\n  - run: create ${steps.nope.id}\n  - run: list\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	rr := &recipeRunner{out: &bytes.Buffer{}, exec: func(args []string) (string, error) {
+		calls = append(calls, args[0])
+		return "", nil
+	}}
+	results := rr.run(r)
+	if len(calls) != 1 {
+		t.Errorf("calls = %q, want only the first step", calls)
+	}
+	if results[1].Status != "failed" || !strings.Contains(results[1].Err.Error(), "undefined reference ${steps.nope.id}") {
+		t.Errorf("step 2 = %s %v", results[1].Status, results[1].Err)
+	}
+	if results[2].Status != "skipped" {
+		t.Errorf("step 3 status = %s, want skipped", results[2].Status)
+	}
+}
+
+func TestParseRecipeErrors(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"name: x\n", "recipe has no steps"},
+		{"steps:\n  - run: frobnicate\n", "step 1: unknown command \"frobnicate\""},
+		{"steps:\n  - run: tui\n", "cannot be used in a recipe"},
+		{"steps:\n  - id: a\n", "step 1: step needs run or wait"},
+		{"steps:\n  - run: list\n    id: a\n  - run: list\n    id: a\n", "step 2: duplicate id"},
+		{"steps:\n  - wait: {type: audio}\n", "wait needs a notebook"},
+		{"steps:\n  - run: create 'x\n", "unterminated"},
+		{"steps:\n  - run: list\n    retries: 3\n", `line 3: unknown key "retries"`},
+		{"steps:\n  - wait: {notebook: x, type: audio, every: 1m}\n", `line 2: unknown key "every"`},
+		{"steps:\n  - wait: {notebook: x, type: audio, timeout: soon}\n", "line 2: "},
+		{"steps:\n  - run: list\n    continue-on-error: maybe\n", "line 3: "},
+		{"steps:\n  - run: list\n    run: show\n", "line 3: "},
+		{"steps: list\n", "line 1: "},
+	}
+	for _, tt := range tests {
+		_, err := parseRecipe([]byte(tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseRecipe(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestSetRecipeIDs(t *testing.T) {
+	const nb = "11111111-2222-3333-4444-555555555555"
+	const audio = "aaaaaaaa-0000-0000-0000-000000000001"
+	tests := []struct {
+		out  string
+		want map[string]string
+	}{
+		{
+			"Creating audio overview for notebook " + nb + "...\n✅ Audio overview creation started.\n  ID: " + audio + "\n",
+			map[string]string{"id": audio, "artifact_id": audio},
+		},
+		{
+			"Creating audio overview for notebook " + nb + "...\n✅ Audio overview creation started.\n",
+			map[string]string{"id": nb},
+		},
+	}
+	for _, tt := range tests {
+		got := map[string]string{}
+		setRecipeIDs(got, []string{"create-audio", nb, "Focus"}, tt.out)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("setRecipeIDs(%q) = %v, want %v", tt.out, got, tt.want)
+		}
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	got, err := splitCommandLine(`add nb "a b" 'c "d"' e\ f ""`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"add", "nb", "a b", `c "d"`, "e f", ""}
+	if !slices.Equal(got, want) {
+		t.Errorf("splitCommandLine = %q, want %q", got, want)
+	}
+}
+
+func TestLoadRecipeExample(t *testing.T) {
+	r, err := loadRecipe("testdata/recipes/digest.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Steps) != 6 || r.Steps[4].Wait == nil || r.Steps[4].Wait.Timeout.String() != "20m0s" {
+		t.Errorf("unexpected recipe: %+v", r.Steps)
+	}
+	if w := r.Steps[4].Wait; w.State != "READY" || w.Interval != 15*time.Second {
+		t.Errorf("wait defaults = %+v", w)
+	}
+}
+
+func TestDecodeYAML(t *testing.T) {
+	var got struct {
+		Name  string            `yaml:"name"`
+		Vars  map[string]string `yaml:"vars"`
+		Steps []string          `yaml:"steps"`
+		Again []string          `yaml:"again"`
+	}
+	if err := decodeYAML([]byte("name: x\nvars:\n  empty:\n  n: 3\nsteps: &s [a, b]\nagain: *s\n"), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "x" || got.Vars["empty"] != "" || got.Vars["n"] != "3" || !slices.Equal(got.Again, []string{"a", "b"}) {
+		t.Errorf("decodeYAML = %+v", got)
+	}
+
+	for src, want := range map[string]string{
+		"name: 1\nname: 2\n": `line 2: mapping key "name" already defined`,
+		"name:\n\t- b\n":     "line 2: ",
+		"steps: [1, 2\n":     "line 1: ",
+		"colour: red\n":      `line 1: unknown key "colour"`,
+		"name: 1\n  b: 2\n":  "line 2: ",
+	} {
+		if err := decodeYAML([]byte(src), &got); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("decodeYAML(%q) error = %v, want prefix %q", src, err, want)
+		}
+	}
+}
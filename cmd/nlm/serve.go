@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// nlm serve exposes notebooks, sources, notes and audio overviews as a
+// REST/JSON API. Requests need a local bearer token; the NotebookLM
+// credentials never leave the server. restRoutes lists the endpoints and
+// is also the source of the OpenAPI spec served at /openapi.json.
+
+type serveOptions struct {
+	Addr    string
+	Token   string
+	OpenAPI bool
+}
+
+func parseServeArgs(args []string) (serveOptions, error) {
+	opts := serveOptions{Addr: "localhost:8080", Token: os.Getenv("NLM_SERVE_TOKEN")}
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&opts.Addr, "addr", opts.Addr, "address to listen on")
+	flags.StringVar(&opts.Token, "token", opts.Token, "bearer token clients must send (or NLM_SERVE_TOKEN; default: generated and saved)")
+	flags.BoolVar(&opts.OpenAPI, "openapi", false, "print the OpenAPI spec and exit")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nlm serve [-addr localhost:8080] [-token token] [-openapi]\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		return opts, fmt.Errorf("invalid arguments")
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return opts, fmt.Errorf("invalid arguments")
+	}
+	return opts, nil
+}
+
+// restBackend is the part of api.Client the REST API uses.
+type restBackend interface {
+	ListRecentlyViewedProjects() ([]*api.Notebook, error)
+	CreateProject(title, emoji string) (*api.Notebook, error)
+	GetProject(projectID string) (*api.Notebook, error)
+	DeleteProjects(projectIDs []string) error
+	AddSourceFromURL(projectID, url string) (string, error)
+	AddSourceFromText(projectID, content, title string) (string, error)
+	DeleteSources(projectID string, sourceIDs []string) error
+	GetNotes(projectID string) ([]*api.Note, error)
+	CreateNote(projectID, title, content string) (*api.Note, error)
+	DeleteNotes(projectID string, noteIDs []string) error
+	CreateAudioOverview(projectID, instructions string) (*api.AudioOverviewResult, error)
+	GetAudioOverview(projectID string) (*api.AudioOverviewResult, error)
+}
+
+// restRoute is one endpoint. Request and Response are zero values of the
+// body types, used for decoding and for the OpenAPI spec; a nil Response
+// means 204 No Content.
+type restRoute struct {
+	Method, Path string
+	ID, Summary  string
+	Request      any
+	Response     any
+	Handle       func(b restBackend, r *http.Request, body any) (any, error)
+}
+
+// Request bodies.
+type (
+	restCreateNotebook struct {
+		Title string `json:"title" doc:"Notebook title"`
+		Emoji string `json:"emoji,omitempty" doc:"Notebook emoji"`
+	}
+	restAddSource struct {
+		URL   string `json:"url,omitempty" doc:"Web page, YouTube or Google Drive URL"`
+		Title string `json:"title,omitempty" doc:"Title of a text source"`
+		Text  string `json:"text,omitempty" doc:"Content of a text source; used when url is empty"`
+	}
+	restCreateNote struct {
+		Title   string `json:"title" doc:"Note title"`
+		Content string `json:"content,omitempty" doc:"Note content"`
+	}
+	restCreateAudio struct {
+		Instructions string `json:"instructions,omitempty" doc:"Instructions for the hosts"`
+	}
+)
+
+// Response bodies that are not generated messages.
+type (
+	restNotebooks struct {
+		Notebooks pbList[*pb.Project] `json:"notebooks"`
+	}
+	restSources struct {
+		Sources pbList[*pb.Source] `json:"sources"`
+	}
+	restNotes struct {
+		Notes pbList[*pb.Note] `json:"notes"`
+	}
+	restSourceAdded struct {
+		SourceID string `json:"source_id" doc:"ID of the new source"`
+	}
+	restAudio struct {
+		AudioID   string `json:"audio_id,omitempty"`
+		Title     string `json:"title,omitempty"`
+		Ready     bool   `json:"ready" doc:"Whether generation has finished"`
+		AudioData string `json:"audio_data,omitempty" doc:"Base64-encoded audio, once ready"`
+	}
+	restError struct {
+		Error string `json:"error"`
+	}
+)
+
+// restStatusError is an error with an HTTP status.
+type restStatusError struct {
+	status int
+	msg    string
+}
+
+func (e *restStatusError) Error() string { return e.msg }
+
+func badRequest(format string, args ...any) error {
+	return &restStatusError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+var restRoutes = []restRoute{
+	{
+		Method: "GET", Path: "/notebooks", ID: "listNotebooks", Summary: "List notebooks",
+		Response: restNotebooks{},
+		Handle: func(b restBackend, r *http.Request, _ any) (any, error) {
+			nbs, err := b.ListRecentlyViewedProjects()
+			return restNotebooks{Notebooks: nbs}, err
+		},
+	},
+	{
+		Method: "POST", Path: "/notebooks", ID: "createNotebook", Summary: "Create a notebook",
+		Request: restCreateNotebook{}, Response: (*pb.Project)(nil),
+		Handle: func(b restBackend, r *http.Request, body any) (any, error) {
+			req := body.(*restCreateNotebook)
+			if req.Title == "" {
+				return nil, badRequest("title is required")
+			}
+			return b.CreateProject(req.Title, req.Emoji)
+		},
+	},
+	{
+		Method: "GET", Path: "/notebooks/{id}", ID: "getNotebook", Summary: "Get a notebook with its sources",
+		Response: (*pb.Project)(nil),
+		Handle: func(b restBackend, r *http.Request, _ any) (any, error) {
+			return b.GetProject(r.PathValue("id"))
+		},
+	},
+	{
+		Method: "DELETE", Path: "/notebooks/{id}", ID: "deleteNotebook", Summary: "Delete a notebook",
+		Handle: func(b restBackend, r *http.Request, _ any) (any, error) {
+			return nil, b.DeleteProjects([]string{r.PathValue("id")})
+		},
+	},
+	{
+		Method: "GET", Path: "/notebooks/{id}/sources", ID: "listSources", Summary: "List a notebook's sources",
+		Response: restSources{},
+		Handle: func(b restBackend, r *http.Request, _ any) (any, error) {
+			nb, err := b.GetProject(r.PathValue("id"))
+			if err != nil {
+				return nil, err
+			}
+			return restSources{Sources: nb.GetSources()}, nil
+		},
+	},
+	{
+		Method: "POST", Path: "/notebooks/{id}/sources", ID: "addSource", Summary: "Add a URL or text source",
+		Request: restAddSource{}, Response: restSourceAdded{},
+		Handle: func(b restBackend, r *http.Request, body any) (any, error) {
+			req := body.(*restAddSource)
+			var id string
+			var err error
+			switch {
+			case req.URL != "":
+				id, err = b.AddSourceFromURL(r.PathValue("id"), req.URL)
+			case req.Text != "":
+				title := req.Title
+				if title == "" {
+					title = "Pasted text"
+				}
+				id, err = b.AddSourceFromText(r.PathValue("id"), req.Text, title)
+			default:
+				return nil, badRequest("url or text is required")
+			}
+			return restSourceAdded{SourceID: id}, err
+		},
+	},
+	{
+		Method: "DELETE", Path: "/notebooks/{id}/sources/{source_id}", ID: "deleteSource", Summary: "Delete a source",
+		Handle: func(b restBackend, r *http.Request, _ any) (any, error) {
+			return nil, b.DeleteSources(r.PathValue("id"), []string{r.PathValue("source_id")})
+		},
+	},
+	{
+		Method: "GET", Path: "/notebooks/{id}/notes", ID: "listNotes", Summary: "List a notebook's notes",
+		Response: restNotes{},
+		Handle: func(b restBackend, r *http.Request, _ any) (any, error) {
+			notes, err := b.GetNotes(r.PathValue("id"))
+			return restNotes{Notes: notes}, err
+		},
+	},
+	{
+		Method: "POST", Path: "/notebooks/{id}/notes", ID: "createNote", Summary: "Create a note",
+		Request: restCreateNote{}, Response: (*pb.Note)(nil),
+		Handle: func(b restBackend, r *http.Request, body any) (any, error) {
+			req := body.(*restCreateNote)
+			if req.Title == "" {
+				return nil, badRequest("title is required")
+			}
+			return b.CreateNote(r.PathValue("id"), req.Title, req.Content)
+		},
+	},
+	{
+		Method: "DELETE", Path: "/notebooks/{id}/notes/{note_id}", ID: "deleteNote", Summary: "Delete a note",
+		Handle: func(b restBackend, r *http.Request, _ any) (any, error) {
+			return nil, b.DeleteNotes(r.PathValue("id"), []string{r.PathValue("note_id")})
+		},
+	},
+	{
+		Method: "GET", Path: "/notebooks/{id}/audio", ID: "getAudio", Summary: "Get the audio overview",
+		Response: restAudio{},
+		Handle: func(b restBackend, r *http.Request, _ any) (any, error) {
+			return audioResponse(b.GetAudioOverview(r.PathValue("id")))
+		},
+	},
+	{
+		Method: "POST", Path: "/notebooks/{id}/audio", ID: "createAudio", Summary: "Start generating an audio overview",
+		Request: restCreateAudio{}, Response: restAudio{},
+		Handle: func(b restBackend, r *http.Request, body any) (any, error) {
+			return audioResponse(b.CreateAudioOverview(r.PathValue("id"), body.(*restCreateAudio).Instructions))
+		},
+	},
+}
+
+func audioResponse(a *api.AudioOverviewResult, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	return restAudio{AudioID: a.AudioID, Title: a.Title, Ready: a.IsReady, AudioData: a.AudioData}, nil
+}
+
+// pbList is a list of generated messages, encoded with protojson.
+type pbList[T proto.Message] []T
+
+func (l pbList[T]) MarshalJSON() ([]byte, error) {
+	parts := make([]json.RawMessage, 0, len(l))
+	for _, m := range l {
+		data, err := restProtoJSON.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, data)
+	}
+	return json.Marshal(parts)
+}
+
+func (pbList[T]) elem() proto.Message {
+	var zero T
+	return zero
+}
+
+// restProtoJSON encodes messages with their proto field names, matching
+// the snake_case names of the other bodies.
+var restProtoJSON = protojson.MarshalOptions{UseProtoNames: true}
+
+// restServer serves restRoutes.
+type restServer struct {
+	backend restBackend
+	token   string
+}
+
+func (s *restServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range restRoutes {
+		mux.HandleFunc(rt.Method+" "+rt.Path, func(w http.ResponseWriter, r *http.Request) {
+			s.serveRoute(w, r, rt)
+		})
+	}
+	spec, _ := json.MarshalIndent(openAPISpec(), "", "  ")
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openapi.json" {
+			got := []byte(r.Header.Get("Authorization"))
+			if subtle.ConstantTimeCompare(got, []byte("Bearer "+s.token)) != 1 {
+				writeRESTError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *restServer) serveRoute(w http.ResponseWriter, r *http.Request, rt restRoute) {
+	var body any
+	if rt.Request != nil {
+		body = newBody(rt.Request)
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 8<<20))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(body); err != nil && !errors.Is(err, io.EOF) {
+			writeRESTError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+	}
+	resp, err := rt.Handle(s.backend, r, body)
+	if err != nil {
+		var se *restStatusError
+		switch {
+		case errors.As(err, &se):
+			writeRESTError(w, se.status, se.msg)
+		case isAuthenticationError(err):
+			writeRESTError(w, http.StatusBadGateway, err.Error()+" (run 'nlm auth' on the server)")
+		default:
+			writeRESTError(w, http.StatusBadGateway, err.Error())
+		}
+		return
+	}
+	if rt.Response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	var data []byte
+	if m, ok := resp.(proto.Message); ok {
+		data, err = restProtoJSON.Marshal(m)
+	} else {
+		data, err = json.Marshal(resp)
+	}
+	if err != nil {
+		writeRESTError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func writeRESTError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(restError{Error: msg})
+}
+
+// serveTokenPath is where the generated bearer token is kept.
+func serveTokenPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(profileStateDir(home), "serve-token"), nil
+}
+
+// loadServeToken returns the saved bearer token, creating it on first use.
+func loadServeToken() (token, path string, err error) {
+	path, err = serveTokenPath()
+	if err != nil {
+		return "", "", err
+	}
+	if data, err := os.ReadFile(path); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), path, nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", "", fmt.Errorf("save token: %w", err)
+	}
+	return token, path, nil
+}
+
+// printOpenAPISpec writes the spec to stdout; it needs no credentials.
+func printOpenAPISpec() error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(openAPISpec())
+}
+
+// runServe serves the REST API until interrupted.
+func runServe(c *api.Client, opts serveOptions) error {
+	token := opts.Token
+	if token == "" {
+		var path string
+		var err error
+		if token, path, err = loadServeToken(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "nlm: bearer token in %s\n", path)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s := &restServer{backend: c, token: token}
+	return serveHTTP(ctx, opts.Addr, s.handler(), "REST API (spec at /openapi.json)")
+}
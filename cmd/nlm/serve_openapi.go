@@ -0,0 +1,227 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// openAPISpec generates the OpenAPI 3.1 document for restRoutes. Schemas
+// for generated messages come from their descriptors and follow protojson
+// with proto field names; the other bodies are described from their Go
+// types, json tags and doc tags.
+func openAPISpec() map[string]any {
+	g := &openAPIGen{schemas: map[string]any{}}
+	paths := map[string]map[string]any{}
+	for _, rt := range restRoutes {
+		op := map[string]any{
+			"operationId": rt.ID,
+			"summary":     rt.Summary,
+			"responses": map[string]any{
+				"default": map[string]any{
+					"description": "Error",
+					"content":     jsonContent(g.schema(reflect.TypeOf(restError{}))),
+				},
+			},
+		}
+		var params []any
+		for _, m := range restPathParam.FindAllStringSubmatch(rt.Path, -1) {
+			params = append(params, map[string]any{
+				"name": m[1], "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if rt.Request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(g.schema(reflect.TypeOf(rt.Request))),
+			}
+		}
+		responses := op["responses"].(map[string]any)
+		if rt.Response == nil {
+			responses["204"] = map[string]any{"description": "Done"}
+		} else {
+			responses["200"] = map[string]any{
+				"description": "OK",
+				"content":     jsonContent(g.schema(reflect.TypeOf(rt.Response))),
+			}
+		}
+		if paths[rt.Path] == nil {
+			paths[rt.Path] = map[string]any{}
+		}
+		paths[rt.Path][strings.ToLower(rt.Method)] = op
+	}
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "nlm REST API",
+			"version":     "1",
+			"description": "NotebookLM notebooks, sources, notes and audio overviews, served by nlm serve.",
+		},
+		"security": []any{map[string]any{"bearer": []any{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+			"schemas": g.schemas,
+		},
+	}
+}
+
+var restPathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+func jsonContent(schema any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// openAPIGen collects named schemas while describing types.
+type openAPIGen struct {
+	schemas map[string]any
+}
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// schema describes a Go type, returning a reference for named structs and
+// generated messages.
+func (g *openAPIGen) schema(t reflect.Type) map[string]any {
+	if t.Implements(protoMessageType) {
+		return g.message(reflect.Zero(t).Interface().(proto.Message).ProtoReflect().Descriptor())
+	}
+	if l, ok := reflect.Zero(t).Interface().(interface{ elem() proto.Message }); ok {
+		return map[string]any{"type": "array", "items": g.message(l.elem().ProtoReflect().Descriptor())}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Struct:
+		name := strings.TrimPrefix(t.Name(), "rest")
+		ref := map[string]any{"$ref": "#/components/schemas/" + name}
+		if _, ok := g.schemas[name]; ok {
+			return ref
+		}
+		g.schemas[name] = nil // reserve against recursion
+		props := map[string]any{}
+		var required []string
+		for i := range t.NumField() {
+			f := t.Field(i)
+			jsonName, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if jsonName == "" || jsonName == "-" {
+				continue
+			}
+			s := g.schema(f.Type)
+			if doc := f.Tag.Get("doc"); doc != "" {
+				s = withDescription(s, doc)
+			}
+			props[jsonName] = s
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, jsonName)
+			}
+		}
+		obj := map[string]any{"type": "object", "properties": props}
+		if required != nil {
+			obj["required"] = required
+		}
+		g.schemas[name] = obj
+		return ref
+	}
+	return map[string]any{}
+}
+
+// withDescription adds a description, wrapping references, whose siblings
+// some tools ignore.
+func withDescription(s map[string]any, doc string) map[string]any {
+	if _, ok := s["$ref"]; ok {
+		return map[string]any{"allOf": []any{s}, "description": doc}
+	}
+	s["description"] = doc
+	return s
+}
+
+// message describes a generated message as protojson encodes it.
+func (g *openAPIGen) message(md protoreflect.MessageDescriptor) map[string]any {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return map[string]any{"type": "string", "format": "date-time"}
+	case "google.protobuf.Duration":
+		return map[string]any{"type": "string", "examples": []any{"1.5s"}}
+	case "google.protobuf.Empty":
+		return map[string]any{"type": "object"}
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue":
+		return map[string]any{}
+	}
+	if strings.HasPrefix(string(md.FullName()), "google.protobuf.") && strings.HasSuffix(string(md.Name()), "Value") {
+		return g.field(md.Fields().ByName("value"))
+	}
+	name := string(md.Name())
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+	if _, ok := g.schemas[name]; ok {
+		return ref
+	}
+	g.schemas[name] = nil // reserve against recursion
+	props := map[string]any{}
+	fields := md.Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		s := g.field(fd)
+		if fd.IsMap() {
+			s = map[string]any{"type": "object", "additionalProperties": g.field(fd.MapValue())}
+		} else if fd.IsList() {
+			s = map[string]any{"type": "array", "items": s}
+		}
+		props[string(fd.Name())] = s
+	}
+	g.schemas[name] = map[string]any{"type": "object", "properties": props}
+	return ref
+}
+
+// field describes a single value of fd.
+func (g *openAPIGen) field(fd protoreflect.FieldDescriptor) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson writes 64-bit integers as strings.
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]any{"type": "number"}
+	case protoreflect.EnumKind:
+		var names []any
+		values := fd.Enum().Values()
+		for i := range values.Len() {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.message(fd.Message())
+	}
+	return map[string]any{}
+}
+
+// newBody returns a pointer to a new value of zero's type, for decoding a
+// request body.
+func newBody(zero any) any {
+	return reflect.New(reflect.TypeOf(zero)).Interface()
+}
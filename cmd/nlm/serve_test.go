@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// fakeRESTBackend records calls and serves a single notebook.
+type fakeRESTBackend struct {
+	restBackend // unimplemented methods panic
+	calls       []string
+}
+
+func (f *fakeRESTBackend) ListRecentlyViewedProjects() ([]*api.Notebook, error) {
+	return []*api.Notebook{{ProjectId: "nb-1", Title: "Runbooks", Sources: []*pb.Source{{SourceId: &pb.SourceId{SourceId: "src-1"}, Title: "Deploys"}}}}, nil
+}
+
+func (f *fakeRESTBackend) GetProject(id string) (*api.Notebook, error) {
+	if id != "nb-1" {
+		return nil, errors.New("notebook not found")
+	}
+	nbs, _ := f.ListRecentlyViewedProjects()
+	return nbs[0], nil
+}
+
+func (f *fakeRESTBackend) AddSourceFromURL(nb, url string) (string, error) {
+	f.calls = append(f.calls, "url "+nb+" "+url)
+	return "src-url", nil
+}
+
+func (f *fakeRESTBackend) AddSourceFromText(nb, content, title string) (string, error) {
+	f.calls = append(f.calls, "text "+nb+" "+title+" "+content)
+	return "src-text", nil
+}
+
+func (f *fakeRESTBackend) DeleteNotes(nb string, ids []string) error {
+	f.calls = append(f.calls, "delete-notes "+nb+" "+strings.Join(ids, ","))
+	return nil
+}
+
+func newTestRESTServer(t *testing.T) (*httptest.Server, *fakeRESTBackend) {
+	t.Helper()
+	b := &fakeRESTBackend{}
+	srv := httptest.NewServer((&restServer{backend: b, token: "tok"}).handler())
+	t.Cleanup(srv.Close)
+	return srv, b
+}
+
+func restDo(t *testing.T, srv *httptest.Server, method, path, token, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestRESTAuth(t *testing.T) {
+	srv, _ := newTestRESTServer(t)
+	if code, _ := restDo(t, srv, "GET", "/notebooks", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", code)
+	}
+	if code, _ := restDo(t, srv, "GET", "/notebooks", "wrong", ""); code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status %d, want 401", code)
+	}
+	if code, body := restDo(t, srv, "GET", "/openapi.json", "", ""); code != http.StatusOK || !strings.Contains(body, `"openapi": "3.1.0"`) {
+		t.Errorf("openapi.json: status %d, body %.100s", code, body)
+	}
+}
+
+func TestRESTNotebooks(t *testing.T) {
+	srv, _ := newTestRESTServer(t)
+	code, body := restDo(t, srv, "GET", "/notebooks", "tok", "")
+	if code != http.StatusOK {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	var got struct {
+		Notebooks []map[string]any `json:"notebooks"`
+	}
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Notebooks) != 1 || got.Notebooks[0]["project_id"] != "nb-1" {
+		t.Errorf("notebooks = %s", body)
+	}
+
+	code, body = restDo(t, srv, "GET", "/notebooks/nb-1/sources", "tok", "")
+	if code != http.StatusOK || !strings.Contains(body, `"source_id":{"source_id":"src-1"}`) {
+		t.Errorf("sources: status %d, body %s", code, body)
+	}
+	if code, body := restDo(t, srv, "GET", "/notebooks/nb-2", "tok", ""); code != http.StatusBadGateway || !strings.Contains(body, "notebook not found") {
+		t.Errorf("missing notebook: status %d, body %s", code, body)
+	}
+}
+
+func TestRESTAddSource(t *testing.T) {
+	srv, b := newTestRESTServer(t)
+	for _, tt := range []struct {
+		body string
+		code int
+		want string
+	}{
+		{`{"url": "https://example.com"}`, http.StatusOK, `{"source_id":"src-url"}`},
+		{`{"text": "hello"}`, http.StatusOK, `{"source_id":"src-text"}`},
+		{`{}`, http.StatusBadRequest, "url or text is required"},
+		{`{"link": "x"}`, http.StatusBadRequest, "unknown field"},
+	} {
+		code, body := restDo(t, srv, "POST", "/notebooks/nb-1/sources", "tok", tt.body)
+		if code != tt.code || !strings.Contains(body, tt.want) {
+			t.Errorf("%s: status %d, body %s; want %d, %s", tt.body, code, body, tt.code, tt.want)
+		}
+	}
+	want := []string{"url nb-1 https://example.com", "text nb-1 Pasted text hello"}
+	if strings.Join(b.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", b.calls, want)
+	}
+}
+
+func TestRESTDeleteNote(t *testing.T) {
+	srv, b := newTestRESTServer(t)
+	code, body := restDo(t, srv, "DELETE", "/notebooks/nb-1/notes/n-1", "tok", "")
+	if code != http.StatusNoContent || body != "" {
+		t.Errorf("status %d, body %q; want 204", code, body)
+	}
+	if len(b.calls) != 1 || b.calls[0] != "delete-notes nb-1 n-1" {
+		t.Errorf("calls = %q", b.calls)
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	data, err := json.Marshal(openAPISpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+	for _, rt := range restRoutes {
+		op := spec.Paths[rt.Path][strings.ToLower(rt.Method)]
+		if op == nil || op["operationId"] != rt.ID {
+			t.Errorf("%s %s missing from spec", rt.Method, rt.Path)
+		}
+	}
+	refs := regexp.MustCompile(`"\$ref":"#/components/schemas/(\w+)"`)
+	for _, m := range refs.FindAllStringSubmatch(string(data), -1) {
+		if spec.Components.Schemas[m[1]] == nil {
+			t.Errorf("unresolved schema reference %s", m[1])
+		}
+	}
+	project, _ := json.Marshal(spec.Components.Schemas["Project"])
+	if !strings.Contains(string(project), `"sources":{"items":{"$ref":"#/components/schemas/Source"},"type":"array"}`) {
+		t.Errorf("Project schema = %s", project)
+	}
+}
+
+func TestLoadServeToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	tok, path, err := loadServeToken()
+	if err != nil || len(tok) != 64 {
+		t.Fatalf("loadServeToken = %q, %v", tok, err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("token file: %v, %v", fi, err)
+	}
+	again, _, err := loadServeToken()
+	if err != nil || again != tok {
+		t.Errorf("second load = %q, %v; want the saved token", again, err)
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// nlm serve-openai exposes notebook chat through the OpenAI chat
+// completions protocol:
+//
+//	GET  /v1/models            one model per notebook, with the notebook ID as model ID
+//	GET  /v1/models/{model}
+//	POST /v1/chat/completions  ask the notebook named by "model"; "stream": true for SSE
+//
+// Requests are stateless: the messages before the last user message become
+// the chat history, and system messages are prepended to the prompt.
+// Citations are returned in the nlm_citations extension field, on the
+// completion or on the final stream chunk.
+
+type serveOpenAIOptions struct {
+	Addr   string
+	APIKey string
+}
+
+func parseServeOpenAIArgs(args []string) (serveOpenAIOptions, error) {
+	opts := serveOpenAIOptions{Addr: "localhost:8080", APIKey: os.Getenv("NLM_OPENAI_API_KEY")}
+	flags := flag.NewFlagSet("serve-openai", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.StringVar(&opts.Addr, "addr", opts.Addr, "address to listen on")
+	flags.StringVar(&opts.APIKey, "api-key", opts.APIKey, "bearer token clients must send (or NLM_OPENAI_API_KEY; default: the serve token)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nlm serve-openai [-addr localhost:8080] [-api-key key]\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		return opts, fmt.Errorf("invalid arguments")
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return opts, fmt.Errorf("invalid arguments")
+	}
+	return opts, nil
+}
+
+// openAIServer serves the OpenAI-compatible API.
+type openAIServer struct {
+	apiKey string
+	// stream and notebooks reach NotebookLM; they are replaced in tests.
+	stream    func(context.Context, api.ChatRequest, func(api.ChatChunk) bool) error
+	notebooks func() ([]*api.Notebook, error)
+}
+
+func newOpenAIServer(c *api.Client, apiKey string) *openAIServer {
+	return &openAIServer{
+		apiKey:    apiKey,
+		stream:    c.StreamChatWithContext,
+		notebooks: c.ListRecentlyViewedProjects,
+	}
+}
+
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+	Name    string `json:"name,omitempty"` // notebook title
+}
+
+type openAIMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+}
+
+type openAIResponseMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type openAIChoice struct {
+	Index        int                    `json:"index"`
+	Message      *openAIResponseMessage `json:"message,omitempty"`
+	Delta        *openAIResponseMessage `json:"delta,omitempty"`
+	FinishReason *string                `json:"finish_reason"`
+}
+
+type openAICompletion struct {
+	ID        string         `json:"id"`
+	Object    string         `json:"object"`
+	Created   int64          `json:"created"`
+	Model     string         `json:"model"`
+	Choices   []openAIChoice `json:"choices"`
+	Citations []api.Citation `json:"nlm_citations,omitempty"`
+}
+
+type openAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+// runServeOpenAI serves the API on opts.Addr until interrupted.
+func runServeOpenAI(c *api.Client, opts serveOpenAIOptions) error {
+	// Like serve, never run without a key: anyone who can reach the
+	// address could use the NotebookLM account.
+	if opts.APIKey == "" {
+		token, path, err := loadServeToken()
+		if err != nil {
+			return err
+		}
+		opts.APIKey = token
+		fmt.Fprintf(os.Stderr, "nlm: API key in %s\n", path)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return serveHTTP(ctx, opts.Addr, newOpenAIServer(c, opts.APIKey).handler(), "OpenAI-compatible API")
+}
+
+// serveHTTP serves h on addr until ctx is done, then shuts down gracefully.
+func serveHTTP(ctx context.Context, addr string, h http.Handler, what string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	fmt.Fprintf(os.Stderr, "nlm: serving %s on http://%s\n", what, ln.Addr())
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func (s *openAIServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", s.listModels)
+	mux.HandleFunc("GET /v1/models/{model}", s.getModel)
+	mux.HandleFunc("POST /v1/chat/completions", s.chatCompletions)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if s.apiKey != "" && subtle.ConstantTimeCompare(got, []byte("Bearer "+s.apiKey)) != 1 {
+			writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "invalid API key")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, typ, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]openAIError{"error": {Message: msg, Type: typ, Code: code}})
+}
+
+// writeOpenAIUpstreamError reports a failed NotebookLM call.
+func writeOpenAIUpstreamError(w http.ResponseWriter, err error) {
+	if isAuthenticationError(err) {
+		writeOpenAIError(w, http.StatusBadGateway, "api_error", "upstream_auth", err.Error()+" (run 'nlm auth')")
+		return
+	}
+	writeOpenAIError(w, http.StatusBadGateway, "api_error", "upstream_error", err.Error())
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *openAIServer) models() ([]openAIModel, error) {
+	notebooks, err := s.notebooks()
+	if err != nil {
+		return nil, err
+	}
+	models := []openAIModel{}
+	for _, nb := range notebooks {
+		models = append(models, openAIModel{
+			ID:      nb.GetProjectId(),
+			Object:  "model",
+			Created: nb.GetMetadata().GetCreateTime().GetSeconds(),
+			OwnedBy: "notebooklm",
+			Name:    strings.TrimSpace(nb.GetTitle()),
+		})
+	}
+	return models, nil
+}
+
+func (s *openAIServer) listModels(w http.ResponseWriter, r *http.Request) {
+	models, err := s.models()
+	if err != nil {
+		writeOpenAIUpstreamError(w, err)
+		return
+	}
+	writeJSON(w, map[string]any{"object": "list", "data": models})
+}
+
+func (s *openAIServer) getModel(w http.ResponseWriter, r *http.Request) {
+	models, err := s.models()
+	if err != nil {
+		writeOpenAIUpstreamError(w, err)
+		return
+	}
+	for _, m := range models {
+		if m.ID == r.PathValue("model") {
+			writeJSON(w, m)
+			return
+		}
+	}
+	writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+		fmt.Sprintf("no notebook %q", r.PathValue("model")))
+}
+
+// text returns the text of a message whose content is a string or
+// an array of content parts.
+func (m openAIMessage) text() (string, error) {
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", fmt.Errorf("%s message content must be a string or an array of parts", m.Role)
+	}
+	var b strings.Builder
+	for _, p := range parts {
+		if p.Type != "text" {
+			return "", fmt.Errorf("unsupported content part type %q", p.Type)
+		}
+		b.WriteString(p.Text)
+	}
+	return b.String(), nil
+}
+
+// openAIChatRequestToChat converts a chat completions request. The last
+// message must come from the user; earlier user and assistant messages
+// become history, and system or developer messages preface the prompt.
+func openAIChatRequestToChat(req *openAIChatRequest) (api.ChatRequest, error) {
+	if req.Model == "" {
+		return api.ChatRequest{}, errors.New("model is required (use a notebook ID from /v1/models)")
+	}
+	if len(req.Messages) == 0 || req.Messages[len(req.Messages)-1].Role != "user" {
+		return api.ChatRequest{}, errors.New("the last message must have role user")
+	}
+	var system []string
+	var turns []api.ChatMessage // oldest first
+	for _, m := range req.Messages {
+		text, err := m.text()
+		if err != nil {
+			return api.ChatRequest{}, err
+		}
+		switch m.Role {
+		case "system", "developer":
+			system = append(system, text)
+		case "user":
+			turns = append(turns, api.ChatMessage{Content: text, Role: 1})
+		case "assistant":
+			turns = append(turns, api.ChatMessage{Content: text, Role: 2})
+		default:
+			return api.ChatRequest{}, fmt.Errorf("unsupported message role %q", m.Role)
+		}
+	}
+	prompt := turns[len(turns)-1].Content
+	if len(system) > 0 {
+		prompt = strings.Join(append(system, prompt), "\n\n")
+	}
+	var history []api.ChatMessage
+	for i := len(turns) - 2; i >= 0; i-- {
+		history = append(history, turns[i])
+	}
+	seq := 0
+	for _, t := range turns {
+		if t.Role == 1 {
+			seq++
+		}
+	}
+	return api.ChatRequest{
+		ProjectID:      req.Model,
+		Prompt:         prompt,
+		ConversationID: uuid.New().String(),
+		History:        history,
+		SeqNum:         seq,
+	}, nil
+}
+
+func (s *openAIServer) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req openAIChatRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<20)).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", "invalid JSON body: "+err.Error())
+		return
+	}
+	chatReq, err := openAIChatRequestToChat(&req)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return
+	}
+	completion := openAICompletion{
+		ID:      "chatcmpl-" + uuid.New().String(),
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+	}
+	if req.Stream {
+		s.streamCompletion(w, r, chatReq, completion)
+		return
+	}
+
+	var answer strings.Builder
+	err = s.stream(r.Context(), chatReq, func(chunk api.ChatChunk) bool {
+		switch chunk.Phase {
+		case api.ChatChunkAnswer:
+			answer.WriteString(chunk.Text)
+		case api.ChatChunkCitations:
+			completion.Citations = chunk.Citations
+		}
+		return r.Context().Err() == nil
+	})
+	if err != nil {
+		writeOpenAIUpstreamError(w, err)
+		return
+	}
+	stop := "stop"
+	completion.Object = "chat.completion"
+	completion.Choices = []openAIChoice{{
+		Message:      &openAIResponseMessage{Role: "assistant", Content: strings.TrimSpace(answer.String())},
+		FinishReason: &stop,
+	}}
+	writeJSON(w, completion)
+}
+
+// streamCompletion answers with server-sent events in the chat completion
+// chunk format, ending with data: [DONE]. Errors after the stream has
+// started are sent as an error event.
+func (s *openAIServer) streamCompletion(w http.ResponseWriter, r *http.Request, req api.ChatRequest, chunk openAICompletion) {
+	flusher, _ := w.(http.Flusher)
+	chunk.Object = "chat.completion.chunk"
+	started := false
+	send := func(v any) {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			started = true
+		}
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	delta := func(d openAIResponseMessage, finish *string) openAICompletion {
+		c := chunk
+		c.Choices = []openAIChoice{{Delta: &d, FinishReason: finish}}
+		return c
+	}
+
+	var citations []api.Citation
+	err := s.stream(r.Context(), req, func(c api.ChatChunk) bool {
+		switch c.Phase {
+		case api.ChatChunkAnswer:
+			if c.Text == "" {
+				break
+			}
+			if !started {
+				send(delta(openAIResponseMessage{Role: "assistant"}, nil))
+			}
+			send(delta(openAIResponseMessage{Content: c.Text}, nil))
+		case api.ChatChunkCitations:
+			citations = c.Citations
+		}
+		return r.Context().Err() == nil
+	})
+	if err != nil {
+		if !started {
+			writeOpenAIUpstreamError(w, err)
+			return
+		}
+		send(map[string]openAIError{"error": {Message: err.Error(), Type: "api_error", Code: "upstream_error"}})
+		return
+	}
+	if !started {
+		send(delta(openAIResponseMessage{Role: "assistant"}, nil))
+	}
+	stop := "stop"
+	final := delta(openAIResponseMessage{}, &stop)
+	final.Citations = citations
+	send(final)
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
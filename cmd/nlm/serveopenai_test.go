@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+func newTestOpenAIServer(t *testing.T, apiKey string, stream func(context.Context, api.ChatRequest, func(api.ChatChunk) bool) error) *httptest.Server {
+	t.Helper()
+	s := &openAIServer{
+		apiKey: apiKey,
+		stream: stream,
+		notebooks: func() ([]*api.Notebook, error) {
+			return []*api.Notebook{{ProjectId: "nb-1", Title: " Runbooks "}, {ProjectId: "nb-2", Title: "Design"}}, nil
+		},
+	}
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func answerStream(got *api.ChatRequest) func(context.Context, api.ChatRequest, func(api.ChatChunk) bool) error {
+	return func(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error {
+		*got = req
+		cb(api.ChatChunk{Phase: api.ChatChunkThinking, Text: "Reading"})
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "Deploys run "})
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "nightly [1]."})
+		cb(api.ChatChunk{Phase: api.ChatChunkCitations, Citations: []api.Citation{{Number: 1, SourceID: "src-1"}}})
+		return nil
+	}
+}
+
+func postJSON(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestOpenAIModels(t *testing.T) {
+	srv := newTestOpenAIServer(t, "", nil)
+	resp, err := http.Get(srv.URL + "/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Object string        `json:"object"`
+		Data   []openAIModel `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Object != "list" || len(list.Data) != 2 || list.Data[0].ID != "nb-1" || list.Data[0].Name != "Runbooks" || list.Data[0].Object != "model" {
+		t.Errorf("models = %+v", list)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/models/nb-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown model status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestOpenAIChatCompletion(t *testing.T) {
+	var got api.ChatRequest
+	srv := newTestOpenAIServer(t, "", answerStream(&got))
+	resp := postJSON(t, srv.URL+"/v1/chat/completions", `{
+		"model": "nb-1",
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": "What runs nightly?"},
+			{"role": "assistant", "content": "Deploys."},
+			{"role": "user", "content": [{"type": "text", "text": "When exactly?"}]}
+		]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var c openAICompletion
+	if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Object != "chat.completion" || c.Model != "nb-1" || len(c.Choices) != 1 ||
+		c.Choices[0].Message.Content != "Deploys run nightly [1]." || *c.Choices[0].FinishReason != "stop" {
+		t.Errorf("completion = %+v", c)
+	}
+	if len(c.Citations) != 1 || c.Citations[0].SourceID != "src-1" {
+		t.Errorf("nlm_citations = %+v", c.Citations)
+	}
+
+	if got.ProjectID != "nb-1" || got.Prompt != "Be brief.\n\nWhen exactly?" || got.SeqNum != 2 {
+		t.Errorf("request = %+v", got)
+	}
+	want := []api.ChatMessage{{Content: "Deploys.", Role: 2}, {Content: "What runs nightly?", Role: 1}}
+	if len(got.History) != 2 || got.History[0] != want[0] || got.History[1] != want[1] {
+		t.Errorf("history = %+v, want %+v", got.History, want)
+	}
+}
+
+func TestOpenAIChatCompletionStream(t *testing.T) {
+	var got api.ChatRequest
+	srv := newTestOpenAIServer(t, "", answerStream(&got))
+	resp := postJSON(t, srv.URL+"/v1/chat/completions",
+		`{"model": "nb-1", "stream": true, "messages": [{"role": "user", "content": "When?"}]}`)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			events = append(events, data)
+		}
+	}
+	if len(events) != 5 || events[4] != "[DONE]" {
+		t.Fatalf("events = %q", events)
+	}
+	var text strings.Builder
+	var last openAICompletion
+	for i, e := range events[:4] {
+		var c openAICompletion
+		if err := json.Unmarshal([]byte(e), &c); err != nil {
+			t.Fatal(err)
+		}
+		if c.Object != "chat.completion.chunk" || len(c.Choices) != 1 {
+			t.Fatalf("event %d = %s", i, e)
+		}
+		text.WriteString(c.Choices[0].Delta.Content)
+		last = c
+	}
+	if !strings.Contains(events[0], `"role":"assistant"`) {
+		t.Errorf("first event = %s, want role", events[0])
+	}
+	if text.String() != "Deploys run nightly [1]." {
+		t.Errorf("streamed text = %q", text.String())
+	}
+	if *last.Choices[0].FinishReason != "stop" || len(last.Citations) != 1 {
+		t.Errorf("final chunk = %s", events[3])
+	}
+}
+
+func TestOpenAIChatCompletionErrors(t *testing.T) {
+	srv := newTestOpenAIServer(t, "", func(context.Context, api.ChatRequest, func(api.ChatChunk) bool) error {
+		return errors.New("backend down")
+	})
+	for _, tt := range []struct {
+		body   string
+		status int
+	}{
+		{`{`, http.StatusBadRequest},
+		{`{"messages": [{"role": "user", "content": "Hi"}]}`, http.StatusBadRequest},
+		{`{"model": "nb-1", "messages": [{"role": "assistant", "content": "Hi"}]}`, http.StatusBadRequest},
+		{`{"model": "nb-1", "messages": [{"role": "tool", "content": "x"}, {"role": "user", "content": "Hi"}]}`, http.StatusBadRequest},
+		{`{"model": "nb-1", "messages": [{"role": "user", "content": [{"type": "image_url"}]}]}`, http.StatusBadRequest},
+		{`{"model": "nb-1", "messages": [{"role": "user", "content": "Hi"}]}`, http.StatusBadGateway},
+		{`{"model": "nb-1", "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`, http.StatusBadGateway},
+	} {
+		resp := postJSON(t, srv.URL+"/v1/chat/completions", tt.body)
+		var e map[string]openAIError
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e["error"].Message == "" {
+			t.Errorf("%s: error body: %v, %v", tt.body, e, err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.body, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestOpenAIAPIKey(t *testing.T) {
+	srv := newTestOpenAIServer(t, "sekret", nil)
+	for key, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "sekret": http.StatusOK} {
+		req, _ := http.NewRequest("GET", srv.URL+"/v1/models", nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("key %q: status = %d, want %d", key, resp.StatusCode, want)
+		}
+	}
+}
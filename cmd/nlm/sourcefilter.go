@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// A source filter, given with -sources, limits chat and transformations to
+// some of a notebook's sources. It is a comma-separated list of terms, and a
+// source is selected if it matches any of them:
+//
+//	type:NAME   sources of a type: youtube, pdf, web, text, docs, slides,
+//	            sheets, file or note
+//	ID          a source ID
+//	GLOB        a title glob such as "Q3*", matched case-insensitively
+//
+// sourceFilterTypes maps the type: names to source types.
+var sourceFilterTypes = map[string]pb.SourceType{
+	"youtube": pb.SourceType_SOURCE_TYPE_YOUTUBE_VIDEO,
+	"web":     pb.SourceType_SOURCE_TYPE_WEB_PAGE,
+	"text":    pb.SourceType_SOURCE_TYPE_TEXT,
+	"docs":    pb.SourceType_SOURCE_TYPE_GOOGLE_DOCS,
+	"slides":  pb.SourceType_SOURCE_TYPE_GOOGLE_SLIDES,
+	"sheets":  pb.SourceType_SOURCE_TYPE_GOOGLE_SHEETS,
+	"file":    pb.SourceType_SOURCE_TYPE_LOCAL_FILE,
+	"note":    pb.SourceType_SOURCE_TYPE_SHARED_NOTE,
+}
+
+// checkSourceFilter reports malformed terms without fetching the notebook.
+func checkSourceFilter(filter string) error {
+	terms := splitList(filter)
+	if len(terms) == 0 {
+		return fmt.Errorf("-sources: empty filter")
+	}
+	for _, t := range terms {
+		if _, err := sourceTermMatcher(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourceTermMatcher returns the predicate for one filter term.
+func sourceTermMatcher(term string) (func(*pb.Source) bool, error) {
+	if name, ok := strings.CutPrefix(term, "type:"); ok {
+		name = strings.ToLower(name)
+		if name == "pdf" {
+			// PDFs are uploaded files; the type alone doesn't tell them apart.
+			return func(src *pb.Source) bool {
+				return src.GetMetadata().GetSourceType() == pb.SourceType_SOURCE_TYPE_LOCAL_FILE &&
+					strings.HasSuffix(strings.ToLower(strings.TrimSpace(src.GetTitle())), ".pdf")
+			}, nil
+		}
+		typ, ok := sourceFilterTypes[name]
+		if !ok {
+			names := append(slices.Collect(maps.Keys(sourceFilterTypes)), "pdf")
+			slices.Sort(names)
+			return nil, fmt.Errorf("-sources: unknown source type %q (use %s)", name, strings.Join(names, ", "))
+		}
+		return func(src *pb.Source) bool { return src.GetMetadata().GetSourceType() == typ }, nil
+	}
+	glob := strings.ToLower(term)
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("-sources: bad title pattern %q: %w", term, err)
+	}
+	return func(src *pb.Source) bool {
+		if src.GetSourceId().GetSourceId() == term {
+			return true
+		}
+		ok, _ := path.Match(glob, strings.ToLower(strings.TrimSpace(src.GetTitle())))
+		return ok
+	}, nil
+}
+
+// matchSources returns the IDs of the sources selected by filter, in
+// notebook order. It is an error for the filter to select nothing.
+func matchSources(sources []*pb.Source, filter string) ([]string, error) {
+	var matchers []func(*pb.Source) bool
+	for _, t := range splitList(filter) {
+		m, err := sourceTermMatcher(t)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	var ids []string
+	for _, src := range sources {
+		for _, m := range matchers {
+			if m(src) {
+				ids = append(ids, src.GetSourceId().GetSourceId())
+				break
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("-sources %q matches none of the notebook's %d sources", filter, len(sources))
+	}
+	return ids, nil
+}
+
+// selectSources resolves the -sources filter against a notebook. It returns
+// nil when no filter is set, meaning all sources.
+func selectSources(c *api.Client, notebookID string) ([]string, error) {
+	if sourceFilter == "" {
+		return nil, nil
+	}
+	nb, err := c.GetProject(notebookID)
+	if err != nil {
+		return nil, fmt.Errorf("get sources: %w", err)
+	}
+	return matchSources(nb.GetSources(), sourceFilter)
+}
+
+// applySourceFilter scopes session to the sources selected with -sources.
+// Without the flag the session keeps the scope it was saved with.
+func applySourceFilter(c *api.Client, session *ChatSession) error {
+	ids, err := selectSources(c, session.NotebookID)
+	if err != nil || ids == nil {
+		return err
+	}
+	session.SourceIDs = ids
+	return nil
+}
+
+// toggleSources flips the sources matched by each term, where a term is a
+// source number as listed by /sources or a filter term. An empty active
+// list means all sources are active, and so does the result when every
+// source ends up selected.
+func toggleSources(sources []*pb.Source, active []string, terms []string) ([]string, error) {
+	on := make(map[string]bool)
+	for _, src := range sources {
+		id := src.GetSourceId().GetSourceId()
+		on[id] = len(active) == 0 || slices.Contains(active, id)
+	}
+	for _, t := range terms {
+		if n, err := strconv.Atoi(t); err == nil {
+			if n < 1 || n > len(sources) {
+				return nil, fmt.Errorf("no source %d (the notebook has %d)", n, len(sources))
+			}
+			id := sources[n-1].GetSourceId().GetSourceId()
+			on[id] = !on[id]
+			continue
+		}
+		ids, err := matchSources(sources, t)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			on[id] = !on[id]
+		}
+	}
+	var next []string
+	for _, src := range sources {
+		if id := src.GetSourceId().GetSourceId(); on[id] {
+			next = append(next, id)
+		}
+	}
+	switch len(next) {
+	case 0:
+		return nil, fmt.Errorf("at least one source must stay active")
+	case len(sources):
+		return nil, nil
+	}
+	return next, nil
+}
+
+// writeSourceScope lists the notebook's sources, numbered for /sources and
+// marking the active ones.
+func writeSourceScope(w io.Writer, sources []*pb.Source, active []string) {
+	n := 0
+	for i, src := range sources {
+		mark := " "
+		if len(active) == 0 || slices.Contains(active, src.GetSourceId().GetSourceId()) {
+			mark = "*"
+			n++
+		}
+		typ := strings.ToLower(strings.TrimPrefix(src.GetMetadata().GetSourceType().String(), "SOURCE_TYPE_"))
+		fmt.Fprintf(w, "  %s %2d. %s (%s)\n", mark, i+1, strings.TrimSpace(src.GetTitle()), typ)
+	}
+	if n == len(sources) {
+		fmt.Fprintf(w, "All %d sources active.\n", len(sources))
+	} else {
+		fmt.Fprintf(w, "%d of %d sources active.\n", n, len(sources))
+	}
+}
+
+// chatSourcesCommand implements /sources in interactive chat: with no
+// arguments it lists the sources, "all" makes every source active again,
+// and anything else toggles the matching sources.
+func chatSourcesCommand(w io.Writer, c *api.Client, session *ChatSession, args []string) error {
+	nb, err := c.GetProject(session.NotebookID)
+	if err != nil {
+		return fmt.Errorf("get sources: %w", err)
+	}
+	sources := nb.GetSources()
+	switch {
+	case len(args) == 1 && args[0] == "all":
+		session.SourceIDs = nil
+	case len(args) > 0:
+		next, err := toggleSources(sources, session.SourceIDs, args)
+		if err != nil {
+			return err
+		}
+		session.SourceIDs = next
+	}
+	writeSourceScope(w, sources, session.SourceIDs)
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+)
+
+func testFilterSources() []*pb.Source {
+	src := func(id, title string, typ pb.SourceType) *pb.Source {
+		return &pb.Source{
+			SourceId: &pb.SourceId{SourceId: id},
+			Title:    title,
+			Metadata: &pb.SourceMetadata{SourceType: typ},
+		}
+	}
+	return []*pb.Source{
+		src("s1", "Q3 Report.pdf", pb.SourceType_SOURCE_TYPE_LOCAL_FILE),
+		src("s2", "Launch talk", pb.SourceType_SOURCE_TYPE_YOUTUBE_VIDEO),
+		src("s3", "q3 roadmap", pb.SourceType_SOURCE_TYPE_WEB_PAGE),
+		src("s4", "notes.txt", pb.SourceType_SOURCE_TYPE_LOCAL_FILE),
+	}
+}
+
+func TestMatchSources(t *testing.T) {
+	for _, tt := range []struct {
+		filter string
+		want   []string
+	}{
+		{"type:pdf", []string{"s1"}},
+		{"type:YouTube", []string{"s2"}},
+		{"type:web,type:pdf", []string{"s1", "s3"}},
+		{"type:file", []string{"s1", "s4"}},
+		{"Q3*", []string{"s1", "s3"}},
+		{"s4, launch*", []string{"s2", "s4"}},
+	} {
+		got, err := matchSources(testFilterSources(), tt.filter)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("matchSources(%q) = %v, %v; want %v", tt.filter, got, err, tt.want)
+		}
+	}
+	for _, filter := range []string{"type:sheets", "type:podcast", "Q4*", "[x"} {
+		if got, err := matchSources(testFilterSources(), filter); err == nil {
+			t.Errorf("matchSources(%q) = %v, want error", filter, got)
+		}
+	}
+}
+
+func TestToggleSources(t *testing.T) {
+	sources := testFilterSources()
+	got, err := toggleSources(sources, nil, []string{"2", "type:web"})
+	if err != nil || !slices.Equal(got, []string{"s1", "s4"}) {
+		t.Fatalf("toggle from all = %v, %v", got, err)
+	}
+	got, err = toggleSources(sources, got, []string{"2", "3"})
+	if err != nil || got != nil {
+		t.Errorf("toggle back to all = %v, %v; want nil", got, err)
+	}
+	if _, err := toggleSources(sources, []string{"s1"}, []string{"1"}); err == nil {
+		t.Error("turning off the last source succeeded, want error")
+	}
+	if _, err := toggleSources(sources, nil, []string{"5"}); err == nil {
+		t.Error("toggling source 5 succeeded, want error")
+	}
+}
+
+func TestWriteSourceScope(t *testing.T) {
+	var buf bytes.Buffer
+	writeSourceScope(&buf, testFilterSources(), []string{"s2", "gone"})
+	got := buf.String()
+	for _, want := range []string{
+		"     1. Q3 Report.pdf (local_file)\n",
+		"  *  2. Launch talk (youtube_video)\n",
+		"1 of 4 sources active.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestNextChatRequestSources(t *testing.T) {
+	session := newChatSession("nb-1")
+	session.SourceIDs = []string{"s1"}
+	session.Messages = append(session.Messages, ChatMessage{Role: "user", Content: "Hi"})
+	if req := nextChatRequest(session); !slices.Equal(req.SourceIDs, []string{"s1"}) {
+		t.Errorf("SourceIDs = %v, want [s1]", req.SourceIDs)
+	}
+	if fork := forkChatSession(session); !slices.Equal(fork.SourceIDs, session.SourceIDs) {
+		t.Errorf("fork SourceIDs = %v", fork.SourceIDs)
+	}
+}
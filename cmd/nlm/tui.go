@@ -0,0 +1,994 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/term"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// tuiTab identifies what the list pane shows.
+type tuiTab int
+
+const (
+	tabNotebooks tuiTab = iota
+	tabSources
+	tabNotes
+	tabArtifacts
+)
+
+func (t tuiTab) String() string {
+	switch t {
+	case tabSources:
+		return "Sources"
+	case tabNotes:
+		return "Notes"
+	case tabArtifacts:
+		return "Artifacts"
+	}
+	return "Notebooks"
+}
+
+// tuiItem is one row of the list pane.
+type tuiItem struct {
+	ID     string
+	Title  string
+	Detail string // secondary column: source type, artifact state, ...
+}
+
+// tuiBackend is what the TUI needs from NotebookLM. It is implemented by
+// apiTUIBackend and faked in tests.
+type tuiBackend interface {
+	List(notebookID string, tab tuiTab) ([]tuiItem, error)
+	Preview(notebookID string, tab tuiTab, id string) (string, error)
+	Add(notebookID, input string) error // creates a notebook when notebookID is empty
+	Delete(notebookID string, tab tuiTab, id string) error
+	Rename(notebookID string, tab tuiTab, id, title string) error
+	GenerateAudio(notebookID, instructions string) error
+	Chat(notebookID, prompt string, onChunk func(api.ChatChunk)) error
+}
+
+// tuiMode is the input mode of the TUI.
+type tuiMode int
+
+const (
+	modeNormal tuiMode = iota
+	modeInput
+	modeConfirm
+)
+
+// tuiModel is the state of the TUI. All fields are owned by the goroutine
+// running the event loop; background work posts closures to updates.
+type tuiModel struct {
+	backend tuiBackend
+	updates chan func(*tuiModel)
+	sync    bool // run background work inline (tests)
+
+	notebookID    string // empty on the notebook list
+	notebookTitle string
+	tab           tuiTab
+	items         []tuiItem
+	cursor        int
+	offset        int
+	listCursor    int // notebook list cursor, restored on back
+
+	previewTitle  string
+	preview       string
+	previewScroll int
+
+	chatOpen   bool
+	chatBusy   bool
+	chatLog    strings.Builder
+	chatStatus tuiStatusLine
+	renderer   *chatStreamRenderer
+
+	mode      tuiMode
+	prompt    string
+	input     []rune
+	onInput   func(string)
+	onConfirm func()
+
+	status string
+	busy   int
+	quit   bool
+}
+
+func newTUIModel(b tuiBackend) *tuiModel {
+	return &tuiModel{
+		backend: b,
+		updates: make(chan func(*tuiModel), 64),
+	}
+}
+
+// runTUI runs the full-screen notebook browser on the terminal.
+func runTUI(c *api.Client) error {
+	return runTUIWith(&apiTUIBackend{c: c}, os.Stdin, os.Stdout)
+}
+
+func runTUIWith(b tuiBackend, in *os.File, out *os.File) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(out.Fd())) {
+		return fmt.Errorf("tui requires a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("enter raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	// Alternate screen, hidden cursor.
+	fmt.Fprint(out, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(out, "\033[?25h\033[?1049l")
+
+	m := newTUIModel(b)
+	m.reload()
+
+	keys := make(chan tuiKey, 16)
+	go readTUIKeys(in, keys)
+
+	for !m.quit {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		var buf bytes.Buffer
+		buf.WriteString("\033[H")
+		for i, line := range m.view(width, height) {
+			if i > 0 {
+				buf.WriteString("\r\n")
+			}
+			buf.WriteString(line)
+			buf.WriteString("\033[K")
+		}
+		out.Write(buf.Bytes())
+
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			m.handleKey(k)
+		case fn := <-m.updates:
+			fn(m)
+		}
+	}
+	return nil
+}
+
+// do runs work in the background and applies the update it returns.
+func (m *tuiModel) do(work func() func(*tuiModel)) {
+	m.busy++
+	apply := func(update func(*tuiModel)) {
+		m.busy--
+		if update != nil {
+			update(m)
+		}
+	}
+	if m.sync {
+		apply(work())
+		return
+	}
+	go func() {
+		update := work()
+		m.updates <- func(m *tuiModel) { apply(update) }
+	}()
+}
+
+// post queues an update from a background goroutine.
+func (m *tuiModel) post(fn func(*tuiModel)) {
+	if m.sync {
+		fn(m)
+		return
+	}
+	m.updates <- fn
+}
+
+// reload refetches the list for the current level.
+func (m *tuiModel) reload() {
+	notebookID, tab := m.notebookID, m.tab
+	m.status = "Loading " + strings.ToLower(tab.String()) + "..."
+	m.do(func() func(*tuiModel) {
+		items, err := m.backend.List(notebookID, tab)
+		return func(m *tuiModel) {
+			if m.notebookID != notebookID || m.tab != tab {
+				return // navigated away meanwhile
+			}
+			if err != nil {
+				m.status = "Error: " + err.Error()
+				return
+			}
+			m.items = items
+			m.cursor = min(m.cursor, max(len(items)-1, 0))
+			m.status = fmt.Sprintf("%d %s", len(items), strings.ToLower(tab.String()))
+		}
+	})
+}
+
+func (m *tuiModel) selected() (tuiItem, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.items) {
+		return tuiItem{}, false
+	}
+	return m.items[m.cursor], true
+}
+
+func (m *tuiModel) setTab(tab tuiTab) {
+	if m.notebookID == "" || tab == tabNotebooks {
+		return
+	}
+	m.tab = tab
+	m.items, m.cursor, m.offset = nil, 0, 0
+	m.reload()
+}
+
+func (m *tuiModel) open() {
+	item, ok := m.selected()
+	if !ok {
+		return
+	}
+	if m.notebookID == "" {
+		m.listCursor = m.cursor
+		m.notebookID, m.notebookTitle = item.ID, item.Title
+		m.chatOpen = false
+		m.previewTitle, m.preview = "", ""
+		m.setTab(tabSources)
+		return
+	}
+	m.showPreview(item)
+}
+
+func (m *tuiModel) back() {
+	if m.chatOpen {
+		m.chatOpen = false
+		return
+	}
+	if m.notebookID == "" {
+		return
+	}
+	m.notebookID, m.notebookTitle = "", ""
+	m.tab = tabNotebooks
+	m.items, m.cursor, m.offset = nil, m.listCursor, 0
+	m.previewTitle, m.preview = "", ""
+	m.reload()
+}
+
+func (m *tuiModel) showPreview(item tuiItem) {
+	notebookID, tab := m.notebookID, m.tab
+	m.chatOpen = false
+	m.previewTitle, m.preview, m.previewScroll = item.Title, "Loading...", 0
+	m.do(func() func(*tuiModel) {
+		text, err := m.backend.Preview(notebookID, tab, item.ID)
+		return func(m *tuiModel) {
+			if m.previewTitle != item.Title {
+				return
+			}
+			if err != nil {
+				m.preview = "Error: " + err.Error()
+				return
+			}
+			m.preview = text
+		}
+	})
+}
+
+// ask switches to input mode with the given prompt.
+func (m *tuiModel) ask(prompt string, onInput func(string)) {
+	m.mode, m.prompt, m.input, m.onInput = modeInput, prompt, nil, onInput
+}
+
+// confirm switches to confirm mode with the given question.
+func (m *tuiModel) confirm(question string, onConfirm func()) {
+	m.mode, m.prompt, m.onConfirm = modeConfirm, question+" [y/N] ", onConfirm
+}
+
+// act runs a mutating backend call in the background, then reloads the list.
+func (m *tuiModel) act(doing, done string, fn func() error) {
+	m.status = doing
+	m.do(func() func(*tuiModel) {
+		err := fn()
+		return func(m *tuiModel) {
+			if err != nil {
+				m.status = "Error: " + err.Error()
+				return
+			}
+			m.status = done
+			m.reload()
+			m.status = done
+		}
+	})
+}
+
+func (m *tuiModel) handleKey(k tuiKey) {
+	switch m.mode {
+	case modeInput:
+		m.handleInputKey(k)
+		return
+	case modeConfirm:
+		m.mode = modeNormal
+		if k.r == 'y' || k.r == 'Y' {
+			m.onConfirm()
+		} else {
+			m.status = "Cancelled"
+		}
+		return
+	}
+
+	switch {
+	case k.name == keyCtrlC || k.r == 'q':
+		m.quit = true
+	case k.name == keyUp || k.r == 'k':
+		m.cursor = max(m.cursor-1, 0)
+	case k.name == keyDown || k.r == 'j':
+		m.cursor = min(m.cursor+1, max(len(m.items)-1, 0))
+	case k.name == keyPgUp:
+		m.cursor = max(m.cursor-10, 0)
+	case k.name == keyPgDn:
+		m.cursor = min(m.cursor+10, max(len(m.items)-1, 0))
+	case k.name == keyEnter || k.name == keyRight || k.r == 'l':
+		m.open()
+	case k.name == keyLeft || k.name == keyEsc || k.name == keyBackspace || k.r == 'h':
+		m.back()
+	case k.name == keyTab:
+		m.setTab(m.tab%3 + 1)
+	case k.r >= '1' && k.r <= '3':
+		m.setTab(tuiTab(k.r - '0'))
+	case k.r == 'J':
+		m.previewScroll++
+	case k.r == 'K':
+		m.previewScroll = max(m.previewScroll-1, 0)
+	case k.r == 'R':
+		m.reload()
+	case k.r == '?':
+		m.chatOpen = false
+		m.previewTitle, m.preview, m.previewScroll = "Keys", tuiHelp, 0
+	case k.r == 'a':
+		m.startAdd()
+	case k.r == 'd':
+		m.startDelete()
+	case k.r == 'r':
+		m.startRename()
+	case k.r == 'g':
+		m.startGenerateAudio()
+	case k.r == 'c':
+		m.startChat()
+	}
+}
+
+func (m *tuiModel) handleInputKey(k tuiKey) {
+	switch k.name {
+	case keyEnter:
+		m.mode = modeNormal
+		if text := strings.TrimSpace(string(m.input)); text != "" {
+			m.onInput(text)
+		}
+	case keyEsc, keyCtrlC:
+		m.mode = modeNormal
+		m.status = "Cancelled"
+	case keyBackspace:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	case keyCtrlU:
+		m.input = nil
+	case keyTab:
+		m.input = append(m.input, ' ')
+	case "":
+		m.input = append(m.input, k.r)
+	}
+}
+
+func (m *tuiModel) startAdd() {
+	notebookID := m.notebookID
+	if notebookID == "" {
+		m.ask("New notebook title: ", func(title string) {
+			m.act("Creating notebook...", "Created notebook "+title, func() error {
+				return m.backend.Add("", title)
+			})
+		})
+		return
+	}
+	m.ask("Add source (URL, file or text): ", func(input string) {
+		m.setTab(tabSources)
+		m.act("Adding source...", "Added source", func() error {
+			return m.backend.Add(notebookID, input)
+		})
+	})
+}
+
+func (m *tuiModel) startDelete() {
+	item, ok := m.selected()
+	if !ok {
+		return
+	}
+	notebookID, tab := m.notebookID, m.tab
+	kind := strings.ToLower(strings.TrimSuffix(tab.String(), "s"))
+	m.confirm(fmt.Sprintf("Delete %s %q?", kind, item.Title), func() {
+		m.act("Deleting...", "Deleted "+item.Title, func() error {
+			return m.backend.Delete(notebookID, tab, item.ID)
+		})
+	})
+}
+
+func (m *tuiModel) startRename() {
+	item, ok := m.selected()
+	if !ok {
+		return
+	}
+	notebookID, tab := m.notebookID, m.tab
+	m.ask(fmt.Sprintf("Rename %q to: ", item.Title), func(title string) {
+		m.act("Renaming...", "Renamed to "+title, func() error {
+			return m.backend.Rename(notebookID, tab, item.ID, title)
+		})
+	})
+	m.input = []rune(item.Title)
+}
+
+func (m *tuiModel) startGenerateAudio() {
+	notebookID := m.notebookID
+	if notebookID == "" {
+		if item, ok := m.selected(); ok {
+			notebookID = item.ID
+		}
+	}
+	if notebookID == "" {
+		return
+	}
+	m.ask("Audio overview instructions: ", func(instructions string) {
+		m.act("Starting audio overview...", "Audio overview started; see Artifacts", func() error {
+			return m.backend.GenerateAudio(notebookID, instructions)
+		})
+	})
+}
+
+func (m *tuiModel) startChat() {
+	if m.notebookID == "" {
+		m.status = "Open a notebook to chat"
+		return
+	}
+	if m.chatBusy {
+		m.chatOpen = true
+		return
+	}
+	m.chatOpen = true
+	m.previewScroll = 0
+	m.ask("Ask: ", m.sendChat)
+}
+
+// sendChat streams an answer to prompt into the chat pane, rendering it
+// with the same chatStreamRenderer the chat command uses.
+func (m *tuiModel) sendChat(prompt string) {
+	notebookID := m.notebookID
+	fmt.Fprintf(&m.chatLog, "> %s\n\n", prompt)
+	m.chatStatus.Reset()
+	m.renderer = newChatStreamRenderer(&m.chatLog, &m.chatStatus, true, false)
+	m.chatBusy = true
+	m.status = "Thinking..."
+	m.do(func() func(*tuiModel) {
+		err := m.backend.Chat(notebookID, prompt, func(chunk api.ChatChunk) {
+			m.post(func(m *tuiModel) { m.renderer.WriteChunk(chunk) })
+		})
+		return func(m *tuiModel) {
+			m.renderer.Finish()
+			m.chatBusy = false
+			m.chatLog.WriteString("\n\n")
+			if err != nil {
+				fmt.Fprintf(&m.chatLog, "Error: %v\n\n", err)
+				m.status = "Chat failed"
+				return
+			}
+			m.status = "Press c to ask a follow-up"
+		}
+	})
+}
+
+const tuiHelp = `Navigation
+  ↑/k ↓/j      move
+  enter/→/l    open notebook, preview item
+  esc/←/h      back
+  tab, 1 2 3   sources, notes, artifacts
+  J/K          scroll preview
+  R            reload
+
+Actions
+  a            add source (new notebook on the list)
+  d            delete
+  r            rename
+  g            generate audio overview
+  c            chat with the notebook
+  q            quit`
+
+// view renders the screen as height lines of at most width columns.
+func (m *tuiModel) view(width, height int) []string {
+	width, height = max(width, 20), max(height, 5)
+	lines := make([]string, 0, height)
+
+	crumb := "nlm"
+	if m.notebookID != "" {
+		crumb += " › " + m.notebookTitle + " › "
+		for t := tabSources; t <= tabArtifacts; t++ {
+			if t == m.tab {
+				crumb += "[" + t.String() + "]"
+			} else {
+				crumb += " " + t.String() + " "
+			}
+		}
+	} else {
+		crumb += " › Notebooks"
+	}
+	lines = append(lines, "\033[7m"+tuiFit(" "+crumb, width)+"\033[0m")
+
+	bodyHeight := height - 3
+	leftWidth := max(width*2/5, 10)
+	rightWidth := width - leftWidth - 3
+
+	// Keep the cursor visible.
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+bodyHeight {
+		m.offset = m.cursor - bodyHeight + 1
+	}
+
+	right := m.rightPane(rightWidth, bodyHeight)
+	for i := 0; i < bodyHeight; i++ {
+		left := ""
+		if idx := m.offset + i; idx < len(m.items) {
+			item := m.items[idx]
+			text := item.Title
+			if item.Detail != "" {
+				text += "  " + ansiGrey + item.Detail + ansiReset
+			}
+			left = tuiFit(" "+text, leftWidth)
+			if idx == m.cursor {
+				left = "\033[7m" + tuiFit(" "+item.Title, leftWidth) + "\033[0m"
+			}
+		} else if idx == 0 && len(m.items) == 0 && m.busy == 0 {
+			left = tuiFit(" (empty)", leftWidth)
+		} else {
+			left = strings.Repeat(" ", leftWidth)
+		}
+		r := ""
+		if i < len(right) {
+			r = right[i]
+		}
+		lines = append(lines, left+" │ "+r)
+	}
+
+	status := m.status
+	if m.busy > 0 {
+		status = "⏳ " + status
+	}
+	if m.chatOpen && m.chatBusy && m.chatStatus.String() != "" {
+		status = m.chatStatus.String()
+	}
+	lines = append(lines, tuiFit(status, width))
+
+	switch m.mode {
+	case modeInput:
+		lines = append(lines, tuiFit(m.prompt+string(m.input)+"█", width))
+	case modeConfirm:
+		lines = append(lines, tuiFit(m.prompt, width))
+	default:
+		lines = append(lines, ansiGrey+tuiFit("enter open  esc back  tab switch  a add  d delete  r rename  g audio  c chat  ? help  q quit", width)+ansiReset)
+	}
+	return lines
+}
+
+// rightPane returns the wrapped lines of the preview or chat pane.
+func (m *tuiModel) rightPane(width, height int) []string {
+	var title, text string
+	switch {
+	case m.chatOpen:
+		title, text = "Chat", m.chatLog.String()
+	case m.preview != "":
+		title, text = m.previewTitle, m.preview
+	default:
+		return nil
+	}
+	lines := []string{"\033[1m" + tuiFit(title, width) + "\033[0m"}
+	body := tuiWrap(text, width)
+	if m.chatOpen {
+		// Follow the end of the conversation unless scrolled back.
+		start := max(len(body)-(height-1)-m.previewScroll, 0)
+		body = body[start:]
+	} else {
+		m.previewScroll = min(m.previewScroll, max(len(body)-1, 0))
+		body = body[m.previewScroll:]
+	}
+	return append(lines, body...)
+}
+
+var ansiPattern = regexp.MustCompile("\033\\[[0-9;?]*[a-zA-Z]")
+
+// tuiFit truncates or pads s to exactly width display columns, treating
+// each rune as one column and ANSI escapes as zero.
+func tuiFit(s string, width int) string {
+	var b strings.Builder
+	cols := 0
+	for i := 0; i < len(s); {
+		if loc := ansiPattern.FindStringIndex(s[i:]); loc != nil && loc[0] == 0 {
+			b.WriteString(s[i : i+loc[1]])
+			i += loc[1]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		if r == '\t' {
+			r = ' '
+		}
+		if unicode.IsControl(r) {
+			continue
+		}
+		if cols == width-1 && i < len(s) && utf8.RuneCountInString(ansiPattern.ReplaceAllString(s[i:], "")) > 0 {
+			b.WriteRune('…')
+			cols++
+			break
+		}
+		b.WriteRune(r)
+		cols++
+		if cols == width {
+			break
+		}
+	}
+	if strings.Contains(s, "\033[") {
+		b.WriteString(ansiReset)
+	}
+	return b.String() + strings.Repeat(" ", max(width-cols, 0))
+}
+
+// tuiWrap word-wraps text to width columns.
+func tuiWrap(text string, width int) []string {
+	text = ansiPattern.ReplaceAllString(text, "")
+	var out []string
+	for _, para := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		line := []rune{}
+		for _, word := range strings.Fields(para) {
+			w := []rune(word)
+			if len(line) > 0 && len(line)+1+len(w) > width {
+				out = append(out, string(line))
+				line = line[:0]
+			}
+			for len(w) > width {
+				out = append(out, string(w[:width]))
+				w = w[width:]
+			}
+			if len(line) > 0 {
+				line = append(line, ' ')
+			}
+			line = append(line, w...)
+		}
+		out = append(out, string(line))
+	}
+	return out
+}
+
+// tuiStatusLine is an io.Writer that keeps only the text after the last
+// carriage return, matching how chatStreamRenderer overwrites its
+// thinking line.
+type tuiStatusLine struct {
+	line string
+}
+
+func (s *tuiStatusLine) Write(p []byte) (int, error) {
+	text := s.line + string(p)
+	if i := strings.LastIndexAny(text, "\r\n"); i >= 0 {
+		text = text[i+1:]
+	}
+	s.line = text
+	return len(p), nil
+}
+
+func (s *tuiStatusLine) String() string {
+	return strings.TrimSpace(ansiPattern.ReplaceAllString(s.line, ""))
+}
+
+func (s *tuiStatusLine) Reset() { s.line = "" }
+
+// Key names produced by readTUIKeys. Printable keys have an empty name and
+// carry their rune.
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyLeft      = "left"
+	keyRight     = "right"
+	keyPgUp      = "pgup"
+	keyPgDn      = "pgdn"
+	keyEnter     = "enter"
+	keyEsc       = "esc"
+	keyTab       = "tab"
+	keyBackspace = "backspace"
+	keyCtrlC     = "ctrl-c"
+	keyCtrlU     = "ctrl-u"
+)
+
+type tuiKey struct {
+	name string
+	r    rune
+}
+
+var tuiEscapeKeys = map[string]string{
+	"\033[A": keyUp, "\033[B": keyDown, "\033[C": keyRight, "\033[D": keyLeft,
+	"\033OA": keyUp, "\033OB": keyDown, "\033OC": keyRight, "\033OD": keyLeft,
+	"\033[5~": keyPgUp, "\033[6~": keyPgDn,
+}
+
+// parseTUIKeys decodes a chunk of raw terminal input.
+func parseTUIKeys(p []byte) []tuiKey {
+	var keys []tuiKey
+	for len(p) > 0 {
+		if p[0] == 0x1b {
+			if len(p) == 1 {
+				keys = append(keys, tuiKey{name: keyEsc})
+				break
+			}
+			matched := false
+			for seq, name := range tuiEscapeKeys {
+				if bytes.HasPrefix(p, []byte(seq)) {
+					keys = append(keys, tuiKey{name: name})
+					p = p[len(seq):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				// Unknown sequence: skip to its final byte.
+				i := 1
+				if i < len(p) && (p[i] == '[' || p[i] == 'O') {
+					i++
+					for i < len(p) && (p[i] < 0x40 || p[i] > 0x7e) {
+						i++
+					}
+				}
+				p = p[min(i+1, len(p)):]
+			}
+			continue
+		}
+		r, size := utf8.DecodeRune(p)
+		p = p[size:]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, tuiKey{name: keyEnter})
+		case '\t':
+			keys = append(keys, tuiKey{name: keyTab})
+		case 0x7f, 0x08:
+			keys = append(keys, tuiKey{name: keyBackspace})
+		case 0x03:
+			keys = append(keys, tuiKey{name: keyCtrlC})
+		case 0x15:
+			keys = append(keys, tuiKey{name: keyCtrlU})
+		default:
+			if !unicode.IsControl(r) {
+				keys = append(keys, tuiKey{r: r})
+			}
+		}
+	}
+	return keys
+}
+
+func readTUIKeys(r io.Reader, keys chan<- tuiKey) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		for _, k := range parseTUIKeys(buf[:n]) {
+			keys <- k
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// apiTUIBackend implements tuiBackend with the NotebookLM API.
+type apiTUIBackend struct {
+	c *api.Client
+}
+
+func (b *apiTUIBackend) List(notebookID string, tab tuiTab) ([]tuiItem, error) {
+	var items []tuiItem
+	switch tab {
+	case tabNotebooks:
+		notebooks, err := b.c.ListRecentlyViewedProjects()
+		if err != nil {
+			return nil, fmt.Errorf("list notebooks: %w", err)
+		}
+		for _, nb := range notebooks {
+			items = append(items, tuiItem{
+				ID:     nb.GetProjectId(),
+				Title:  strings.TrimSpace(strings.TrimSpace(nb.GetEmoji()) + " " + nb.GetTitle()),
+				Detail: fmt.Sprintf("%d sources", len(nb.GetSources())),
+			})
+		}
+	case tabSources:
+		p, err := b.c.GetProject(notebookID)
+		if err != nil {
+			return nil, fmt.Errorf("list sources: %w", err)
+		}
+		for _, src := range p.GetSources() {
+			items = append(items, tuiItem{
+				ID:     src.GetSourceId().GetSourceId(),
+				Title:  strings.TrimSpace(src.GetTitle()),
+				Detail: strings.TrimPrefix(src.GetMetadata().GetSourceType().String(), "SOURCE_TYPE_"),
+			})
+		}
+	case tabNotes:
+		notes, err := b.c.GetNotes(notebookID)
+		if err != nil {
+			return nil, fmt.Errorf("list notes: %w", err)
+		}
+		for _, n := range notes {
+			items = append(items, tuiItem{ID: n.GetNoteId(), Title: n.GetTitle()})
+		}
+	case tabArtifacts:
+		artifacts, err := b.c.ListArtifacts(notebookID)
+		if err != nil {
+			return nil, fmt.Errorf("list artifacts: %w", err)
+		}
+		for _, a := range artifacts {
+			items = append(items, tuiItem{
+				ID:     a.GetArtifactId(),
+				Title:  strings.TrimPrefix(a.GetType().String(), "ARTIFACT_TYPE_"),
+				Detail: strings.TrimPrefix(a.GetState().String(), "ARTIFACT_STATE_"),
+			})
+		}
+	}
+	return items, nil
+}
+
+func (b *apiTUIBackend) Preview(notebookID string, tab tuiTab, id string) (string, error) {
+	var buf bytes.Buffer
+	var msg proto.Message
+	switch tab {
+	case tabNotes:
+		if err := readNote(b.c, &buf, notebookID, id); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	case tabSources:
+		src, err := b.c.LoadSource(id)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, "%s\n\nID: %s\nType: %s\nStatus: %s\n",
+			src.GetTitle(), id,
+			src.GetMetadata().GetSourceType(),
+			src.GetMetadata().GetStatus())
+		if t := src.GetMetadata().GetLastModifiedTime(); t != nil {
+			fmt.Fprintf(&buf, "Last modified: %s\n", t.AsTime().Format(time.RFC3339))
+		}
+		buf.WriteString("\n")
+		msg = src.GetMetadata()
+	case tabArtifacts:
+		a, err := b.c.GetArtifact(id)
+		if err != nil {
+			return "", err
+		}
+		msg = a
+	default:
+		p, err := b.c.GetProject(id)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, "ID: %s\nSources: %d\n\n", id, len(p.GetSources()))
+		for _, src := range p.GetSources() {
+			fmt.Fprintf(&buf, "• %s\n", strings.TrimSpace(src.GetTitle()))
+		}
+		return buf.String(), nil
+	}
+	buf.WriteString(protojson.MarshalOptions{Multiline: true}.Format(msg))
+	return buf.String(), nil
+}
+
+func (b *apiTUIBackend) Add(notebookID, input string) error {
+	if notebookID == "" {
+		_, err := b.c.CreateProject(input, "📙")
+		return err
+	}
+	_, err := addSourceInput(b.c, io.Discard, notebookID, input)
+	return err
+}
+
+func (b *apiTUIBackend) Delete(notebookID string, tab tuiTab, id string) error {
+	switch tab {
+	case tabSources:
+		return b.c.DeleteSources(notebookID, []string{id})
+	case tabNotes:
+		return b.c.DeleteNotes(notebookID, []string{id})
+	case tabArtifacts:
+		_, err := b.c.OrchestrationService().DeleteArtifact(context.Background(), &pb.DeleteArtifactRequest{ArtifactId: id})
+		return err
+	default:
+		return b.c.DeleteProjects([]string{id})
+	}
+}
+
+func (b *apiTUIBackend) Rename(notebookID string, tab tuiTab, id, title string) error {
+	var err error
+	switch tab {
+	case tabSources:
+		_, err = b.c.MutateSource(id, &pb.Source{Title: title})
+	case tabNotes:
+		var notes []*api.Note
+		if notes, err = b.c.GetNotes(notebookID); err != nil {
+			return err
+		}
+		for _, n := range notes {
+			if n.GetNoteId() == id {
+				_, err = b.c.MutateNote(notebookID, id, n.GetContentText(), title)
+				return err
+			}
+		}
+		return fmt.Errorf("note %s not found", id)
+	case tabArtifacts:
+		_, err = b.c.RenameArtifact(id, title)
+	default:
+		_, err = b.c.MutateProject(id, &pb.Project{Title: title})
+	}
+	return err
+}
+
+func (b *apiTUIBackend) GenerateAudio(notebookID, instructions string) error {
+	if existing, _ := b.c.ListAudioOverviews(notebookID); len(existing) > 0 {
+		return fmt.Errorf("notebook already has an audio overview; delete it from Artifacts first")
+	}
+	_, err := b.c.CreateAudioOverview(notebookID, instructions)
+	return err
+}
+
+// Chat sends prompt in the notebook's saved chat session, so conversations
+// continue across the TUI and the chat command, limited to the same sources.
+func (b *apiTUIBackend) Chat(notebookID, prompt string, onChunk func(api.ChatChunk)) error {
+	session, err := loadChatSession(notebookID)
+	if err != nil {
+		session = &ChatSession{
+			NotebookID: notebookID,
+			Messages:   []ChatMessage{},
+			CreatedAt:  time.Now(),
+		}
+	}
+	if session.ConversationID == "" {
+		session.ConversationID = uuid.New().String()
+	}
+	session.Messages = append(session.Messages, ChatMessage{
+		Role: "user", Content: prompt, Timestamp: time.Now(),
+	})
+
+	var answer, thinking strings.Builder
+	var citations []api.Citation
+	err = b.c.StreamChat(nextChatRequest(session), func(chunk api.ChatChunk) bool {
+		switch chunk.Phase {
+		case api.ChatChunkAnswer:
+			answer.WriteString(chunk.Text)
+		case api.ChatChunkThinking:
+			thinking.WriteString(chunk.Text + "\n")
+		case api.ChatChunkCitations:
+			citations = chunk.Citations
+		}
+		onChunk(chunk)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if text := strings.TrimSpace(answer.String()); text != "" {
+		session.Messages = append(session.Messages, ChatMessage{
+			Role: "assistant", Content: text, Timestamp: time.Now(),
+			Thinking: thinking.String(), Citations: citations,
+		})
+	}
+	session.UpdatedAt = time.Now()
+	return saveChatSession(session)
+}
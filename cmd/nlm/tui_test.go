@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+type fakeTUIBackend struct {
+	items map[string][]tuiItem // keyed by notebook ID + "/" + tab
+	calls []string
+}
+
+func (f *fakeTUIBackend) List(notebookID string, tab tuiTab) ([]tuiItem, error) {
+	return f.items[notebookID+"/"+tab.String()], nil
+}
+
+func (f *fakeTUIBackend) Preview(notebookID string, tab tuiTab, id string) (string, error) {
+	return "preview of " + id, nil
+}
+
+func (f *fakeTUIBackend) Add(notebookID, input string) error {
+	f.calls = append(f.calls, fmt.Sprintf("add %s %s", notebookID, input))
+	return nil
+}
+
+func (f *fakeTUIBackend) Delete(notebookID string, tab tuiTab, id string) error {
+	f.calls = append(f.calls, fmt.Sprintf("delete %s %s %s", notebookID, tab, id))
+	return nil
+}
+
+func (f *fakeTUIBackend) Rename(notebookID string, tab tuiTab, id, title string) error {
+	f.calls = append(f.calls, fmt.Sprintf("rename %s %s %s %s", notebookID, tab, id, title))
+	return nil
+}
+
+func (f *fakeTUIBackend) GenerateAudio(notebookID, instructions string) error {
+	f.calls = append(f.calls, fmt.Sprintf("audio %s %s", notebookID, instructions))
+	return nil
+}
+
+func (f *fakeTUIBackend) Chat(notebookID, prompt string, onChunk func(api.ChatChunk)) error {
+	f.calls = append(f.calls, fmt.Sprintf("chat %s %s", notebookID, prompt))
+	onChunk(api.ChatChunk{Phase: api.ChatChunkThinking, Header: "Reading sources", Text: "**Reading sources**"})
+	onChunk(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "The answer "})
+	onChunk(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "is 42."})
+	return nil
+}
+
+func newTestTUI() (*tuiModel, *fakeTUIBackend) {
+	b := &fakeTUIBackend{items: map[string][]tuiItem{
+		"/Notebooks":   {{ID: "nb-1", Title: "Physics"}, {ID: "nb-2", Title: "History"}},
+		"nb-2/Sources": {{ID: "src-1", Title: "Paper", Detail: "PDF"}},
+		"nb-2/Notes":   {{ID: "note-1", Title: "Summary"}},
+	}}
+	m := newTUIModel(b)
+	m.sync = true
+	m.reload()
+	return m, b
+}
+
+func typeKeys(m *tuiModel, s string) {
+	for _, k := range parseTUIKeys([]byte(s)) {
+		m.handleKey(k)
+	}
+}
+
+func TestTUINavigation(t *testing.T) {
+	m, _ := newTestTUI()
+	typeKeys(m, "j\r")
+	if m.notebookID != "nb-2" || m.tab != tabSources {
+		t.Fatalf("after open: notebook %q tab %v, want nb-2 Sources", m.notebookID, m.tab)
+	}
+	if len(m.items) != 1 || m.items[0].ID != "src-1" {
+		t.Fatalf("sources = %v", m.items)
+	}
+	typeKeys(m, "\r")
+	if m.preview != "preview of src-1" {
+		t.Errorf("preview = %q", m.preview)
+	}
+	typeKeys(m, "\t")
+	if m.tab != tabNotes || m.items[0].ID != "note-1" {
+		t.Errorf("after tab: tab %v items %v", m.tab, m.items)
+	}
+	typeKeys(m, "3")
+	if m.tab != tabArtifacts || len(m.items) != 0 {
+		t.Errorf("after 3: tab %v items %v", m.tab, m.items)
+	}
+	typeKeys(m, "\x1b[D")
+	if m.notebookID != "" || m.cursor != 1 {
+		t.Errorf("after back: notebook %q cursor %d, want top level at 1", m.notebookID, m.cursor)
+	}
+	typeKeys(m, "q")
+	if !m.quit {
+		t.Error("q did not quit")
+	}
+}
+
+func TestTUIActions(t *testing.T) {
+	m, b := newTestTUI()
+	typeKeys(m, "aReading list\r")
+	typeKeys(m, "j\r")
+	typeKeys(m, "ahttps://example.com\r")
+	typeKeys(m, "d")
+	if m.mode != modeConfirm {
+		t.Fatalf("d: mode = %v, want confirm", m.mode)
+	}
+	typeKeys(m, "y")
+	typeKeys(m, "r\x15Draft\r")
+	typeKeys(m, "dn")
+	typeKeys(m, "gDeep dive\r")
+	typeKeys(m, "a\x1b") // cancelled
+	want := []string{
+		"add  Reading list",
+		"add nb-2 https://example.com",
+		"delete nb-2 Sources src-1",
+		"rename nb-2 Sources src-1 Draft",
+		"audio nb-2 Deep dive",
+	}
+	if !slices.Equal(b.calls, want) {
+		t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(b.calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTUIChat(t *testing.T) {
+	m, b := newTestTUI()
+	typeKeys(m, "c")
+	if m.mode != modeNormal || m.chatOpen {
+		t.Fatal("chat opened without a notebook")
+	}
+	typeKeys(m, "j\rcWhat is it?\r")
+	if len(b.calls) != 1 || b.calls[0] != "chat nb-2 What is it?" {
+		t.Fatalf("calls = %q", b.calls)
+	}
+	screen := strings.Join(m.view(80, 12), "\n")
+	for _, want := range []string{"> What is it?", "The answer is 42.", "History"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen missing %q:\n%s", want, screen)
+		}
+	}
+}
+
+func TestTUIView(t *testing.T) {
+	m, _ := newTestTUI()
+	lines := m.view(40, 8)
+	if len(lines) != 8 {
+		t.Fatalf("view returned %d lines, want 8", len(lines))
+	}
+	for i, line := range lines {
+		if n := len([]rune(ansiPattern.ReplaceAllString(line, ""))); n > 40 {
+			t.Errorf("line %d is %d columns wide: %q", i, n, line)
+		}
+	}
+}
+
+func TestParseTUIKeys(t *testing.T) {
+	got := parseTUIKeys([]byte("a\x1b[A\x1b[6~\r\x7fé\x1b[1;5C\x1b"))
+	want := []tuiKey{{r: 'a'}, {name: keyUp}, {name: keyPgDn}, {name: keyEnter}, {name: keyBackspace}, {r: 'é'}, {name: keyEsc}}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseTUIKeys = %v, want %v", got, want)
+	}
+}
+
+func TestTUIFit(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abc…"},
+		{"abcd", 4, "abcd"},
+	}
+	for _, tt := range tests {
+		if got := tuiFit(tt.s, tt.width); got != tt.want {
+			t.Errorf("tuiFit(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// Watch event types.
+const (
+	eventSourceAdded     = "source_added"
+	eventSourceRemoved   = "source_removed"
+	eventSourceStatus    = "source_status"
+	eventNoteAdded       = "note_added"
+	eventNoteEdited      = "note_edited"
+	eventNoteRemoved     = "note_removed"
+	eventArtifactAdded   = "artifact_added"
+	eventArtifactReady   = "artifact_ready"
+	eventArtifactFailed  = "artifact_failed"
+	eventArtifactRemoved = "artifact_removed"
+)
+
+// watchEvent is one change observed in a notebook. It is printed as a
+// JSON line with -json and passed to -exec hooks.
+type watchEvent struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	NotebookID string    `json:"notebook_id"`
+	ID         string    `json:"id"`
+	Title      string    `json:"title,omitempty"`
+	Kind       string    `json:"kind,omitempty"` // source or artifact type
+	Status     string    `json:"status,omitempty"`
+	Previous   string    `json:"previous_status,omitempty"`
+}
+
+// watchItem is the state of one source, note or artifact between polls.
+type watchItem struct {
+	Title  string
+	Kind   string
+	Status string
+	Hash   string // content hash, for notes
+}
+
+// watchSnapshot is the state of a notebook at one poll.
+type watchSnapshot struct {
+	Sources   map[string]watchItem
+	Notes     map[string]watchItem
+	Artifacts map[string]watchItem
+}
+
+type watchOptions struct {
+	Interval time.Duration
+	JSON     bool
+	Exec     string
+}
+
+func parseWatchArgs(args []string) (watchOptions, string, error) {
+	opts := watchOptions{Interval: 30 * time.Second}
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.DurationVar(&opts.Interval, "interval", opts.Interval, "polling interval")
+	flags.BoolVar(&opts.JSON, "json", false, "print events as JSON lines")
+	flags.StringVar(&opts.Exec, "exec", "", "shell command to run for each event")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nlm watch <notebook-id> [-interval 30s] [-json] [-exec command]\n")
+	}
+
+	// Flags may follow the notebook ID.
+	flagArgs, positional := splitFlagArgs(args, "json")
+	if err := flags.Parse(flagArgs); err != nil {
+		return opts, "", fmt.Errorf("invalid arguments")
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return opts, "", fmt.Errorf("invalid arguments")
+	}
+	if opts.Interval < time.Second {
+		return opts, "", fmt.Errorf("-interval must be at least 1s")
+	}
+	return opts, positional[0], nil
+}
+
+// watchNotebook polls the notebook and reports changes until interrupted.
+func watchNotebook(c *api.Client, notebookID string, opts watchOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	hook := newWebhookNotifier()
+	w := &notebookWatcher{
+		interval: opts.Interval,
+		fetch:    func() (*watchSnapshot, error) { return fetchWatchSnapshot(c, notebookID) },
+		emit: func(e watchEvent) {
+			e.NotebookID = notebookID
+			writeWatchEvent(os.Stdout, e, opts.JSON)
+			if opts.Exec != "" {
+				if err := runWatchHook(opts.Exec, e); err != nil {
+					fmt.Fprintf(os.Stderr, "nlm: exec hook for %s: %v\n", e.Type, err)
+				}
+			}
+			if p, ok := watchEventWebhook(e); ok {
+				hook.notify(p)
+			}
+		},
+	}
+	fmt.Fprintf(os.Stderr, "Watching notebook %s every %v (Ctrl-C to stop)\n", notebookID, opts.Interval)
+	return w.run(ctx)
+}
+
+// notebookWatcher polls with fetch and emits the differences between
+// consecutive snapshots.
+type notebookWatcher struct {
+	interval time.Duration
+	fetch    func() (*watchSnapshot, error)
+	emit     func(watchEvent)
+	now      func() time.Time
+}
+
+func (w *notebookWatcher) run(ctx context.Context) error {
+	now := w.now
+	if now == nil {
+		now = time.Now
+	}
+	var prev *watchSnapshot
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		cur, err := w.fetch()
+		switch {
+		case err != nil && isAuthenticationError(err):
+			return err
+		case err != nil:
+			// Keep the previous snapshot so nothing is reported twice.
+			fmt.Fprintf(os.Stderr, "nlm: poll failed: %v\n", err)
+		case prev == nil:
+			prev = cur
+			if debug {
+				fmt.Fprintf(os.Stderr, "nlm: %d sources, %d notes, %d artifacts\n", len(cur.Sources), len(cur.Notes), len(cur.Artifacts))
+			}
+		default:
+			t := now()
+			for _, e := range diffWatchSnapshots(prev, cur) {
+				e.Time = t
+				w.emit(e)
+			}
+			prev = cur
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchWatchSnapshot loads the notebook's sources, notes and artifacts.
+func fetchWatchSnapshot(c *api.Client, notebookID string) (*watchSnapshot, error) {
+	s := &watchSnapshot{
+		Sources:   map[string]watchItem{},
+		Notes:     map[string]watchItem{},
+		Artifacts: map[string]watchItem{},
+	}
+	p, err := c.GetProject(notebookID)
+	if err != nil {
+		return nil, fmt.Errorf("get notebook: %w", err)
+	}
+	for _, src := range p.GetSources() {
+		s.Sources[src.GetSourceId().GetSourceId()] = watchItem{
+			Title:  strings.TrimSpace(src.GetTitle()),
+			Kind:   src.GetMetadata().GetSourceType().String(),
+			Status: src.GetMetadata().GetStatus().String(),
+		}
+	}
+	notes, err := c.GetNotes(notebookID)
+	if err != nil {
+		return nil, fmt.Errorf("get notes: %w", err)
+	}
+	for _, n := range notes {
+		sum := sha256.Sum256([]byte(n.GetTitle() + "\x00" + n.GetContentText()))
+		s.Notes[n.GetNoteId()] = watchItem{Title: n.GetTitle(), Hash: hex.EncodeToString(sum[:8])}
+	}
+	artifacts, err := c.ListArtifacts(notebookID)
+	if err != nil {
+		return nil, fmt.Errorf("list artifacts: %w", err)
+	}
+	for _, a := range artifacts {
+		s.Artifacts[a.GetArtifactId()] = watchItem{
+			Kind:   a.GetType().String(),
+			Status: a.GetState().String(),
+		}
+	}
+	return s, nil
+}
+
+// diffWatchSnapshots returns the events that turn prev into cur, grouped by
+// sources, notes and artifacts and ordered by ID within each group.
+func diffWatchSnapshots(prev, cur *watchSnapshot) []watchEvent {
+	var events []watchEvent
+	event := func(typ, id string, it watchItem) watchEvent {
+		return watchEvent{Type: typ, ID: id, Title: it.Title, Kind: it.Kind, Status: it.Status}
+	}
+
+	for _, id := range sortedWatchIDs(prev.Sources, cur.Sources) {
+		old, had := prev.Sources[id]
+		it, has := cur.Sources[id]
+		switch {
+		case !had:
+			events = append(events, event(eventSourceAdded, id, it))
+		case !has:
+			events = append(events, event(eventSourceRemoved, id, old))
+		case old.Status != it.Status:
+			e := event(eventSourceStatus, id, it)
+			e.Previous = old.Status
+			events = append(events, e)
+		}
+	}
+	for _, id := range sortedWatchIDs(prev.Notes, cur.Notes) {
+		old, had := prev.Notes[id]
+		it, has := cur.Notes[id]
+		switch {
+		case !had:
+			events = append(events, event(eventNoteAdded, id, it))
+		case !has:
+			events = append(events, event(eventNoteRemoved, id, old))
+		case old.Hash != it.Hash:
+			events = append(events, event(eventNoteEdited, id, it))
+		}
+	}
+	for _, id := range sortedWatchIDs(prev.Artifacts, cur.Artifacts) {
+		old, had := prev.Artifacts[id]
+		it, has := cur.Artifacts[id]
+		if !has {
+			events = append(events, event(eventArtifactRemoved, id, old))
+			continue
+		}
+		if !had {
+			events = append(events, event(eventArtifactAdded, id, it))
+		}
+		if old.Status == it.Status {
+			continue
+		}
+		var typ string
+		switch it.Status {
+		case "ARTIFACT_STATE_READY":
+			typ = eventArtifactReady
+		case "ARTIFACT_STATE_FAILED":
+			typ = eventArtifactFailed
+		default:
+			continue
+		}
+		e := event(typ, id, it)
+		e.Previous = old.Status
+		events = append(events, e)
+	}
+	return events
+}
+
+func sortedWatchIDs(a, b map[string]watchItem) []string {
+	ids := make([]string, 0, len(a)+len(b))
+	for id := range a {
+		ids = append(ids, id)
+	}
+	for id := range b {
+		if _, ok := a[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// writeWatchEvent prints e as a JSON line or a line of text.
+func writeWatchEvent(w io.Writer, e watchEvent, asJSON bool) {
+	if asJSON {
+		data, _ := json.Marshal(e)
+		fmt.Fprintf(w, "%s\n", data)
+		return
+	}
+	title := e.Title
+	if title == "" {
+		title = strings.TrimPrefix(e.Kind, "ARTIFACT_TYPE_")
+	}
+	var what string
+	switch e.Type {
+	case eventSourceAdded:
+		what = "source added"
+	case eventSourceRemoved:
+		what = "source removed"
+	case eventSourceStatus:
+		what = fmt.Sprintf("source status %s → %s",
+			strings.TrimPrefix(e.Previous, "SOURCE_STATUS_"), strings.TrimPrefix(e.Status, "SOURCE_STATUS_"))
+	case eventNoteAdded:
+		what = "note added"
+	case eventNoteEdited:
+		what = "note edited"
+	case eventNoteRemoved:
+		what = "note removed"
+	case eventArtifactAdded:
+		what = "artifact added"
+	case eventArtifactReady:
+		what = "artifact ready"
+	case eventArtifactFailed:
+		what = "artifact failed"
+	case eventArtifactRemoved:
+		what = "artifact removed"
+	default:
+		what = e.Type
+	}
+	fmt.Fprintf(w, "%s  %-20s %s  %s\n", e.Time.Format("15:04:05"), what, e.ID, title)
+}
+
+// runWatchHook runs command with sh -c, passing the event as JSON on stdin
+// and its fields in NLM_EVENT_* environment variables.
+func runWatchHook(command string, e watchEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = strings.NewReader(string(data) + "\n")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"NLM_EVENT="+e.Type,
+		"NLM_EVENT_ID="+e.ID,
+		"NLM_EVENT_TITLE="+e.Title,
+		"NLM_EVENT_KIND="+e.Kind,
+		"NLM_EVENT_STATUS="+e.Status,
+		"NLM_NOTEBOOK_ID="+e.NotebookID,
+	)
+	return cmd.Run()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffWatchSnapshots(t *testing.T) {
+	prev := &watchSnapshot{
+		Sources: map[string]watchItem{
+			"s1": {Title: "Paper", Status: "SOURCE_STATUS_ENABLED"},
+			"s2": {Title: "Old", Status: "SOURCE_STATUS_ENABLED"},
+		},
+		Notes: map[string]watchItem{"n1": {Title: "Draft", Hash: "a"}},
+		Artifacts: map[string]watchItem{
+			"a1": {Kind: "ARTIFACT_TYPE_AUDIO_OVERVIEW", Status: "ARTIFACT_STATE_CREATING"},
+			"a2": {Kind: "ARTIFACT_TYPE_REPORT", Status: "ARTIFACT_STATE_CREATING"},
+		},
+	}
+	cur := &watchSnapshot{
+		Sources: map[string]watchItem{
+			"s1": {Title: "Paper", Status: "SOURCE_STATUS_ERROR"},
+			"s3": {Title: "New", Status: "SOURCE_STATUS_ENABLED"},
+		},
+		Notes: map[string]watchItem{"n1": {Title: "Draft", Hash: "b"}},
+		Artifacts: map[string]watchItem{
+			"a1": {Kind: "ARTIFACT_TYPE_AUDIO_OVERVIEW", Status: "ARTIFACT_STATE_READY"},
+			"a2": {Kind: "ARTIFACT_TYPE_REPORT", Status: "ARTIFACT_STATE_FAILED"},
+			"a3": {Kind: "ARTIFACT_TYPE_VIDEO_OVERVIEW", Status: "ARTIFACT_STATE_READY"},
+		},
+	}
+	var got []string
+	for _, e := range diffWatchSnapshots(prev, cur) {
+		got = append(got, e.Type+" "+e.ID)
+	}
+	want := []string{
+		"source_status s1",
+		"source_removed s2",
+		"source_added s3",
+		"note_edited n1",
+		"artifact_ready a1",
+		"artifact_failed a2",
+		"artifact_added a3",
+		"artifact_ready a3",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if events := diffWatchSnapshots(cur, cur); len(events) != 0 {
+		t.Errorf("no-change diff = %v", events)
+	}
+}
+
+func TestNotebookWatcher(t *testing.T) {
+	snapshots := []*watchSnapshot{
+		{Sources: map[string]watchItem{}},
+		{Sources: map[string]watchItem{"s1": {Title: "Paper"}}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events []watchEvent
+	polls := 0
+	w := &notebookWatcher{
+		interval: time.Millisecond,
+		fetch: func() (*watchSnapshot, error) {
+			s := snapshots[min(polls, len(snapshots)-1)]
+			if polls++; polls > 3 {
+				cancel()
+			}
+			return s, nil
+		},
+		emit: func(e watchEvent) { events = append(events, e) },
+	}
+	if err := w.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != eventSourceAdded || events[0].Time.IsZero() {
+		t.Errorf("events = %+v, want one source_added", events)
+	}
+}
+
+func TestWriteWatchEvent(t *testing.T) {
+	e := watchEvent{
+		Time:       time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Type:       eventSourceStatus,
+		NotebookID: "nb",
+		ID:         "s1",
+		Title:      "Paper",
+		Status:     "SOURCE_STATUS_ERROR",
+		Previous:   "SOURCE_STATUS_ENABLED",
+	}
+	var buf bytes.Buffer
+	writeWatchEvent(&buf, e, false)
+	if got := buf.String(); !strings.Contains(got, "15:04:05  source status ENABLED → ERROR") || !strings.Contains(got, "s1  Paper") {
+		t.Errorf("text = %q", got)
+	}
+	buf.Reset()
+	writeWatchEvent(&buf, e, true)
+	if got := buf.String(); !strings.HasPrefix(got, `{"time":"2024-01-02T15:04:05Z","type":"source_status","notebook_id":"nb"`) {
+		t.Errorf("json = %q", got)
+	}
+}
+
+func TestRunWatchHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	e := watchEvent{Type: eventArtifactReady, NotebookID: "nb", ID: "a1"}
+	if err := runWatchHook(`echo "$NLM_EVENT $NLM_NOTEBOOK_ID $NLM_EVENT_ID" > `+out+` && cat >> `+out, e); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.HasPrefix(got, "artifact_ready nb a1\n{") {
+		t.Errorf("hook output = %q", got)
+	}
+}
+
+func TestParseWatchArgs(t *testing.T) {
+	opts, nb, err := parseWatchArgs([]string{"nb-1", "-json", "-interval", "5s", "-exec", "notify-send done"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nb != "nb-1" || !opts.JSON || opts.Interval != 5*time.Second || opts.Exec != "notify-send done" {
+		t.Errorf("parseWatchArgs = %+v %q", opts, nb)
+	}
+	for _, args := range [][]string{{}, {"a", "b"}, {"nb", "-interval", "10ms"}} {
+		if _, _, err := parseWatchArgs(args); err == nil {
+			t.Errorf("parseWatchArgs(%q) succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// Webhook events.
+const (
+	webhookCompleted = "generation.completed"
+	webhookFailed    = "generation.failed"
+	webhookPing      = "ping"
+)
+
+// webhookPayload is the JSON body POSTed to the webhook URL.
+type webhookPayload struct {
+	Event      string    `json:"event"`
+	DeliveryID string    `json:"delivery_id"`
+	Time       time.Time `json:"time"`
+	NotebookID string    `json:"notebook_id,omitempty"`
+	JobID      string    `json:"job_id,omitempty"` // artifact or research ID
+	Kind       string    `json:"kind,omitempty"`   // audio_overview, video_overview, deep_research, ...
+	Status     string    `json:"status,omitempty"` // ready or failed
+	Error      string    `json:"error,omitempty"`
+}
+
+// webhookNotifier delivers signed webhook payloads.
+//
+// Each request carries the headers
+//
+//	X-Nlm-Event:     the payload's event
+//	X-Nlm-Delivery:  the payload's delivery_id
+//	X-Nlm-Timestamp: Unix seconds when the request was signed
+//	X-Nlm-Signature: sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// keyed with the webhook secret. Receivers should recompute the signature
+// and reject stale timestamps.
+type webhookNotifier struct {
+	url     string
+	secret  string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+}
+
+// newWebhookNotifier returns a notifier for the configured webhook_url, or
+// nil if none is configured. loadConfig has already checked that a
+// webhook_secret goes with it.
+func newWebhookNotifier() *webhookNotifier {
+	url := settingValue(activeSettings, "webhook_url")
+	if url == "" {
+		return nil
+	}
+	return &webhookNotifier{
+		url:     url,
+		secret:  settingValue(activeSettings, "webhook_secret"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		retries: 3,
+		backoff: time.Second,
+	}
+}
+
+// signWebhook returns the X-Nlm-Signature value for body sent at timestamp.
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// send POSTs p, retrying network errors and 5xx responses with backoff.
+func (n *webhookNotifier) send(ctx context.Context, p webhookPayload) error {
+	if p.DeliveryID == "" {
+		p.DeliveryID = uuid.New().String()
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now().UTC()
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < max(n.retries, 1); attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(n.backoff << (attempt - 1)):
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+		ts := time.Now().Unix()
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "nlm-webhook")
+		req.Header.Set("X-Nlm-Event", p.Event)
+		req.Header.Set("X-Nlm-Delivery", p.DeliveryID)
+		req.Header.Set("X-Nlm-Timestamp", strconv.FormatInt(ts, 10))
+		req.Header.Set("X-Nlm-Signature", signWebhook(n.secret, ts, body))
+		resp, err := n.client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("webhook: %w", err)
+			continue
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			lastErr = fmt.Errorf("webhook: %s", resp.Status)
+		default:
+			return fmt.Errorf("webhook: %s", resp.Status)
+		}
+	}
+	return lastErr
+}
+
+// notify sends p if a webhook is configured. Delivery failures are reported
+// on stderr but do not fail the command.
+func (n *webhookNotifier) notify(p webhookPayload) {
+	if n == nil {
+		return
+	}
+	if err := n.send(context.Background(), p); err != nil {
+		fmt.Fprintf(os.Stderr, "nlm: %v\n", err)
+	} else if debug {
+		fmt.Fprintf(os.Stderr, "nlm: webhook %s delivered for %s\n", p.Event, p.JobID)
+	}
+}
+
+// artifactKind returns the payload kind for an artifact type, such as
+// audio_overview for ARTIFACT_TYPE_AUDIO_OVERVIEW.
+func artifactKind(t pb.ArtifactType) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "ARTIFACT_TYPE_"))
+}
+
+// artifactWebhook returns the payload for an artifact that reached a
+// final state, and whether the state is final.
+func artifactWebhook(notebookID string, a *pb.Artifact) (webhookPayload, bool) {
+	p := webhookPayload{
+		NotebookID: notebookID,
+		JobID:      a.GetArtifactId(),
+		Kind:       artifactKind(a.GetType()),
+	}
+	switch a.GetState() {
+	case pb.ArtifactState_ARTIFACT_STATE_READY:
+		p.Event, p.Status = webhookCompleted, "ready"
+	case pb.ArtifactState_ARTIFACT_STATE_FAILED:
+		p.Event, p.Status = webhookFailed, "failed"
+	default:
+		return p, false
+	}
+	return p, true
+}
+
+// watchEventWebhook returns the payload for an artifact_ready or
+// artifact_failed watch event.
+func watchEventWebhook(e watchEvent) (webhookPayload, bool) {
+	p := webhookPayload{
+		Time:       e.Time,
+		NotebookID: e.NotebookID,
+		JobID:      e.ID,
+		Kind:       strings.ToLower(strings.TrimPrefix(e.Kind, "ARTIFACT_TYPE_")),
+	}
+	switch e.Type {
+	case eventArtifactReady:
+		p.Event, p.Status = webhookCompleted, "ready"
+	case eventArtifactFailed:
+		p.Event, p.Status = webhookFailed, "failed"
+	default:
+		return p, false
+	}
+	return p, true
+}
+
+// waitForGeneration waits for artifacts in the notebook to finish, then
+// reports each one and sends its webhook. With no IDs it waits for every
+// artifact that is still being created.
+func waitForGeneration(c *api.Client, notebookID string, ids []string) error {
+	const (
+		interval = 15 * time.Second
+		timeout  = 30 * time.Minute
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	hook := newWebhookNotifier()
+	pending := map[string]bool{}
+	for _, id := range ids {
+		pending[id] = true
+	}
+	failed := 0
+	first, idle := true, false
+	list := func() ([]*pb.Artifact, error) { return c.ListArtifacts(notebookID) }
+	err := api.WaitArtifacts(ctx, interval, list, func(artifacts []*pb.Artifact) (bool, error) {
+		defer func() { first = false }()
+		seen := map[string]bool{}
+		for _, a := range artifacts {
+			id := a.GetArtifactId()
+			seen[id] = true
+			if first && len(ids) == 0 && a.GetState() == pb.ArtifactState_ARTIFACT_STATE_CREATING {
+				pending[id] = true
+			}
+			if !pending[id] {
+				continue
+			}
+			p, done := artifactWebhook(notebookID, a)
+			if !done {
+				continue
+			}
+			delete(pending, id)
+			if p.Status == "failed" {
+				failed++
+				fmt.Printf("❌ %s %s failed\n", p.Kind, id)
+			} else {
+				fmt.Printf("✅ %s %s ready\n", p.Kind, id)
+			}
+			hook.notify(p)
+		}
+		for id := range pending {
+			if !seen[id] {
+				return true, fmt.Errorf("artifact %s not found in notebook %s", id, notebookID)
+			}
+		}
+		if first && len(pending) == 0 && len(ids) == 0 {
+			idle = true
+			return true, nil
+		}
+		if first && len(pending) > 0 {
+			fmt.Fprintf(os.Stderr, "Waiting for %d artifact(s)...\n", len(pending))
+		}
+		return len(pending) == 0, nil
+	})
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%d artifact(s) still in progress after %v", len(pending), timeout)
+	case err != nil:
+		return err
+	case idle:
+		fmt.Fprintln(os.Stderr, "No artifacts in progress.")
+		return nil
+	case failed > 0:
+		return fmt.Errorf("%d artifact(s) failed", failed)
+	}
+	return nil
+}
+
+// testWebhook sends a ping to the configured webhook.
+func testWebhook() error {
+	hook := newWebhookNotifier()
+	if hook == nil {
+		return fmt.Errorf("no webhook configured (set webhook_url in the config file or NLM_WEBHOOK_URL)")
+	}
+	p := webhookPayload{Event: webhookPing}
+	if err := hook.send(context.Background(), p); err != nil {
+		return err
+	}
+	fmt.Printf("Delivered ping to %s\n", hook.url)
+	return nil
+}
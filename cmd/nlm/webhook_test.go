@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+)
+
+// verifyWebhook checks a request the way a receiver would.
+func verifyWebhook(t *testing.T, secret string, r *http.Request, body []byte) {
+	t.Helper()
+	ts, err := strconv.ParseInt(r.Header.Get("X-Nlm-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatalf("bad timestamp header: %v", err)
+	}
+	if d := time.Since(time.Unix(ts, 0)); d < -time.Minute || d > time.Minute {
+		t.Errorf("timestamp %d is %v old", ts, d)
+	}
+	want := signWebhook(secret, ts, body)
+	if got := r.Header.Get("X-Nlm-Signature"); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	const secret = "s3cret"
+	var got webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyWebhook(t, secret, r, body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("bad body %q: %v", body, err)
+		}
+		if r.Header.Get("X-Nlm-Event") != got.Event || r.Header.Get("X-Nlm-Delivery") != got.DeliveryID {
+			t.Errorf("headers %v do not match payload %+v", r.Header, got)
+		}
+	}))
+	defer srv.Close()
+
+	n := &webhookNotifier{url: srv.URL, secret: secret, client: srv.Client(), retries: 1}
+	a := &pb.Artifact{
+		ArtifactId: "a1",
+		Type:       pb.ArtifactType_ARTIFACT_TYPE_AUDIO_OVERVIEW,
+		State:      pb.ArtifactState_ARTIFACT_STATE_READY,
+	}
+	p, done := artifactWebhook("nb", a)
+	if !done {
+		t.Fatal("READY artifact not reported as done")
+	}
+	if err := n.send(t.Context(), p); err != nil {
+		t.Fatal(err)
+	}
+	if got.Event != webhookCompleted || got.Kind != "audio_overview" || got.Status != "ready" || got.JobID != "a1" || got.NotebookID != "nb" || got.DeliveryID == "" {
+		t.Errorf("payload = %+v", got)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	for _, tt := range []struct {
+		status    int
+		wantCalls int
+	}{
+		{http.StatusServiceUnavailable, 3},
+		{http.StatusBadRequest, 1},
+	} {
+		calls := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(tt.status)
+		}))
+		n := &webhookNotifier{url: srv.URL, client: srv.Client(), retries: 3, backoff: time.Millisecond}
+		err := n.send(t.Context(), webhookPayload{Event: webhookPing})
+		srv.Close()
+		if err == nil || calls != tt.wantCalls {
+			t.Errorf("status %d: err = %v after %d calls, want error after %d", tt.status, err, calls, tt.wantCalls)
+		}
+	}
+}
+
+func TestWatchEventWebhook(t *testing.T) {
+	p, ok := watchEventWebhook(watchEvent{Type: eventArtifactFailed, ID: "a2", NotebookID: "nb", Kind: "ARTIFACT_TYPE_VIDEO_OVERVIEW"})
+	if !ok || p.Event != webhookFailed || p.Kind != "video_overview" || p.Status != "failed" {
+		t.Errorf("watchEventWebhook = %+v, %v", p, ok)
+	}
+	if _, ok := watchEventWebhook(watchEvent{Type: eventNoteEdited}); ok {
+		t.Error("note_edited produced a webhook")
+	}
+	if _, done := artifactWebhook("nb", &pb.Artifact{State: pb.ArtifactState_ARTIFACT_STATE_CREATING}); done {
+		t.Error("CREATING artifact reported as done")
+	}
+}
@@ -0,0 +1,135 @@
+// Command harhttprr converts Chrome DevTools HAR captures to httprr
+// recordings and back.
+//
+// Usage:
+//
+//	harhttprr import [-host h1,h2] [-rpc id1,id2] [-keep-browser-headers] <in.har> <out.httprr>
+//	harhttprr export <in.httprr> <out.har>
+//
+// Imported recordings are scrubbed of cookies, authorization headers and
+// the at= XSRF token, so browser captures can be committed as test fixtures.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tmc/nlm/internal/httprr"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "harhttprr: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		usage(stderr)
+		return errors.New("missing subcommand")
+	}
+	switch args[0] {
+	case "import":
+		return runImport(args[1:], stdout, stderr)
+	case "export":
+		return runExport(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		usage(stderr)
+		return nil
+	default:
+		usage(stderr)
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: harhttprr import [flags] <in.har> <out.httprr>")
+	fmt.Fprintln(w, "       harhttprr export <in.httprr> <out.har>")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Convert Chrome DevTools HAR captures to scrubbed httprr recordings and back.")
+}
+
+func runImport(args []string, stdout, stderr io.Writer) error {
+	var (
+		hosts    string
+		rpcIDs   string
+		keepHdrs bool
+	)
+	flags := flag.NewFlagSet("harhttprr import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&hosts, "host", "notebooklm.google.com", "comma-separated hosts to import")
+	flags.StringVar(&rpcIDs, "rpc", "", "comma-separated batchexecute RPC IDs to import (default all)")
+	flags.BoolVar(&keepHdrs, "keep-browser-headers", false, "keep browser-only headers such as User-Agent and Sec-*")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("import requires <in.har> <out.httprr>")
+	}
+	in, out := flags.Arg(0), flags.Arg(1)
+
+	src, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	n, err := httprr.ImportHAR(src, dst, httprr.HARImportOptions{
+		Hosts:              splitList(hosts),
+		RPCIDs:             splitList(rpcIDs),
+		KeepBrowserHeaders: keepHdrs,
+	})
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("import %s: %w", in, err)
+	}
+	fmt.Fprintf(stdout, "Imported %d entries %s -> %s\n", n, in, out)
+	return nil
+}
+
+func runExport(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("harhttprr export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("export requires <in.httprr> <out.har>")
+	}
+	in, out := flags.Arg(0), flags.Arg(1)
+
+	dst, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	err = httprr.ExportHAR(in, dst)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("export %s: %w", in, err)
+	}
+	fmt.Fprintf(stdout, "Exported %s -> %s\n", in, out)
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,199 @@
+// Command rpccoverage reports which NotebookLM RPCs have recorded fixtures.
+//
+// It reads the rpc_id option of every method in the notebooklm.v1alpha1
+// protos, scans httprr recordings and txtar exports for the RPC IDs they
+// exercise, and scans the mock response generator for RPC IDs that are
+// only faked. Each method is reported as "recorded", "mock" or "none".
+//
+// Usage:
+//
+//	go run ./internal/cmd/rpccoverage [-mocks file] [-json] [-missing] [dir ...]
+//
+// With no directories, the current directory is scanned.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/httprr"
+)
+
+// Coverage status values.
+const (
+	statusRecorded = "recorded"
+	statusMock     = "mock"
+	statusNone     = "none"
+)
+
+// method is the coverage of one proto method.
+type method struct {
+	Service    string   `json:"service"`
+	Method     string   `json:"method"`
+	RPCID      string   `json:"rpc_id"`
+	Status     string   `json:"status"`
+	Recordings []string `json:"recordings,omitempty"`
+}
+
+type options struct {
+	mocks   string
+	json    bool
+	missing bool
+	dirs    []string
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "rpccoverage: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	var opts options
+	flags := flag.NewFlagSet("rpccoverage", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.mocks, "mocks", "internal/notebooklm/api/testdata/mock_responses.go", "Go source of the mock response generator")
+	flags.BoolVar(&opts.json, "json", false, "write the report as JSON")
+	flags.BoolVar(&opts.missing, "missing", false, "only report methods without a recording")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	opts.dirs = flags.Args()
+	if len(opts.dirs) == 0 {
+		opts.dirs = []string{"."}
+	}
+
+	methods, err := report(opts)
+	if err != nil {
+		return err
+	}
+	if opts.missing {
+		methods = slices.DeleteFunc(methods, func(m method) bool { return m.Status == statusRecorded })
+	}
+	if opts.json {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(methods)
+	}
+	return writeTable(stdout, methods)
+}
+
+// report cross-references proto methods with recordings and mocks.
+func report(opts options) ([]method, error) {
+	recorded, err := httprr.ScanRPCIDs(opts.dirs...)
+	if err != nil {
+		return nil, fmt.Errorf("scan recordings: %w", err)
+	}
+	mocked := map[string]bool{}
+	if opts.mocks != "" {
+		mocked, err = mockRPCIDs(opts.mocks)
+		if err != nil {
+			return nil, fmt.Errorf("scan mocks: %w", err)
+		}
+	}
+
+	methods := protoMethods()
+	for i, m := range methods {
+		switch {
+		case len(recorded[m.RPCID]) > 0:
+			methods[i].Status = statusRecorded
+			methods[i].Recordings = recorded[m.RPCID]
+		case mocked[m.RPCID]:
+			methods[i].Status = statusMock
+		default:
+			methods[i].Status = statusNone
+		}
+	}
+	return methods, nil
+}
+
+// protoMethods returns every method in the notebooklm.v1alpha1 package
+// that carries an rpc_id option, in declaration order.
+func protoMethods() []method {
+	// Reference the generated package so its descriptors are registered.
+	_ = pb.File_notebooklm_v1alpha1_orchestration_proto
+
+	var methods []method
+	protoregistry.GlobalFiles.RangeFilesByPackage("notebooklm.v1alpha1", func(fd protoreflect.FileDescriptor) bool {
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			sd := services.Get(i)
+			for j := 0; j < sd.Methods().Len(); j++ {
+				md := sd.Methods().Get(j)
+				opts, ok := md.Options().(*descriptorpb.MethodOptions)
+				if !ok || opts == nil {
+					continue
+				}
+				id, _ := proto.GetExtension(opts, pb.E_RpcId).(string)
+				if id == "" {
+					continue
+				}
+				methods = append(methods, method{
+					Service: string(sd.Name()),
+					Method:  string(md.Name()),
+					RPCID:   id,
+				})
+			}
+		}
+		return true
+	})
+	// Files are visited in no particular order; keep declaration order within a service.
+	slices.SortStableFunc(methods, func(a, b method) int {
+		return strings.Compare(a.Service, b.Service)
+	})
+	return methods
+}
+
+// mockRPCPattern matches the RPC ID at the start of a batchexecute envelope,
+// as written in mock request and response literals: [["wXbhsf",...
+var mockRPCPattern = regexp.MustCompile(`\[\["([a-zA-Z0-9]{5,6})",`)
+
+// mockRPCIDs returns the RPC IDs referenced by the mock generator source.
+func mockRPCIDs(file string) (map[string]bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool)
+	for _, m := range mockRPCPattern.FindAllSubmatch(data, -1) {
+		ids[string(m[1])] = true
+	}
+	return ids, nil
+}
+
+func writeTable(w io.Writer, methods []method) error {
+	counts := map[string]int{}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tMETHOD\tRPC ID\tSTATUS\tRECORDINGS")
+	for _, m := range methods {
+		counts[m.Status]++
+		recs := "-"
+		if len(m.Recordings) > 0 {
+			recs = m.Recordings[0]
+			if len(m.Recordings) > 1 {
+				recs += fmt.Sprintf(" (+%d)", len(m.Recordings)-1)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.Service, m.Method, m.RPCID, m.Status, recs)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d methods: %d recorded, %d mock only, %d none\n",
+		len(methods), counts[statusRecorded], counts[statusMock], counts[statusNone])
+	return err
+}
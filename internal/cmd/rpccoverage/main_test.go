@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunJSON(t *testing.T) {
+	dir := t.TempDir()
+
+	req := "POST https://notebooklm.google.com/_/LabsTailwindUi/data/batchexecute?rpcids=rLM1Ne HTTP/1.1\r\nHost: notebooklm.google.com\r\nContent-Length: 0\r\n\r\n"
+	resp := "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"
+	trace := fmt.Sprintf("httprr trace v1\n%d %d\n%s%s", len(req), len(resp), req, resp)
+	if err := os.WriteFile(filepath.Join(dir, "get_project.httprr"), []byte(trace), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mocks := filepath.Join(dir, "mocks.go")
+	if err := os.WriteFile(mocks, []byte("package testdata\n\nconst r = `[[\"wXbhsf\",\"[]\"]]`\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"-json", "-mocks", mocks, dir}, &stdout, &stderr); err != nil {
+		t.Fatalf("run: %v\n%s", err, stderr.String())
+	}
+	var methods []method
+	if err := json.Unmarshal(stdout.Bytes(), &methods); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+
+	status := map[string]string{}
+	for _, m := range methods {
+		status[m.Method] = m.Status
+	}
+	want := map[string]string{
+		"GetProject":                 statusRecorded,
+		"ListRecentlyViewedProjects": statusMock,
+		"DeleteProjects":             statusNone,
+		"ShareProject":               statusNone,
+	}
+	for name, st := range want {
+		if status[name] != st {
+			t.Errorf("%s status = %q, want %q", name, status[name], st)
+		}
+	}
+}
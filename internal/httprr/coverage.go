@@ -0,0 +1,117 @@
+package httprr
+
+import (
+	"bufio"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+// ScanRPCIDs walks the given files and directories for httprr recordings
+// (.httprr, .httprr.gz) and txtar exports (.txtar) and returns, for each
+// batchexecute RPC ID found, the sorted list of files containing a request
+// for it.
+//
+// Only well-formed HTTP requests are considered. Placeholder traces whose
+// requests cannot be parsed, such as those written by the mock generator
+// in internal/notebooklm/api/testdata, are skipped because they do not
+// exercise the real wire format.
+func ScanRPCIDs(paths ...string) (map[string][]string, error) {
+	found := make(map[string][]string)
+	add := func(file string, ids []string) {
+		for _, id := range ids {
+			if !slices.Contains(found[id], file) {
+				found[id] = append(found[id], file)
+			}
+		}
+	}
+
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch {
+			case strings.HasSuffix(path, ".httprr"), strings.HasSuffix(path, ".httprr.gz"):
+				ids, err := traceRPCIDs(path)
+				if err != nil {
+					return err
+				}
+				add(path, ids)
+			case strings.HasSuffix(path, ".txtar"):
+				ids, err := txtarRPCIDs(path)
+				if err != nil {
+					return err
+				}
+				add(path, ids)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for id := range found {
+		slices.Sort(found[id])
+	}
+	return found, nil
+}
+
+// traceRPCIDs returns the RPC IDs requested in the httprr trace file.
+func traceRPCIDs(file string) ([]string, error) {
+	pairs, err := readTrace(file)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, p := range pairs {
+		ids = append(ids, requestRPCIDs(p[0])...)
+	}
+	return ids, nil
+}
+
+// txtarRPCIDs returns the RPC IDs requested in a txtar export written by
+// ExportToTxtar.
+func txtarRPCIDs(file string) ([]string, error) {
+	archive, err := txtar.ParseFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, f := range archive.Files {
+		if !strings.HasSuffix(f.Name, "_request.http") {
+			continue
+		}
+		ids = append(ids, requestRPCIDs(string(f.Data))...)
+	}
+	return ids, nil
+}
+
+// requestRPCIDs parses a serialized HTTP request and returns its RPC IDs.
+// Batched requests list several comma-separated IDs in the rpcids parameter.
+func requestRPCIDs(raw string) []string {
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		return nil
+	}
+	defer req.Body.Close()
+
+	var ids []string
+	for _, id := range strings.Split(extractRPCID(req), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,57 @@
+package httprr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+func TestScanRPCIDs(t *testing.T) {
+	dir := t.TempDir()
+
+	// A real recording with a batched request.
+	req := "POST https://notebooklm.google.com/_/LabsTailwindUi/data/batchexecute?rpcids=rLM1Ne%2CcFji9 HTTP/1.1\r\nHost: notebooklm.google.com\r\nContent-Length: 0\r\n\r\n"
+	resp := "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"
+	trace := fmt.Sprintf("httprr trace v1\n%d %d\n%s%s", len(req), len(resp), req, resp)
+	if err := os.WriteFile(filepath.Join(dir, "real.httprr"), []byte(trace), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A placeholder trace as written by the mock generator.
+	mockReq := `POST /_/NotebookLmUi/data/batchexecute HTTP/1.1\r\nHost: notebooklm.google.com\r\n\r\n[["wXbhsf","[]"]]`
+	mockResp := `HTTP/1.1 200 OK\r\n\r\n[]`
+	mock := fmt.Sprintf("httprr trace v1\n%d %d\n%s%s", len(mockReq), len(mockResp), mockReq, mockResp)
+	if err := os.WriteFile(filepath.Join(dir, "mock.httprr"), []byte(mock), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A txtar export.
+	body := `f.req=[[["CCqFvf","[\"t\"]",null,"generic"]]]&at=`
+	archive := &txtar.Archive{Files: []txtar.File{{
+		Name: "01_CCqFvf_request.http",
+		Data: []byte(fmt.Sprintf("POST /_/LabsTailwindUi/data/batchexecute HTTP/1.1\r\nHost: notebooklm.google.com\r\nContent-Length: %d\r\n\r\n%s", len(body), body)),
+	}}}
+	if err := os.WriteFile(filepath.Join(dir, "export.txtar"), txtar.Format(archive), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ScanRPCIDs(dir)
+	if err != nil {
+		t.Fatalf("ScanRPCIDs: %v", err)
+	}
+	for _, id := range []string{"rLM1Ne", "cFji9", "CCqFvf"} {
+		if len(got[id]) != 1 {
+			t.Errorf("ScanRPCIDs()[%q] = %v, want one file", id, got[id])
+		}
+	}
+	if !slices.Equal(got["CCqFvf"], []string{filepath.Join(dir, "export.txtar")}) {
+		t.Errorf("CCqFvf found in %v, want export.txtar", got["CCqFvf"])
+	}
+	if _, ok := got["wXbhsf"]; ok {
+		t.Errorf("ScanRPCIDs counted placeholder trace: %v", got["wXbhsf"])
+	}
+}
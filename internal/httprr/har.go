@@ -0,0 +1,442 @@
+package httprr
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HAR is the subset of the HTTP Archive 1.2 format written by Chrome DevTools
+// that is needed to convert browser captures to and from httprr traces.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the top-level log object of a HAR file.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages,omitempty"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator identifies the application that produced a HAR file.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage is a page record. It is preserved only for completeness.
+type HARPage struct {
+	StartedDateTime string `json:"startedDateTime"`
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+}
+
+// HAREntry is a single request/response exchange.
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+}
+
+// HARRequest is the request half of a HAR entry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	Cookies     []HARNameValue `json:"cookies"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse is the response half of a HAR entry.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue is a header, query parameter or cookie.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is a request body.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is a response body. Chrome base64-encodes binary bodies
+// and sets Encoding to "base64".
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings holds the per-phase timings of an entry. httprr does not
+// record timings, so exported entries report zero durations.
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARImportOptions controls which HAR entries are converted by ImportHAR.
+type HARImportOptions struct {
+	// Hosts limits the import to requests for these hosts.
+	// If empty, notebooklm.google.com is used.
+	Hosts []string
+	// RPCIDs, if set, limits the import to batchexecute calls for these RPC IDs.
+	RPCIDs []string
+	// KeepBrowserHeaders keeps browser-only headers such as Sec-* and
+	// User-Agent instead of dropping them.
+	KeepBrowserHeaders bool
+}
+
+// browserHeaders are dropped from imported requests unless
+// HARImportOptions.KeepBrowserHeaders is set. They vary per browser
+// session and are never sent by the nlm client.
+var browserHeaders = []string{
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Cache-Control",
+	"Origin",
+	"Pragma",
+	"Priority",
+	"Referer",
+	"User-Agent",
+	"X-Browser-Channel",
+	"X-Browser-Copyright",
+	"X-Browser-Validation",
+	"X-Browser-Year",
+	"X-Same-Domain",
+}
+
+// hopHeaders describe the transfer of a response body rather than its
+// content. HAR bodies are already decoded, so these are dropped and
+// Content-Length is recomputed.
+var hopHeaders = []string{
+	"Alt-Svc",
+	"Content-Encoding",
+	"Content-Length",
+	"Transfer-Encoding",
+}
+
+// ImportHAR converts the entries of a HAR file read from r into an httprr
+// trace written to w. Requests pass through the credential and request ID
+// scrubbers used by OpenForNLMTest, and responses through the default
+// Set-Cookie scrubber, so the result is safe to commit as a test fixture.
+// Unlike OpenForNLMTest, response bodies are kept as captured: project
+// lists are not truncated and timestamps and IDs are not normalized. It
+// returns the number of entries written.
+func ImportHAR(r io.Reader, w io.Writer, opts HARImportOptions) (int, error) {
+	var har HAR
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return 0, fmt.Errorf("decode har: %w", err)
+	}
+
+	hosts := opts.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{"notebooklm.google.com"}
+	}
+
+	rr := &RecordReplay{}
+	rr.ScrubReq(defaultRequestScrubbers()...)
+	rr.ScrubReq(scrubNLMCredentials, scrubNLMRequestID, scrubNLMAuthTokenFromBody)
+	rr.ScrubResp(defaultResponseScrubbers()...)
+
+	if _, err := fmt.Fprintf(w, "httprr trace v1\n"); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for i, e := range har.Log.Entries {
+		req, err := harRequest(e.Request, opts.KeepBrowserHeaders)
+		if err != nil {
+			return n, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if !containsFold(hosts, req.URL.Hostname()) {
+			continue
+		}
+		if len(opts.RPCIDs) > 0 && !slices.Contains(opts.RPCIDs, extractRPCID(req)) {
+			continue
+		}
+		resp, err := harResponse(e.Response, req)
+		if err != nil {
+			return n, fmt.Errorf("entry %d: %w", i, err)
+		}
+
+		reqLog, err := rr.reqWire(req)
+		if err != nil {
+			return n, fmt.Errorf("entry %d: %w", i, err)
+		}
+		respLog, err := rr.respWire(resp)
+		if err != nil {
+			return n, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if _, err := fmt.Fprintf(w, "%d %d\n%s%s", len(reqLog), len(respLog), reqLog, respLog); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// harRequest builds an http.Request from a HAR request.
+func harRequest(hr HARRequest, keepBrowserHeaders bool) (*http.Request, error) {
+	var body io.Reader
+	if hr.PostData != nil {
+		body = strings.NewReader(hr.PostData.Text)
+	}
+	req, err := http.NewRequest(hr.Method, hr.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	for _, h := range hr.Headers {
+		// HTTP/2 pseudo-headers such as :authority are not valid HTTP/1.1 headers.
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		req.Header.Add(h.Name, h.Value)
+	}
+	req.Header.Del("Content-Length")
+	req.Header.Del("Host")
+	if !keepBrowserHeaders {
+		for _, h := range browserHeaders {
+			req.Header.Del(h)
+		}
+		for name := range req.Header {
+			if strings.HasPrefix(name, "Sec-") {
+				req.Header.Del(name)
+			}
+		}
+	}
+	return req, nil
+}
+
+// harResponse builds an http.Response from a HAR response.
+func harResponse(hr HARResponse, req *http.Request) (*http.Response, error) {
+	body := []byte(hr.Content.Text)
+	if hr.Content.Encoding == "base64" {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(hr.Content.Text)
+		if err != nil {
+			return nil, fmt.Errorf("decode response body: %w", err)
+		}
+	}
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", hr.Status, hr.StatusText),
+		StatusCode:    hr.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	for _, h := range hr.Headers {
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		resp.Header.Add(h.Name, h.Value)
+	}
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	return resp, nil
+}
+
+// ExportHAR converts the httprr trace in file to a HAR document written to w.
+// Gzip-compressed traces (.gz) are supported.
+func ExportHAR(file string, w io.Writer) error {
+	pairs, err := readTrace(file)
+	if err != nil {
+		return err
+	}
+
+	har := HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "github.com/tmc/nlm/internal/httprr", Version: "1"},
+		Entries: []HAREntry{},
+	}}
+	started := time.Unix(0, 0).UTC().Format(time.RFC3339Nano)
+	for i, p := range pairs {
+		req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(p[0])))
+		if err != nil {
+			return fmt.Errorf("entry %d: read request: %w", i, err)
+		}
+		reqBody, err := io.ReadAll(req.Body)
+		if err != nil {
+			return fmt.Errorf("entry %d: read request body: %w", i, err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(p[1])), req)
+		if err != nil {
+			return fmt.Errorf("entry %d: read response: %w", i, err)
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("entry %d: read response body: %w", i, err)
+		}
+
+		u := req.URL
+		if !u.IsAbs() {
+			u = &url.URL{Scheme: "https", Host: req.Host, Path: u.Path, RawQuery: u.RawQuery}
+		}
+		entry := HAREntry{
+			StartedDateTime: started,
+			Request: HARRequest{
+				Method:      req.Method,
+				URL:         u.String(),
+				HTTPVersion: req.Proto,
+				Headers:     harHeaders(req.Header),
+				QueryString: harQuery(u.Query()),
+				Cookies:     []HARNameValue{},
+				HeadersSize: -1,
+				BodySize:    len(reqBody),
+			},
+			Response: HARResponse{
+				Status:      resp.StatusCode,
+				StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+				HTTPVersion: resp.Proto,
+				Headers:     harHeaders(resp.Header),
+				Cookies:     []HARNameValue{},
+				Content: HARContent{
+					Size:     len(respBody),
+					MimeType: resp.Header.Get("Content-Type"),
+					Text:     string(respBody),
+				},
+				HeadersSize: -1,
+				BodySize:    len(respBody),
+			},
+		}
+		if len(reqBody) > 0 {
+			entry.Request.PostData = &HARPostData{
+				MimeType: req.Header.Get("Content-Type"),
+				Text:     string(reqBody),
+			}
+		}
+		har.Log.Entries = append(har.Log.Entries, entry)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(har)
+}
+
+// harHeaders converts h to sorted HAR name/value pairs.
+func harHeaders(h http.Header) []HARNameValue {
+	out := []HARNameValue{}
+	for _, name := range sortedKeys(h) {
+		for _, v := range h[name] {
+			out = append(out, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+// harQuery converts q to sorted HAR name/value pairs.
+func harQuery(q url.Values) []HARNameValue {
+	out := []HARNameValue{}
+	for _, name := range sortedKeys(q) {
+		for _, v := range q[name] {
+			out = append(out, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+// readTrace reads the (request, response) pairs of an httprr trace file,
+// in recorded order.
+func readTrace(file string) ([][2]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	bdata, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data := string(bdata)
+	line, data, ok := strings.Cut(data, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	if !ok || line != "httprr trace v1" {
+		return nil, fmt.Errorf("read %s: not an httprr trace", file)
+	}
+
+	var pairs [][2]string
+	for data != "" {
+		line, data, ok = strings.Cut(data, "\n")
+		line = strings.TrimSuffix(line, "\r")
+		f1, f2, _ := strings.Cut(line, " ")
+		n1, err1 := strconv.Atoi(f1)
+		n2, err2 := strconv.Atoi(f2)
+		if !ok || err1 != nil || err2 != nil || n1 > len(data) || n2 > len(data[n1:]) {
+			return nil, fmt.Errorf("read %s: corrupt httprr trace", file)
+		}
+		pairs = append(pairs, [2]string{data[:n1], data[n1 : n1+n2]})
+		data = data[n1+n2:]
+	}
+	return pairs, nil
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[M ~map[string][]string](m M) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,170 @@
+package httprr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testHAR = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "WebInspector", "version": "537.36"},
+    "entries": [
+      {
+        "startedDateTime": "2025-09-14T01:02:03.000Z",
+        "request": {
+          "method": "POST",
+          "url": "https://notebooklm.google.com/_/LabsTailwindUi/data/batchexecute?rpcids=wXbhsf&f.sid=123&_reqid=48151",
+          "httpVersion": "http/2.0",
+          "headers": [
+            {"name": ":authority", "value": "notebooklm.google.com"},
+            {"name": "content-type", "value": "application/x-www-form-urlencoded;charset=UTF-8"},
+            {"name": "cookie", "value": "SID=secret"},
+            {"name": "user-agent", "value": "Mozilla/5.0"},
+            {"name": "sec-fetch-mode", "value": "cors"}
+          ],
+          "postData": {
+            "mimeType": "application/x-www-form-urlencoded;charset=UTF-8",
+            "text": "f.req=[[[\"wXbhsf\",\"[null,1]\",null,\"generic\"]]]&at=AJpMio2G6FWsQX6b:1757812453964&"
+          }
+        },
+        "response": {
+          "status": 200,
+          "statusText": "",
+          "httpVersion": "http/2.0",
+          "headers": [
+            {"name": "content-type", "value": "application/json; charset=utf-8"},
+            {"name": "content-encoding", "value": "gzip"},
+            {"name": "set-cookie", "value": "NID=secret"}
+          ],
+          "content": {"size": 28, "mimeType": "application/json", "text": "BODY", "encoding": "base64"}
+        }
+      },
+      {
+        "startedDateTime": "2025-09-14T01:02:04.000Z",
+        "request": {
+          "method": "GET",
+          "url": "https://fonts.gstatic.com/s/roboto.woff2",
+          "httpVersion": "http/2.0",
+          "headers": []
+        },
+        "response": {
+          "status": 200,
+          "statusText": "",
+          "httpVersion": "http/2.0",
+          "headers": [],
+          "content": {"size": 0, "mimeType": "font/woff2"}
+        }
+      }
+    ]
+  }
+}`
+
+const testHARBody = ")]}'\n[[\"wrb.fr\",\"wXbhsf\",\"[]\"]]"
+
+func TestImportHAR(t *testing.T) {
+	har := strings.Replace(testHAR, "BODY", base64.StdEncoding.EncodeToString([]byte(testHARBody)), 1)
+
+	var out bytes.Buffer
+	n, err := ImportHAR(strings.NewReader(har), &out, HARImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportHAR: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("ImportHAR imported %d entries, want 1", n)
+	}
+
+	got := out.String()
+	if !strings.HasPrefix(got, "httprr trace v1\n") {
+		t.Fatalf("missing trace header:\n%s", got)
+	}
+	for _, secret := range []string{"SID=secret", "NID=secret", "AJpMio2G6FWsQX6b", "Mozilla", "Sec-Fetch-Mode", ":authority", "48151"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("imported trace contains %q:\n%s", secret, got)
+		}
+	}
+	if strings.Contains(got, "Content-Encoding") {
+		t.Errorf("imported trace kept Content-Encoding for a decoded body:\n%s", got)
+	}
+	if !strings.Contains(got, testHARBody) {
+		t.Errorf("imported trace missing decoded response body:\n%s", got)
+	}
+
+	// The imported trace must be loadable by the replayer.
+	file := filepath.Join(t.TempDir(), "imported.httprr")
+	if err := os.WriteFile(file, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := open(file, nil); err != nil {
+		t.Fatalf("open imported trace: %v", err)
+	}
+}
+
+func TestImportHARFilterRPCIDs(t *testing.T) {
+	har := strings.Replace(testHAR, "BODY", base64.StdEncoding.EncodeToString([]byte(testHARBody)), 1)
+
+	var out bytes.Buffer
+	n, err := ImportHAR(strings.NewReader(har), &out, HARImportOptions{RPCIDs: []string{"CCqFvf"}})
+	if err != nil {
+		t.Fatalf("ImportHAR: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("ImportHAR imported %d entries, want 0", n)
+	}
+}
+
+func TestExportHARRoundTrip(t *testing.T) {
+	har := strings.Replace(testHAR, "BODY", base64.StdEncoding.EncodeToString([]byte(testHARBody)), 1)
+
+	var trace bytes.Buffer
+	if _, err := ImportHAR(strings.NewReader(har), &trace, HARImportOptions{}); err != nil {
+		t.Fatalf("ImportHAR: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "trace.httprr")
+	if err := os.WriteFile(file, trace.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var exported bytes.Buffer
+	if err := ExportHAR(file, &exported); err != nil {
+		t.Fatalf("ExportHAR: %v", err)
+	}
+
+	var got HAR
+	if err := json.Unmarshal(exported.Bytes(), &got); err != nil {
+		t.Fatalf("exported HAR is not valid JSON: %v", err)
+	}
+	if len(got.Log.Entries) != 1 {
+		t.Fatalf("exported %d entries, want 1", len(got.Log.Entries))
+	}
+	e := got.Log.Entries[0]
+	if e.Request.Method != "POST" {
+		t.Errorf("request method = %q, want POST", e.Request.Method)
+	}
+	if !strings.HasPrefix(e.Request.URL, "https://notebooklm.google.com/_/LabsTailwindUi/data/batchexecute?") {
+		t.Errorf("request URL = %q", e.Request.URL)
+	}
+	if e.Request.PostData == nil || !strings.Contains(e.Request.PostData.Text, `"wXbhsf"`) {
+		t.Errorf("request post data = %+v", e.Request.PostData)
+	}
+	if e.Response.Status != 200 {
+		t.Errorf("response status = %d, want 200", e.Response.Status)
+	}
+	if e.Response.Content.Text != testHARBody {
+		t.Errorf("response body = %q, want %q", e.Response.Content.Text, testHARBody)
+	}
+
+	// Re-importing the export yields the same trace.
+	var again bytes.Buffer
+	if _, err := ImportHAR(&exported, &again, HARImportOptions{}); err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	if again.String() != trace.String() {
+		t.Errorf("round trip mismatch:\n--- first ---\n%s\n--- second ---\n%s", trace.String(), again.String())
+	}
+}
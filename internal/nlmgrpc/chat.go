@@ -0,0 +1,82 @@
+package nlmgrpc
+
+import (
+	"context"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chatFunc streams a chat answer; it is api.Client.StreamChatWithContext
+// outside tests.
+type chatFunc func(context.Context, api.ChatRequest, func(api.ChatChunk) bool) error
+
+// GenerateFreeFormStreamed streams a chat answer. Like the NotebookLM
+// endpoint, each message carries the answer so far; the last one has
+// is_final set and the citations.
+func (s *orchestration) GenerateFreeFormStreamed(req *pb.GenerateFreeFormStreamedRequest, stream pb.LabsTailwindOrchestrationService_GenerateFreeFormStreamedServer) error {
+	projectID := req.GetProjectId()
+	if projectID == "" {
+		projectID = req.GetNotebookId()
+	}
+	if projectID == "" || req.GetPrompt() == "" {
+		return status.Error(codes.InvalidArgument, "project_id and prompt are required")
+	}
+	chatReq := api.ChatRequest{
+		ProjectID:      projectID,
+		Prompt:         req.GetPrompt(),
+		SourceIDs:      req.GetSourceIds(),
+		ConversationID: req.GetConversationId(),
+		SeqNum:         int(req.GetSequenceNumber()),
+	}
+	for _, h := range req.GetHistory() {
+		chatReq.History = append(chatReq.History, api.ChatMessage{Content: h.GetContent(), Role: int(h.GetRole())})
+	}
+
+	var answer string
+	var citations []*pb.ChatCitation
+	var sendErr error
+	err := s.chat(stream.Context(), chatReq, func(chunk api.ChatChunk) bool {
+		switch chunk.Phase {
+		case api.ChatChunkAnswer:
+			if chunk.Text == "" {
+				return true
+			}
+			answer += chunk.Text
+			sendErr = stream.Send(&pb.GenerateFreeFormStreamedResponse{Chunk: answer})
+		case api.ChatChunkCitations:
+			citations = chatCitations(chunk.Citations)
+		}
+		return sendErr == nil
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return statusError(err)
+	}
+	final := &pb.GenerateFreeFormStreamedResponse{Chunk: answer, IsFinal: true, Citations: citations}
+	if chatReq.ConversationID != "" {
+		final.Conversation = &pb.ChatConversationMetadata{ConversationId: chatReq.ConversationID, SequenceNumber: req.GetSequenceNumber()}
+	}
+	return stream.Send(final)
+}
+
+// chatCitations converts parsed citations to their wire messages, keeping
+// the cited spans and the source excerpt.
+func chatCitations(cs []api.Citation) []*pb.ChatCitation {
+	var out []*pb.ChatCitation
+	for _, c := range cs {
+		pc := &pb.ChatCitation{Confidence: c.Confidence}
+		for _, r := range c.Spans {
+			pc.Ranges = append(pc.Ranges, &pb.ChatCharacterRange{Start: int32(r.Start), End: int32(r.End)})
+		}
+		if c.Text != "" {
+			pc.Excerpts = []*pb.ChatCitationExcerpt{{Segments: []*pb.ChatCitationSegment{{Text: c.Text}}}}
+		}
+		out = append(out, pc)
+	}
+	return out
+}
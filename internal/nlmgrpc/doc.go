@@ -0,0 +1,5 @@
+// Package nlmgrpc implements the generated NotebookLM gRPC services by
+// proxying each call to NotebookLM through the batchexecute clients in
+// gen/service, so other languages can use typed clients generated from
+// the same protos against a local endpoint.
+package nlmgrpc
@@ -0,0 +1,218 @@
+package nlmgrpc
+
+import (
+	"context"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/gen/service"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// orchestration implements LabsTailwindOrchestrationServiceServer by
+// forwarding each call to the generated batchexecute client. Chat, which
+// has its own endpoint, is in chat.go.
+type orchestration struct {
+	pb.UnimplementedLabsTailwindOrchestrationServiceServer
+	client *service.LabsTailwindOrchestrationServiceClient
+	chat   chatFunc
+}
+
+// Artifact operations.
+
+func (s *orchestration) CreateArtifact(ctx context.Context, req *pb.CreateArtifactRequest) (*pb.Artifact, error) {
+	return forward(ctx, s.client.CreateArtifact, req)
+}
+
+func (s *orchestration) GetArtifact(ctx context.Context, req *pb.GetArtifactRequest) (*pb.Artifact, error) {
+	return forward(ctx, s.client.GetArtifact, req)
+}
+
+func (s *orchestration) UpdateArtifact(ctx context.Context, req *pb.UpdateArtifactRequest) (*pb.Artifact, error) {
+	return forward(ctx, s.client.UpdateArtifact, req)
+}
+
+func (s *orchestration) RenameArtifact(ctx context.Context, req *pb.RenameArtifactRequest) (*pb.Artifact, error) {
+	return forward(ctx, s.client.RenameArtifact, req)
+}
+
+func (s *orchestration) DeleteArtifact(ctx context.Context, req *pb.DeleteArtifactRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.DeleteArtifact, req)
+}
+
+func (s *orchestration) ListArtifacts(ctx context.Context, req *pb.ListArtifactsRequest) (*pb.ListArtifactsResponse, error) {
+	return forward(ctx, s.client.ListArtifacts, req)
+}
+
+func (s *orchestration) QueryArtifacts(ctx context.Context, req *pb.QueryArtifactsRequest) (*pb.QueryArtifactsResponse, error) {
+	return forward(ctx, s.client.QueryArtifacts, req)
+}
+
+// Source operations.
+
+func (s *orchestration) ActOnSources(ctx context.Context, req *pb.ActOnSourcesRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.ActOnSources, req)
+}
+
+func (s *orchestration) AddSources(ctx context.Context, req *pb.AddSourceRequest) (*pb.Project, error) {
+	return forward(ctx, s.client.AddSources, req)
+}
+
+func (s *orchestration) CheckSourceFreshness(ctx context.Context, req *pb.CheckSourceFreshnessRequest) (*pb.CheckSourceFreshnessResponse, error) {
+	return forward(ctx, s.client.CheckSourceFreshness, req)
+}
+
+func (s *orchestration) DeleteSources(ctx context.Context, req *pb.DeleteSourcesRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.DeleteSources, req)
+}
+
+func (s *orchestration) DiscoverSources(ctx context.Context, req *pb.DiscoverSourcesRequest) (*pb.DiscoverSourcesResponse, error) {
+	return forward(ctx, s.client.DiscoverSources, req)
+}
+
+func (s *orchestration) LoadSource(ctx context.Context, req *pb.LoadSourceRequest) (*pb.Source, error) {
+	return forward(ctx, s.client.LoadSource, req)
+}
+
+func (s *orchestration) MutateSource(ctx context.Context, req *pb.MutateSourceRequest) (*pb.Source, error) {
+	return forward(ctx, s.client.MutateSource, req)
+}
+
+func (s *orchestration) RefreshSource(ctx context.Context, req *pb.RefreshSourceRequest) (*pb.Source, error) {
+	return forward(ctx, s.client.RefreshSource, req)
+}
+
+// Audio operations.
+
+func (s *orchestration) CreateAudioOverview(ctx context.Context, req *pb.CreateAudioOverviewRequest) (*pb.AudioOverview, error) {
+	return forward(ctx, s.client.CreateAudioOverview, req)
+}
+
+func (s *orchestration) GetAudioOverview(ctx context.Context, req *pb.GetAudioOverviewRequest) (*pb.AudioOverview, error) {
+	return forward(ctx, s.client.GetAudioOverview, req)
+}
+
+func (s *orchestration) DeleteAudioOverview(ctx context.Context, req *pb.DeleteAudioOverviewRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.DeleteAudioOverview, req)
+}
+
+// Video operations.
+
+func (s *orchestration) CreateVideoOverview(ctx context.Context, req *pb.CreateVideoOverviewRequest) (*pb.VideoOverview, error) {
+	return forward(ctx, s.client.CreateVideoOverview, req)
+}
+
+// Note operations.
+
+func (s *orchestration) CreateNote(ctx context.Context, req *pb.CreateNoteRequest) (*pb.Note, error) {
+	return forward(ctx, s.client.CreateNote, req)
+}
+
+func (s *orchestration) DeleteNotes(ctx context.Context, req *pb.DeleteNotesRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.DeleteNotes, req)
+}
+
+func (s *orchestration) GetNotes(ctx context.Context, req *pb.GetNotesRequest) (*pb.GetNotesResponse, error) {
+	return forward(ctx, s.client.GetNotes, req)
+}
+
+func (s *orchestration) MutateNote(ctx context.Context, req *pb.MutateNoteRequest) (*pb.Note, error) {
+	return forward(ctx, s.client.MutateNote, req)
+}
+
+// Project operations.
+
+func (s *orchestration) CreateProject(ctx context.Context, req *pb.CreateProjectRequest) (*pb.Project, error) {
+	return forward(ctx, s.client.CreateProject, req)
+}
+
+func (s *orchestration) DeleteProjects(ctx context.Context, req *pb.DeleteProjectsRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.DeleteProjects, req)
+}
+
+func (s *orchestration) GetProject(ctx context.Context, req *pb.GetProjectRequest) (*pb.Project, error) {
+	return forward(ctx, s.client.GetProject, req)
+}
+
+func (s *orchestration) ListFeaturedProjects(ctx context.Context, req *pb.ListFeaturedProjectsRequest) (*pb.ListFeaturedProjectsResponse, error) {
+	return forward(ctx, s.client.ListFeaturedProjects, req)
+}
+
+func (s *orchestration) ListRecentlyViewedProjects(ctx context.Context, req *pb.ListRecentlyViewedProjectsRequest) (*pb.ListRecentlyViewedProjectsResponse, error) {
+	return forward(ctx, s.client.ListRecentlyViewedProjects, req)
+}
+
+func (s *orchestration) MutateProject(ctx context.Context, req *pb.MutateProjectRequest) (*pb.Project, error) {
+	return forward(ctx, s.client.MutateProject, req)
+}
+
+func (s *orchestration) RemoveRecentlyViewedProject(ctx context.Context, req *pb.RemoveRecentlyViewedProjectRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.RemoveRecentlyViewedProject, req)
+}
+
+// Generation operations.
+
+func (s *orchestration) GenerateDocumentGuides(ctx context.Context, req *pb.GenerateDocumentGuidesRequest) (*pb.GenerateDocumentGuidesResponse, error) {
+	return forward(ctx, s.client.GenerateDocumentGuides, req)
+}
+
+func (s *orchestration) GenerateNotebookGuide(ctx context.Context, req *pb.GenerateNotebookGuideRequest) (*pb.GenerateNotebookGuideResponse, error) {
+	return forward(ctx, s.client.GenerateNotebookGuide, req)
+}
+
+func (s *orchestration) GenerateOutline(ctx context.Context, req *pb.GenerateOutlineRequest) (*pb.GenerateOutlineResponse, error) {
+	return forward(ctx, s.client.GenerateOutline, req)
+}
+
+func (s *orchestration) GenerateReportSuggestions(ctx context.Context, req *pb.GenerateReportSuggestionsRequest) (*pb.GenerateReportSuggestionsResponse, error) {
+	return forward(ctx, s.client.GenerateReportSuggestions, req)
+}
+
+func (s *orchestration) GenerateSection(ctx context.Context, req *pb.GenerateSectionRequest) (*pb.GenerateSectionResponse, error) {
+	return forward(ctx, s.client.GenerateSection, req)
+}
+
+func (s *orchestration) StartDraft(ctx context.Context, req *pb.StartDraftRequest) (*pb.StartDraftResponse, error) {
+	return forward(ctx, s.client.StartDraft, req)
+}
+
+func (s *orchestration) StartSection(ctx context.Context, req *pb.StartSectionRequest) (*pb.StartSectionResponse, error) {
+	return forward(ctx, s.client.StartSection, req)
+}
+
+func (s *orchestration) GenerateMagicView(ctx context.Context, req *pb.GenerateMagicViewRequest) (*pb.GenerateMagicViewResponse, error) {
+	return forward(ctx, s.client.GenerateMagicView, req)
+}
+
+// Analytics and feedback.
+
+func (s *orchestration) GetProjectAnalytics(ctx context.Context, req *pb.GetProjectAnalyticsRequest) (*pb.ProjectAnalytics, error) {
+	return forward(ctx, s.client.GetProjectAnalytics, req)
+}
+
+func (s *orchestration) SubmitFeedback(ctx context.Context, req *pb.SubmitFeedbackRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.SubmitFeedback, req)
+}
+
+// Conversation lifecycle.
+
+func (s *orchestration) GetConversations(ctx context.Context, req *pb.GetConversationsRequest) (*pb.GetConversationsResponse, error) {
+	return forward(ctx, s.client.GetConversations, req)
+}
+
+func (s *orchestration) GetConversationHistory(ctx context.Context, req *pb.GetConversationHistoryRequest) (*pb.GetConversationHistoryResponse, error) {
+	return forward(ctx, s.client.GetConversationHistory, req)
+}
+
+func (s *orchestration) DeleteChatHistory(ctx context.Context, req *pb.DeleteChatHistoryRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.DeleteChatHistory, req)
+}
+
+// Account operations.
+
+func (s *orchestration) GetOrCreateAccount(ctx context.Context, req *pb.GetOrCreateAccountRequest) (*pb.Account, error) {
+	return forward(ctx, s.client.GetOrCreateAccount, req)
+}
+
+func (s *orchestration) MutateAccount(ctx context.Context, req *pb.MutateAccountRequest) (*pb.Account, error) {
+	return forward(ctx, s.client.MutateAccount, req)
+}
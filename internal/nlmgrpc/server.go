@@ -0,0 +1,132 @@
+package nlmgrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/batchexecute"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// New returns a gRPC server with the orchestration, sharing and
+// guidebooks services and server reflection registered. Requests use
+// client's credentials.
+func New(client *api.Client, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	register(s, &orchestration{client: client.OrchestrationService(), chat: client.StreamChatWithContext},
+		&sharing{client: client.SharingService()},
+		&guidebooks{client: client.GuidebooksService()})
+	return s
+}
+
+func register(s *grpc.Server, o *orchestration, sh *sharing, g *guidebooks) {
+	pb.RegisterLabsTailwindOrchestrationServiceServer(s, o)
+	pb.RegisterLabsTailwindSharingServiceServer(s, sh)
+	pb.RegisterLabsTailwindGuidebooksServiceServer(s, g)
+	reflection.Register(s)
+}
+
+// BearerToken returns server options that reject calls without the
+// metadata "authorization: Bearer token". Reflection stays open so
+// tools can discover the services before they are configured.
+func BearerToken(token string) []grpc.ServerOption {
+	check := func(ctx context.Context, method string) error {
+		if strings.HasPrefix(method, "/grpc.reflection.") {
+			return nil
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, v := range md.Get("authorization") {
+			if subtle.ConstantTimeCompare([]byte(v), []byte("Bearer "+token)) == 1 {
+				return nil
+			}
+		}
+		return status.Error(codes.Unauthenticated, "invalid bearer token")
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := check(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := check(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// forward calls a generated client method and converts its error to a
+// gRPC status.
+func forward[Req, Resp any](ctx context.Context, call func(context.Context, Req) (Resp, error), req Req) (Resp, error) {
+	resp, err := call(ctx, req)
+	if err != nil {
+		var zero Resp
+		return zero, statusError(err)
+	}
+	return resp, nil
+}
+
+// statusError maps a NotebookLM error to the closest gRPC status code.
+func statusError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	code := codes.Unknown
+	var apiErr *batchexecute.APIError
+	var beErr *batchexecute.BatchExecuteError
+	switch {
+	case errors.Is(err, batchexecute.ErrUnauthorized):
+		code = codes.Unauthenticated
+	case errors.As(err, &apiErr) && apiErr.ErrorCode != nil:
+		code = errorTypeCodes[apiErr.ErrorCode.Type]
+	case errors.As(err, &apiErr):
+		code = httpStatusCode(apiErr.HTTPStatus)
+	case errors.As(err, &beErr):
+		code = httpStatusCode(beErr.StatusCode)
+	}
+	return status.Error(code, err.Error())
+}
+
+var errorTypeCodes = map[batchexecute.ErrorType]codes.Code{
+	batchexecute.ErrorTypeUnknown:           codes.Unknown,
+	batchexecute.ErrorTypeAuthentication:    codes.Unauthenticated,
+	batchexecute.ErrorTypeAuthorization:     codes.PermissionDenied,
+	batchexecute.ErrorTypePermissionDenied:  codes.PermissionDenied,
+	batchexecute.ErrorTypeRateLimit:         codes.ResourceExhausted,
+	batchexecute.ErrorTypeResourceExhausted: codes.ResourceExhausted,
+	batchexecute.ErrorTypeNotFound:          codes.NotFound,
+	batchexecute.ErrorTypeInvalidInput:      codes.InvalidArgument,
+	batchexecute.ErrorTypeServerError:       codes.Unavailable,
+	batchexecute.ErrorTypeNetworkError:      codes.Unavailable,
+	batchexecute.ErrorTypeUnavailable:       codes.Unavailable,
+}
+
+func httpStatusCode(s int) codes.Code {
+	switch {
+	case s == http.StatusBadRequest:
+		return codes.InvalidArgument
+	case s == http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case s == http.StatusForbidden:
+		return codes.PermissionDenied
+	case s == http.StatusNotFound:
+		return codes.NotFound
+	case s == http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case s >= 500:
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
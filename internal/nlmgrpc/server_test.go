@@ -0,0 +1,192 @@
+package nlmgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/gen/service"
+	"github.com/tmc/nlm/internal/batchexecute"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// roundTripFunc answers batchexecute requests without a network.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// batchexecuteReply returns a chunked batchexecute response carrying data
+// for rpcID, or an HTTP error when code is not 200.
+func batchexecuteReply(code int, rpcID, data string) roundTripFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		chunk := fmt.Sprintf(`[["wrb.fr",%q,%s,null,null,null,"generic"]]`, rpcID, strconv.Quote(data))
+		body := ")]}'\n\n" + strconv.Itoa(len(chunk)) + "\n" + chunk + "\n"
+		return &http.Response{
+			StatusCode: code,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	}
+}
+
+// dial starts the services on an in-memory listener and returns a client
+// connection to them.
+func dial(t *testing.T, rt http.RoundTripper, chat chatFunc, opts ...grpc.ServerOption) *grpc.ClientConn {
+	t.Helper()
+	hc := batchexecute.WithHTTPClient(&http.Client{Transport: rt})
+	s := grpc.NewServer(opts...)
+	register(s,
+		&orchestration{client: service.NewLabsTailwindOrchestrationServiceClient("tok", "SID=x", hc), chat: chat},
+		&sharing{client: service.NewLabsTailwindSharingServiceClient("tok", "SID=x", hc)},
+		&guidebooks{client: service.NewLabsTailwindGuidebooksServiceClient("tok", "SID=x", hc)})
+	ln := bufconn.Listen(1 << 20)
+	go s.Serve(ln)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestUnaryForwarding(t *testing.T) {
+	conn := dial(t, batchexecuteReply(http.StatusOK, "wXbhsf", `[[["Runbooks",null,"nb-1"]]]`), nil)
+	resp, err := pb.NewLabsTailwindOrchestrationServiceClient(conn).ListRecentlyViewedProjects(context.Background(), &pb.ListRecentlyViewedProjectsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Projects) != 1 || resp.Projects[0].ProjectId != "nb-1" || resp.Projects[0].Title != "Runbooks" {
+		t.Errorf("projects = %v", resp.Projects)
+	}
+}
+
+func TestUnaryUnauthenticated(t *testing.T) {
+	conn := dial(t, batchexecuteReply(http.StatusUnauthorized, "rLM1Ne", "null"), nil)
+	_, err := pb.NewLabsTailwindOrchestrationServiceClient(conn).GetProject(context.Background(), &pb.GetProjectRequest{ProjectId: "nb-1"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("err = %v, want Unauthenticated", err)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want codes.Code
+	}{
+		{fmt.Errorf("GetProject: %w", &batchexecute.APIError{ErrorCode: &batchexecute.ErrorCode{Type: batchexecute.ErrorTypeNotFound}}), codes.NotFound},
+		{&batchexecute.APIError{HTTPStatus: 429}, codes.ResourceExhausted},
+		{&batchexecute.BatchExecuteError{StatusCode: 503}, codes.Unavailable},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("boom"), codes.Unknown},
+	} {
+		if got := status.Code(statusError(tt.err)); got != tt.want {
+			t.Errorf("statusError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateFreeFormStreamed(t *testing.T) {
+	var got api.ChatRequest
+	chat := func(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error {
+		got = req
+		cb(api.ChatChunk{Phase: api.ChatChunkThinking, Text: "Reading"})
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "Deploys run "})
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "nightly [1]."})
+		cb(api.ChatChunk{Phase: api.ChatChunkCitations, Citations: []api.Citation{{Number: 1, Text: "cron: nightly", Spans: []api.TextRange{{Start: 0, End: 19}}}}})
+		return nil
+	}
+	conn := dial(t, nil, chat)
+	stream, err := pb.NewLabsTailwindOrchestrationServiceClient(conn).GenerateFreeFormStreamed(context.Background(), &pb.GenerateFreeFormStreamedRequest{
+		ProjectId:      "nb-1",
+		Prompt:         "When?",
+		ConversationId: "conv-1",
+		SequenceNumber: 2,
+		History:        []*pb.GenerateFreeFormStreamedHistoryEntry{{Content: "Deploys.", Role: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []*pb.GenerateFreeFormStreamedResponse
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, resp)
+	}
+	if len(chunks) != 3 || chunks[0].Chunk != "Deploys run " || chunks[1].Chunk != "Deploys run nightly [1]." {
+		t.Fatalf("chunks = %v", chunks)
+	}
+	final := chunks[2]
+	if !final.IsFinal || final.Chunk != "Deploys run nightly [1]." || final.GetConversation().GetConversationId() != "conv-1" {
+		t.Errorf("final = %v", final)
+	}
+	if len(final.Citations) != 1 || final.Citations[0].Ranges[0].End != 19 || final.Citations[0].Excerpts[0].Segments[0].Text != "cron: nightly" {
+		t.Errorf("citations = %v", final.Citations)
+	}
+	if got.ProjectID != "nb-1" || got.SeqNum != 2 || len(got.History) != 1 || got.History[0].Role != 2 {
+		t.Errorf("chat request = %+v", got)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	conn := dial(t, batchexecuteReply(http.StatusOK, "wXbhsf", `[[]]`), nil, BearerToken("sekret")...)
+	client := pb.NewLabsTailwindOrchestrationServiceClient(conn)
+	for token, want := range map[string]codes.Code{"": codes.Unauthenticated, "wrong": codes.Unauthenticated, "sekret": codes.OK} {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		_, err := client.ListRecentlyViewedProjects(ctx, &pb.ListRecentlyViewedProjectsRequest{})
+		if status.Code(err) != want {
+			t.Errorf("token %q: err = %v, want %v", token, err, want)
+		}
+	}
+
+	// Reflection needs no token.
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&rpb.ServerReflectionRequest{MessageRequest: &rpb.ServerReflectionRequest_ListServices{}}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		names = append(names, s.Name)
+	}
+	for _, want := range []string{
+		"notebooklm.v1alpha1.LabsTailwindOrchestrationService",
+		"notebooklm.v1alpha1.LabsTailwindSharingService",
+		"notebooklm.v1alpha1.LabsTailwindGuidebooksService",
+	} {
+		if !strings.Contains(strings.Join(names, " "), want) {
+			t.Errorf("reflection services = %v, missing %s", names, want)
+		}
+	}
+}
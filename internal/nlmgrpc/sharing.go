@@ -0,0 +1,67 @@
+package nlmgrpc
+
+import (
+	"context"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/gen/service"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// sharing implements LabsTailwindSharingServiceServer.
+type sharing struct {
+	pb.UnimplementedLabsTailwindSharingServiceServer
+	client *service.LabsTailwindSharingServiceClient
+}
+
+// Audio sharing.
+
+func (s *sharing) ShareAudio(ctx context.Context, req *pb.ShareAudioRequest) (*pb.ShareAudioResponse, error) {
+	return forward(ctx, s.client.ShareAudio, req)
+}
+
+// Project sharing.
+
+func (s *sharing) GetProjectDetails(ctx context.Context, req *pb.GetProjectDetailsRequest) (*pb.ProjectDetails, error) {
+	return forward(ctx, s.client.GetProjectDetails, req)
+}
+
+func (s *sharing) ShareProject(ctx context.Context, req *pb.ShareProjectRequest) (*pb.ShareProjectResponse, error) {
+	return forward(ctx, s.client.ShareProject, req)
+}
+
+// guidebooks implements LabsTailwindGuidebooksServiceServer.
+type guidebooks struct {
+	pb.UnimplementedLabsTailwindGuidebooksServiceServer
+	client *service.LabsTailwindGuidebooksServiceClient
+}
+
+// Guidebook operations.
+
+func (s *guidebooks) DeleteGuidebook(ctx context.Context, req *pb.DeleteGuidebookRequest) (*emptypb.Empty, error) {
+	return forward(ctx, s.client.DeleteGuidebook, req)
+}
+
+func (s *guidebooks) GetGuidebook(ctx context.Context, req *pb.GetGuidebookRequest) (*pb.Guidebook, error) {
+	return forward(ctx, s.client.GetGuidebook, req)
+}
+
+func (s *guidebooks) ListRecentlyViewedGuidebooks(ctx context.Context, req *pb.ListRecentlyViewedGuidebooksRequest) (*pb.ListRecentlyViewedGuidebooksResponse, error) {
+	return forward(ctx, s.client.ListRecentlyViewedGuidebooks, req)
+}
+
+func (s *guidebooks) PublishGuidebook(ctx context.Context, req *pb.PublishGuidebookRequest) (*pb.PublishGuidebookResponse, error) {
+	return forward(ctx, s.client.PublishGuidebook, req)
+}
+
+func (s *guidebooks) GetGuidebookDetails(ctx context.Context, req *pb.GetGuidebookDetailsRequest) (*pb.GuidebookDetails, error) {
+	return forward(ctx, s.client.GetGuidebookDetails, req)
+}
+
+func (s *guidebooks) ShareGuidebook(ctx context.Context, req *pb.ShareGuidebookRequest) (*pb.ShareGuidebookResponse, error) {
+	return forward(ctx, s.client.ShareGuidebook, req)
+}
+
+func (s *guidebooks) GuidebookGenerateAnswer(ctx context.Context, req *pb.GuidebookGenerateAnswerRequest) (*pb.GuidebookGenerateAnswerResponse, error) {
+	return forward(ctx, s.client.GuidebookGenerateAnswer, req)
+}
@@ -0,0 +1,62 @@
+package nlmmcp
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// HTTPOptions configures the HTTP transports.
+type HTTPOptions struct {
+	// Token is the bearer token clients must send. Empty disables auth.
+	Token string
+	// AllowedOrigins lists the origins browser clients may connect from;
+	// "*" allows any. Requests with any other Origin header are refused,
+	// which also guards local servers against DNS rebinding.
+	AllowedOrigins []string
+	// SessionTimeout closes streamable HTTP sessions that have been idle
+	// this long. Zero keeps them until the client ends them.
+	SessionTimeout time.Duration
+}
+
+// Handler serves server to any number of clients, over the streamable
+// HTTP transport at /mcp and the legacy SSE transport at /sse. Each client
+// gets its own session; the server, and so the NotebookLM client and
+// resource subscriptions, are shared.
+func Handler(server *mcp.Server, opts HTTPOptions) http.Handler {
+	getServer := func(*http.Request) *mcp.Server { return server }
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", mcp.NewStreamableHTTPHandler(getServer, &mcp.StreamableHTTPOptions{SessionTimeout: opts.SessionTimeout}))
+	mux.Handle("/sse", mcp.NewSSEHandler(getServer, nil))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !slices.Contains(opts.AllowedOrigins, "*") && !slices.Contains(opts.AllowedOrigins, origin) {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Expose-Headers", "Mcp-Session-Id")
+			if r.Method == http.MethodOptions {
+				h.Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+				h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Last-Event-ID, Mcp-Protocol-Version, Mcp-Session-Id")
+				h.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		if opts.Token != "" {
+			got := []byte(r.Header.Get("Authorization"))
+			if subtle.ConstantTimeCompare(got, []byte("Bearer "+opts.Token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
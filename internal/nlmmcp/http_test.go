@@ -0,0 +1,79 @@
+package nlmmcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// bearerTransport adds an Authorization header to each request.
+type bearerTransport string
+
+func (t bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+string(t))
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func newHTTPServer(t *testing.T, opts HTTPOptions) *httptest.Server {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	newResources(&fakeResourceBackend{}).register(server)
+	ts := httptest.NewServer(Handler(server, opts))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestHTTPTransports(t *testing.T) {
+	ts := newHTTPServer(t, HTTPOptions{Token: "sekret"})
+	hc := &http.Client{Transport: bearerTransport("sekret")}
+	ctx := context.Background()
+	for name, transport := range map[string]mcp.Transport{
+		"streamable": &mcp.StreamableClientTransport{Endpoint: ts.URL + "/mcp", HTTPClient: hc},
+		"sse":        &mcp.SSEClientTransport{Endpoint: ts.URL + "/sse", HTTPClient: hc},
+	} {
+		cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, transport, nil)
+		if err != nil {
+			t.Fatalf("%s: connect: %v", name, err)
+		}
+		if res, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: notebooksURI}); err != nil || len(res.Contents) != 1 {
+			t.Errorf("%s: read = %+v, %v", name, res, err)
+		}
+		cs.Close()
+	}
+}
+
+func TestHTTPAuthAndCORS(t *testing.T) {
+	ts := newHTTPServer(t, HTTPOptions{Token: "sekret", AllowedOrigins: []string{"https://app.example"}})
+	for _, tt := range []struct {
+		method, origin, token string
+		want                  int
+	}{
+		{"POST", "", "", http.StatusUnauthorized},
+		{"POST", "", "wrong", http.StatusUnauthorized},
+		{"POST", "https://evil.example", "sekret", http.StatusForbidden},
+		{"OPTIONS", "https://app.example", "", http.StatusNoContent},
+	} {
+		req, _ := http.NewRequest(tt.method, ts.URL+"/mcp", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s origin=%q token=%q: status %d, want %d", tt.method, tt.origin, tt.token, resp.StatusCode, tt.want)
+		}
+		if tt.want == http.StatusNoContent && resp.Header.Get("Access-Control-Allow-Origin") != tt.origin {
+			t.Errorf("preflight headers = %v", resp.Header)
+		}
+	}
+}
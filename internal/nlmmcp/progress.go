@@ -0,0 +1,125 @@
+package nlmmcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// Long-running tools report what they are doing with
+// notifications/progress when the client sent a progress token, and stop
+// when the client cancels the call.
+
+// waitInterval is how often waiting tools poll NotebookLM; waitTimeout is
+// how long they wait before giving up.
+var (
+	waitInterval = 10 * time.Second
+	waitTimeout  = 30 * time.Minute
+)
+
+// progress reports the progress of one tool call.
+type progress struct {
+	req  *mcp.CallToolRequest
+	step float64
+}
+
+func newProgress(req *mcp.CallToolRequest) *progress {
+	return &progress{req: req}
+}
+
+// report sends msg as the next progress step. Progress is a no-op when
+// the client did not ask for it, and send errors are ignored: progress is
+// advisory.
+func (p *progress) report(ctx context.Context, format string, args ...any) {
+	if p == nil || p.req == nil || p.req.Session == nil || p.req.Params.GetProgressToken() == nil {
+		return
+	}
+	p.step++
+	p.req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: p.req.Params.GetProgressToken(),
+		Message:       fmt.Sprintf(format, args...),
+		Progress:      p.step,
+	})
+}
+
+// chatFunc streams a chat response, as api.Client.StreamChatWithContext.
+type chatFunc func(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error
+
+// streamChat returns the answer to req, reporting each new thinking
+// header as progress.
+func streamChat(ctx context.Context, chat chatFunc, req api.ChatRequest, p *progress) (string, error) {
+	var answer strings.Builder
+	var header string
+	err := chat(ctx, req, func(c api.ChatChunk) bool {
+		switch c.Phase {
+		case api.ChatChunkThinking:
+			if c.Header != "" && c.Header != header {
+				header = c.Header
+				p.report(ctx, "%s", header)
+			}
+		case api.ChatChunkAnswer:
+			if answer.Len() == 0 {
+				p.report(ctx, "answering")
+			}
+			answer.WriteString(c.Text)
+		}
+		return ctx.Err() == nil
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	return answer.String(), err
+}
+
+// waitForArtifact polls list until the artifact is ready or failed,
+// reporting each state change. It returns the final state.
+func waitForArtifact(ctx context.Context, list func() ([]*pb.Artifact, error), artifactID string, p *progress) (pb.ArtifactState, error) {
+	ctx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+	last := pb.ArtifactState(-1)
+	err := api.WaitArtifacts(ctx, waitInterval, list, func(artifacts []*pb.Artifact) (bool, error) {
+		for _, a := range artifacts {
+			if a.GetArtifactId() != artifactID {
+				continue
+			}
+			if state := a.GetState(); state != last {
+				last = state
+				p.report(ctx, "%s: %s", artifactID, artifactStateLabel(state))
+			}
+		}
+		return last == pb.ArtifactState_ARTIFACT_STATE_READY || last == pb.ArtifactState_ARTIFACT_STATE_FAILED, nil
+	})
+	return last, err
+}
+
+// waitForResearch polls until the research is done, reporting the time
+// spent after each poll.
+func waitForResearch(ctx context.Context, poll func() (*api.DeepResearchResult, error), p *progress) (*api.DeepResearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+	start := time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := poll()
+		if err != nil {
+			return nil, err
+		}
+		if result.Done {
+			p.report(ctx, "research done")
+			return result, nil
+		}
+		p.report(ctx, "researching (%v)", time.Since(start).Round(time.Second))
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(waitInterval):
+		}
+	}
+}
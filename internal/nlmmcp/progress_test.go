@@ -0,0 +1,139 @@
+package nlmmcp
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+type waitInput struct{}
+
+// callWithProgress registers handler as a tool, calls it with a progress
+// token and returns the result and the progress messages received.
+func callWithProgress(t *testing.T, handler func(ctx context.Context, p *progress) string) (string, []string) {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "work"}, func(ctx context.Context, req *mcp.CallToolRequest, _ waitInput) (*mcp.CallToolResult, any, error) {
+		return textResult(handler(ctx, newProgress(req))), nil, nil
+	})
+	var mu sync.Mutex
+	var messages []string
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			messages = append(messages, req.Params.Message)
+		},
+	})
+	ctx := context.Background()
+	st, ct := mcp.NewInMemoryTransports()
+	ss, err := server.Connect(ctx, st, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+	cs, err := client.Connect(ctx, ct, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	// SetProgressToken loses the token when Meta is nil.
+	params := &mcp.CallToolParams{Name: "work", Arguments: map[string]any{}, Meta: mcp.Meta{"progressToken": "tok"}}
+	res, err := cs.CallTool(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Notifications are delivered asynchronously.
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	return res.Content[0].(*mcp.TextContent).Text, messages
+}
+
+func TestStreamChatProgress(t *testing.T) {
+	chat := func(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error {
+		cb(api.ChatChunk{Phase: api.ChatChunkThinking, Header: "Reading sources", Text: "Reading sources\n..."})
+		cb(api.ChatChunk{Phase: api.ChatChunkThinking, Header: "Reading sources", Text: "Reading sources\n...more"})
+		cb(api.ChatChunk{Phase: api.ChatChunkThinking, Header: "Drafting"})
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "Deploys run "})
+		cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "nightly."})
+		return nil
+	}
+	answer, messages := callWithProgress(t, func(ctx context.Context, p *progress) string {
+		answer, err := streamChat(ctx, chat, api.ChatRequest{}, p)
+		if err != nil {
+			t.Error(err)
+		}
+		return answer
+	})
+	if answer != "Deploys run nightly." {
+		t.Errorf("answer = %q", answer)
+	}
+	if want := []string{"Reading sources", "Drafting", "answering"}; !slices.Equal(messages, want) {
+		t.Errorf("progress = %q, want %q", messages, want)
+	}
+}
+
+func TestWaitForArtifactProgress(t *testing.T) {
+	defer setWaitInterval(time.Millisecond)()
+	states := []pb.ArtifactState{
+		pb.ArtifactState_ARTIFACT_STATE_CREATING,
+		pb.ArtifactState_ARTIFACT_STATE_CREATING,
+		pb.ArtifactState_ARTIFACT_STATE_READY,
+	}
+	polls := 0
+	list := func() ([]*pb.Artifact, error) {
+		state := states[min(polls, len(states)-1)]
+		polls++
+		return []*pb.Artifact{{ArtifactId: "other"}, {ArtifactId: "a-1", State: state}}, nil
+	}
+	text, messages := callWithProgress(t, func(ctx context.Context, p *progress) string {
+		state, err := waitForArtifact(ctx, list, "a-1", p)
+		return artifactWaitResult("audio overview", "a-1", state, err).Content[0].(*mcp.TextContent).Text
+	})
+	if text != "audio overview a-1 is ready" {
+		t.Errorf("result = %q", text)
+	}
+	if want := []string{"a-1: ARTIFACT_STATE_CREATING", "a-1: ARTIFACT_STATE_READY"}; !slices.Equal(messages, want) {
+		t.Errorf("progress = %q, want %q", messages, want)
+	}
+}
+
+func TestWaitCancellation(t *testing.T) {
+	defer setWaitInterval(time.Hour)()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := waitForArtifact(ctx, func() ([]*pb.Artifact, error) { return nil, nil }, "a-1", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("waitForArtifact err = %v, want canceled", err)
+	}
+	_, err = waitForResearch(ctx, func() (*api.DeepResearchResult, error) { return &api.DeepResearchResult{}, nil }, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("waitForResearch err = %v, want canceled", err)
+	}
+
+	chat := func(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error {
+		for cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "."}) {
+		}
+		return nil
+	}
+	if _, err := streamChat(ctx, chat, api.ChatRequest{}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("streamChat err = %v, want canceled", err)
+	}
+}
+
+func setWaitInterval(d time.Duration) (restore func()) {
+	old := waitInterval
+	waitInterval = d
+	return func() { waitInterval = old }
+}
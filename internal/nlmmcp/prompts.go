@@ -0,0 +1,159 @@
+package nlmmcp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// registerPrompts registers a prompt for each generation action. The
+// prompt asks the assistant itself to do the transformation, with
+// excerpts of the selected sources embedded, so clients can offer them as
+// slash commands. NotebookLM does not expose source text, so the excerpts
+// are the passages a chat over the sources cites.
+func registerPrompts(server *mcp.Server, res *resources, chat chatFunc) {
+	for _, g := range generationActions {
+		server.AddPrompt(&mcp.Prompt{
+			Name:        g.name,
+			Title:       strings.ReplaceAll(g.name, "_", " "),
+			Description: fmt.Sprintf("%s from excerpts of a notebook's sources.", g.instruction),
+			Arguments: []*mcp.PromptArgument{
+				{Name: "notebook_id", Description: "Notebook ID", Required: true},
+				{Name: "sources", Description: "Comma-separated source IDs or title fragments (default: all sources)"},
+				{Name: "audience", Description: "Who the result is for, such as \"new engineers\""},
+				{Name: "length", Description: "How long the result should be, such as short, medium or long"},
+			},
+		}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return generationPrompt(ctx, res, chat, g, req.Params.Arguments)
+		})
+	}
+}
+
+func generationPrompt(ctx context.Context, res *resources, chat chatFunc, g generationAction, args map[string]string) (*mcp.GetPromptResult, error) {
+	notebookID := strings.TrimSpace(args["notebook_id"])
+	if notebookID == "" {
+		return nil, fmt.Errorf("notebook_id is required")
+	}
+	project, err := res.backend.GetProject(notebookID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	sources, err := selectPromptSources(project.GetSources(), args["sources"])
+	if err != nil {
+		return nil, err
+	}
+	excerpts, err := sourceExcerpts(ctx, chat, notebookID, g, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s from the excerpts below of the sources of the notebook %q.", g.instruction, strings.TrimSpace(project.GetTitle()))
+	if audience := strings.TrimSpace(args["audience"]); audience != "" {
+		fmt.Fprintf(&b, " Write for %s.", audience)
+	}
+	if length := strings.TrimSpace(args["length"]); length != "" {
+		fmt.Fprintf(&b, " Keep it %s.", length)
+	}
+	b.WriteString(" Cite sources by title where the excerpts name them.")
+
+	messages := []*mcp.PromptMessage{{Role: "user", Content: &mcp.TextContent{Text: b.String()}}}
+	addExcerpts := func(heading string, passages []string) {
+		if len(passages) == 0 {
+			return
+		}
+		var text strings.Builder
+		text.WriteString(heading + "\n")
+		for _, p := range passages {
+			fmt.Fprintf(&text, "\n> %s\n", strings.ReplaceAll(strings.TrimSpace(p), "\n", "\n> "))
+		}
+		messages = append(messages, &mcp.PromptMessage{Role: "user", Content: &mcp.TextContent{Text: text.String()}})
+	}
+	titles := make([]string, len(sources))
+	n := len(excerpts[""])
+	for i, src := range sources {
+		titles[i] = strconv.Quote(strings.TrimSpace(src.GetTitle()))
+		passages := excerpts[src.GetSourceId().GetSourceId()]
+		n += len(passages)
+		addExcerpts(fmt.Sprintf("Excerpts from %s:", titles[i]), passages)
+	}
+	addExcerpts(fmt.Sprintf("Excerpts from %s; NotebookLM does not say which of these sources each one comes from:", strings.Join(titles, ", ")), excerpts[""])
+	return &mcp.GetPromptResult{
+		Description: fmt.Sprintf("%s for %q (%d excerpts of %d sources)", g.name, strings.TrimSpace(project.GetTitle()), n, len(sources)),
+		Messages:    messages,
+	}, nil
+}
+
+// sourceExcerpts asks NotebookLM, in one chat restricted to sources, for
+// the passages that matter to g, and returns the cited passages by source
+// ID, without duplicates. Chat citations only name their source when the
+// chat has a single source; passages whose source is unknown, or not one of
+// sources, are returned under "".
+func sourceExcerpts(ctx context.Context, chat chatFunc, notebookID string, g generationAction, sources []*pb.Source) (map[string][]string, error) {
+	req := api.ChatRequest{
+		ProjectID: notebookID,
+		Prompt:    fmt.Sprintf("Quote the passages of these sources that someone would need to do this: %s. Cite every passage.", g.instruction),
+	}
+	selected := make(map[string]bool)
+	for _, src := range sources {
+		req.SourceIDs = append(req.SourceIDs, src.GetSourceId().GetSourceId())
+		selected[src.GetSourceId().GetSourceId()] = true
+	}
+	excerpts := make(map[string][]string)
+	seen := make(map[string]bool)
+	err := chat(ctx, req, func(c api.ChatChunk) bool {
+		for _, cite := range c.Citations {
+			id := cite.SourceID
+			if !selected[id] {
+				id = ""
+			}
+			key := id + "\x00" + cite.Text
+			if cite.Text == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			excerpts[id] = append(excerpts[id], cite.Text)
+		}
+		return ctx.Err() == nil
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("excerpt sources: %w", err)
+	}
+	if len(excerpts) == 0 {
+		return nil, fmt.Errorf("NotebookLM cited no passages from the selected sources")
+	}
+	return excerpts, nil
+}
+
+// selectPromptSources returns the sources matching any of the
+// comma-separated terms, by exact ID or case-insensitive title fragment,
+// or all sources when terms is empty.
+func selectPromptSources(sources []*pb.Source, terms string) ([]*pb.Source, error) {
+	if strings.TrimSpace(terms) == "" {
+		return sources, nil
+	}
+	var out []*pb.Source
+	for _, src := range sources {
+		id := src.GetSourceId().GetSourceId()
+		title := strings.ToLower(src.GetTitle())
+		for _, term := range strings.Split(terms, ",") {
+			term = strings.TrimSpace(term)
+			if term != "" && (term == id || strings.Contains(title, strings.ToLower(term))) {
+				out = append(out, src)
+				break
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no sources match %q", terms)
+	}
+	return out, nil
+}
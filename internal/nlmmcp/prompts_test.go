@@ -0,0 +1,167 @@
+package nlmmcp
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+// fakePromptBackend is a notebook with two sources.
+type fakePromptBackend struct {
+	fakeResourceBackend
+	loads int
+}
+
+func (f *fakePromptBackend) GetProject(id string) (*api.Notebook, error) {
+	return &api.Notebook{ProjectId: id, Title: "Runbooks", Sources: []*pb.Source{
+		{SourceId: &pb.SourceId{SourceId: "src-1"}, Title: "Deploys"},
+		{SourceId: &pb.SourceId{SourceId: "src-2"}, Title: "Incident review"},
+	}}, nil
+}
+
+func (f *fakePromptBackend) LoadSource(id string) (*pb.Source, error) {
+	f.loads++
+	return f.fakeResourceBackend.LoadSource(id)
+}
+
+// excerptChat cites one passage of each requested source, twice, and
+// records the requests. Like NotebookLM, it names the cited source only
+// when the chat has a single source.
+type excerptChat struct {
+	mu   sync.Mutex
+	reqs []api.ChatRequest
+}
+
+func (e *excerptChat) chat(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error {
+	e.mu.Lock()
+	e.reqs = append(e.reqs, req)
+	e.mu.Unlock()
+	var cs []api.Citation
+	for i, id := range req.SourceIDs {
+		c := api.Citation{Number: i + 1, Text: "Passage of " + id + "\nsecond line"}
+		if len(req.SourceIDs) == 1 {
+			c.SourceID = id
+		}
+		cs = append(cs, c, c)
+	}
+	cb(api.ChatChunk{Phase: api.ChatChunkAnswer, Text: "Quoted."})
+	cb(api.ChatChunk{Phase: api.ChatChunkCitations, Citations: cs})
+	return nil
+}
+
+func connectPrompts(t *testing.T) (*mcp.ClientSession, *fakePromptBackend, *excerptChat) {
+	t.Helper()
+	backend, chat := &fakePromptBackend{}, &excerptChat{}
+	res := newResources(backend)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	registerPrompts(server, res, chat.chat)
+	ctx := context.Background()
+	st, ct := mcp.NewInMemoryTransports()
+	ss, err := server.Connect(ctx, st, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, ct, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs, backend, chat
+}
+
+func TestListPrompts(t *testing.T) {
+	cs, _, _ := connectPrompts(t)
+	list, err := cs.ListPrompts(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Prompts) != len(generationActions) {
+		t.Fatalf("got %d prompts, want %d", len(list.Prompts), len(generationActions))
+	}
+	for _, p := range list.Prompts {
+		if len(p.Arguments) != 4 || p.Arguments[0].Name != "notebook_id" || !p.Arguments[0].Required {
+			t.Errorf("prompt %s arguments = %+v", p.Name, p.Arguments)
+		}
+	}
+}
+
+func TestGetPrompt(t *testing.T) {
+	cs, backend, chat := connectPrompts(t)
+	ctx := context.Background()
+	got, err := cs.GetPrompt(ctx, &mcp.GetPromptParams{Name: "study_guide", Arguments: map[string]string{
+		"notebook_id": "nb-1",
+		"sources":     "incident",
+		"audience":    "new on-call engineers",
+		"length":      "short",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages) != 2 {
+		t.Fatalf("messages = %+v", got.Messages)
+	}
+	text := got.Messages[0].Content.(*mcp.TextContent).Text
+	for _, want := range []string{`"Runbooks"`, "Write for new on-call engineers.", "Keep it short."} {
+		if !strings.Contains(text, want) {
+			t.Errorf("instruction %q missing %q", text, want)
+		}
+	}
+	want := "Excerpts from \"Incident review\":\n\n> Passage of src-2\n> second line\n"
+	if got := got.Messages[1].Content.(*mcp.TextContent).Text; got != want {
+		t.Errorf("excerpts = %q, want %q", got, want)
+	}
+	if len(chat.reqs) != 1 || !slices.Equal(chat.reqs[0].SourceIDs, []string{"src-2"}) {
+		t.Errorf("chat requests = %+v, want one over src-2", chat.reqs)
+	}
+
+	all, err := cs.GetPrompt(ctx, &mcp.GetPromptParams{Name: "faq", Arguments: map[string]string{"notebook_id": "nb-1"}})
+	if err != nil || len(all.Messages) != 2 {
+		t.Fatalf("faq over all sources = %+v, %v", all, err)
+	}
+	want = "Excerpts from \"Deploys\", \"Incident review\"; NotebookLM does not say which of these sources each one comes from:\n\n" +
+		"> Passage of src-1\n> second line\n\n> Passage of src-2\n> second line\n"
+	if got := all.Messages[1].Content.(*mcp.TextContent).Text; got != want {
+		t.Errorf("unattributed excerpts = %q, want %q", got, want)
+	}
+	if want := `faq for "Runbooks" (2 excerpts of 2 sources)`; all.Description != want {
+		t.Errorf("description = %q, want %q", all.Description, want)
+	}
+	if len(chat.reqs) != 2 || backend.loads != 0 {
+		t.Errorf("%d chats and %d LoadSource calls, want 2 and 0", len(chat.reqs), backend.loads)
+	}
+
+	for _, args := range []map[string]string{
+		{"sources": "deploys"},
+		{"notebook_id": "nb-1", "sources": "nothing"},
+	} {
+		if _, err := cs.GetPrompt(ctx, &mcp.GetPromptParams{Name: "faq", Arguments: args}); err == nil {
+			t.Errorf("GetPrompt(%v) succeeded", args)
+		}
+	}
+}
+
+func TestSourceExcerptsUnknownSource(t *testing.T) {
+	chat := func(ctx context.Context, req api.ChatRequest, cb func(api.ChatChunk) bool) error {
+		cb(api.ChatChunk{Phase: api.ChatChunkCitations, Citations: []api.Citation{
+			{Number: 1, SourceID: "src-1", Text: "named"},
+			{Number: 2, Text: "unnamed"},
+			{Number: 3, SourceID: "src-9", Text: "elsewhere"},
+		}})
+		return nil
+	}
+	sources := []*pb.Source{{SourceId: &pb.SourceId{SourceId: "src-1"}}, {SourceId: &pb.SourceId{SourceId: "src-2"}}}
+	got, err := sourceExcerpts(context.Background(), chat, "nb-1", generationActions[0], sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got["src-1"], []string{"named"}) || !slices.Equal(got[""], []string{"unnamed", "elsewhere"}) || len(got) != 2 {
+		t.Errorf("excerpts = %q", got)
+	}
+}
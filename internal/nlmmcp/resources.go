@@ -0,0 +1,378 @@
+package nlmmcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Resources expose notebook content under nlm://notebooks:
+//
+//	nlm://notebooks                                      notebook list
+//	nlm://notebooks/{notebook_id}/sources                source list
+//	nlm://notebooks/{notebook_id}/sources/{source_id}    a source, from LoadSource
+//	nlm://notebooks/{notebook_id}/notes                  note list
+//	nlm://notebooks/{notebook_id}/notes/{note_id}        a note, as Markdown
+//	nlm://notebooks/{notebook_id}/artifacts              artifact list
+//
+// NotebookLM has no change feed, so subscriptions are served by re-reading
+// the subscribed resources every resourcePollInterval and notifying
+// subscribers when the content changes. A session's subscriptions end when
+// it closes, including HTTP sessions that time out.
+
+const (
+	notebooksURI         = "nlm://notebooks"
+	resourcePollInterval = 30 * time.Second
+)
+
+// resourceBackend is the part of api.Client the resources read.
+type resourceBackend interface {
+	ListRecentlyViewedProjects() ([]*api.Notebook, error)
+	GetProject(projectID string) (*api.Notebook, error)
+	LoadSource(sourceID string) (*pb.Source, error)
+	GetNotes(projectID string) ([]*api.Note, error)
+	ListArtifacts(projectID string) ([]*pb.Artifact, error)
+}
+
+type notebookResource struct {
+	notebookSummary
+	Sources   string `json:"sources"`
+	Notes     string `json:"notes"`
+	Artifacts string `json:"artifacts"`
+}
+
+type itemResource struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URI   string `json:"uri"`
+}
+
+// resources reads resources and polls the subscribed ones for changes.
+type resources struct {
+	backend  resourceBackend
+	server   *mcp.Server
+	interval time.Duration
+
+	mu       sync.Mutex
+	watched  map[string]*watchedResource
+	sessions map[*mcp.ServerSession]bool // sessions waited on by dropSession
+	polling  bool
+}
+
+type watchedResource struct {
+	subscribers map[*mcp.ServerSession]bool
+	sum         [sha256.Size]byte
+}
+
+func newResources(backend resourceBackend) *resources {
+	return &resources{
+		backend:  backend,
+		interval: resourcePollInterval,
+		watched:  map[string]*watchedResource{},
+		sessions: map[*mcp.ServerSession]bool{},
+	}
+}
+
+// register adds the resources to server, which must have been created
+// with r.subscribe and r.unsubscribe as its subscription handlers.
+func (r *resources) register(server *mcp.Server) {
+	r.server = server
+	read := func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return r.read(req.Params.URI)
+	}
+	server.AddResource(&mcp.Resource{
+		URI:         notebooksURI,
+		Name:        "notebooks",
+		Title:       "Notebooks",
+		Description: "Recently viewed notebooks, with the URIs of their sources, notes and artifacts.",
+		MIMEType:    "application/json",
+	}, read)
+	for _, t := range []*mcp.ResourceTemplate{{
+		URITemplate: notebooksURI + "/{notebook_id}/sources",
+		Name:        "sources",
+		Title:       "Notebook sources",
+		Description: "Sources in a notebook, with their URIs.",
+		MIMEType:    "application/json",
+	}, {
+		URITemplate: notebooksURI + "/{notebook_id}/sources/{source_id}",
+		Name:        "source",
+		Title:       "Source",
+		Description: "A source's title, type, status and metadata.",
+		MIMEType:    "application/json",
+	}, {
+		URITemplate: notebooksURI + "/{notebook_id}/notes",
+		Name:        "notes",
+		Title:       "Notebook notes",
+		Description: "Notes in a notebook, with their URIs.",
+		MIMEType:    "application/json",
+	}, {
+		URITemplate: notebooksURI + "/{notebook_id}/notes/{note_id}",
+		Name:        "note",
+		Title:       "Note",
+		Description: "A note's title and content.",
+		MIMEType:    "text/markdown",
+	}, {
+		URITemplate: notebooksURI + "/{notebook_id}/artifacts",
+		Name:        "artifacts",
+		Title:       "Notebook artifacts",
+		Description: "Artifacts in a notebook, such as audio overviews and reports, with their type and state.",
+		MIMEType:    "application/json",
+	}} {
+		server.AddResourceTemplate(t, read)
+	}
+}
+
+// parseResourceURI splits a resource URI into the notebook ID, the
+// collection (sources, notes or artifacts) and the item ID. All are empty
+// for the notebook list.
+func parseResourceURI(uri string) (notebookID, collection, itemID string, ok bool) {
+	if uri == notebooksURI {
+		return "", "", "", true
+	}
+	rest, found := strings.CutPrefix(uri, notebooksURI+"/")
+	if !found {
+		return "", "", "", false
+	}
+	parts := strings.Split(rest, "/")
+	for i, p := range parts {
+		var err error
+		if parts[i], err = url.PathUnescape(p); err != nil || parts[i] == "" {
+			return "", "", "", false
+		}
+	}
+	switch {
+	case len(parts) == 2 && (parts[1] == "sources" || parts[1] == "notes" || parts[1] == "artifacts"):
+		return parts[0], parts[1], "", true
+	case len(parts) == 3 && (parts[1] == "sources" || parts[1] == "notes"):
+		return parts[0], parts[1], parts[2], true
+	}
+	return "", "", "", false
+}
+
+func (r *resources) read(uri string) (*mcp.ReadResourceResult, error) {
+	notebookID, collection, itemID, ok := parseResourceURI(uri)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	base := notebooksURI + "/" + url.PathEscape(notebookID)
+	switch {
+	case notebookID == "":
+		notebooks, err := r.backend.ListRecentlyViewedProjects()
+		if err != nil {
+			return nil, fmt.Errorf("list notebooks: %w", err)
+		}
+		out := make([]notebookResource, 0, len(notebooks))
+		for _, nb := range notebooks {
+			nbURI := notebooksURI + "/" + url.PathEscape(nb.GetProjectId())
+			out = append(out, notebookResource{
+				notebookSummary: notebookSummary{ID: nb.GetProjectId(), Title: nb.GetTitle(), Emoji: nb.GetEmoji()},
+				Sources:         nbURI + "/sources",
+				Notes:           nbURI + "/notes",
+				Artifacts:       nbURI + "/artifacts",
+			})
+		}
+		return jsonResource(uri, out)
+
+	case collection == "sources" && itemID == "":
+		project, err := r.backend.GetProject(notebookID)
+		if err != nil {
+			return nil, fmt.Errorf("get project: %w", err)
+		}
+		out := make([]itemResource, 0, len(project.GetSources()))
+		for _, src := range project.GetSources() {
+			id := src.GetSourceId().GetSourceId()
+			out = append(out, itemResource{ID: id, Title: src.GetTitle(), URI: base + "/sources/" + url.PathEscape(id)})
+		}
+		return jsonResource(uri, out)
+
+	case collection == "sources":
+		project, err := r.backend.GetProject(notebookID)
+		if err != nil {
+			return nil, fmt.Errorf("get project: %w", err)
+		}
+		if !slices.ContainsFunc(project.GetSources(), func(src *pb.Source) bool { return src.GetSourceId().GetSourceId() == itemID }) {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		src, err := r.backend.LoadSource(itemID)
+		if err != nil {
+			return nil, fmt.Errorf("load source: %w", err)
+		}
+		data, err := protojson.MarshalOptions{Multiline: true, UseProtoNames: true}.Marshal(src)
+		if err != nil {
+			return nil, err
+		}
+		return textResource(uri, "application/json", string(data)), nil
+
+	case collection == "notes":
+		notes, err := r.backend.GetNotes(notebookID)
+		if err != nil {
+			return nil, fmt.Errorf("get notes: %w", err)
+		}
+		if itemID == "" {
+			out := make([]itemResource, 0, len(notes))
+			for _, note := range notes {
+				out = append(out, itemResource{ID: note.GetNoteId(), Title: note.GetTitle(), URI: base + "/notes/" + url.PathEscape(note.GetNoteId())})
+			}
+			return jsonResource(uri, out)
+		}
+		for _, note := range notes {
+			if note.GetNoteId() == itemID {
+				return textResource(uri, "text/markdown", "# "+note.GetTitle()+"\n\n"+note.GetContentText()), nil
+			}
+		}
+		return nil, mcp.ResourceNotFoundError(uri)
+
+	default: // artifacts
+		artifacts, err := r.backend.ListArtifacts(notebookID)
+		if err != nil {
+			return nil, fmt.Errorf("list artifacts: %w", err)
+		}
+		out := make([]artifactSummary, 0, len(artifacts))
+		for _, a := range artifacts {
+			out = append(out, artifactSummary{ID: a.ArtifactId, Type: artifactTypeLabel(a.Type), State: artifactStateLabel(a.State)})
+		}
+		return jsonResource(uri, out)
+	}
+}
+
+func textResource(uri, mimeType, text string) *mcp.ReadResourceResult {
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: mimeType, Text: text}}}
+}
+
+func jsonResource(uri string, v any) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return textResource(uri, "application/json", string(data)), nil
+}
+
+func contentSum(res *mcp.ReadResourceResult) [sha256.Size]byte {
+	h := sha256.New()
+	for _, c := range res.Contents {
+		h.Write([]byte(c.Text))
+		h.Write(c.Blob)
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// subscribe records the resource's current content and starts polling.
+func (r *resources) subscribe(ctx context.Context, req *mcp.SubscribeRequest) error {
+	uri := req.Params.URI
+	r.mu.Lock()
+	if w := r.watched[uri]; w != nil {
+		r.addSubscriber(w, req.Session)
+		r.mu.Unlock()
+		return nil
+	}
+	r.mu.Unlock()
+
+	res, err := r.read(uri)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w := r.watched[uri]
+	if w == nil { // else subscribed concurrently
+		w = &watchedResource{subscribers: map[*mcp.ServerSession]bool{}, sum: contentSum(res)}
+		r.watched[uri] = w
+	}
+	r.addSubscriber(w, req.Session)
+	if !r.polling {
+		r.polling = true
+		go r.poll()
+	}
+	return nil
+}
+
+// addSubscriber adds ss to w and, the first time ss subscribes to
+// anything, arranges for its subscriptions to be dropped when it closes.
+// r.mu must be held.
+func (r *resources) addSubscriber(w *watchedResource, ss *mcp.ServerSession) {
+	w.subscribers[ss] = true
+	if ss == nil || r.sessions[ss] {
+		return
+	}
+	r.sessions[ss] = true
+	go func() {
+		ss.Wait()
+		r.dropSession(ss)
+	}()
+}
+
+// dropSession removes the subscriptions of a closed session.
+func (r *resources) dropSession(ss *mcp.ServerSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, ss)
+	for uri, w := range r.watched {
+		delete(w.subscribers, ss)
+		if len(w.subscribers) == 0 {
+			delete(r.watched, uri)
+		}
+	}
+}
+
+func (r *resources) unsubscribe(ctx context.Context, req *mcp.UnsubscribeRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w := r.watched[req.Params.URI]; w != nil {
+		delete(w.subscribers, req.Session)
+		if len(w.subscribers) == 0 {
+			delete(r.watched, req.Params.URI)
+		}
+	}
+	return nil
+}
+
+// poll re-reads the watched resources until none are left. Read errors
+// are skipped; the next poll tries again.
+func (r *resources) poll() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.mu.Lock()
+		if len(r.watched) == 0 {
+			r.polling = false
+			r.mu.Unlock()
+			return
+		}
+		uris := make([]string, 0, len(r.watched))
+		for uri := range r.watched {
+			uris = append(uris, uri)
+		}
+		r.mu.Unlock()
+
+		for _, uri := range uris {
+			res, err := r.read(uri)
+			if err != nil {
+				continue
+			}
+			sum := contentSum(res)
+			r.mu.Lock()
+			w := r.watched[uri]
+			changed := w != nil && w.sum != sum
+			if changed {
+				w.sum = sum
+			}
+			r.mu.Unlock()
+			if changed {
+				r.server.ResourceUpdated(context.Background(), &mcp.ResourceUpdatedNotificationParams{URI: uri})
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package nlmmcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+	"github.com/tmc/nlm/internal/notebooklm/api"
+)
+
+type fakeResourceBackend struct {
+	mu    sync.Mutex
+	notes []*api.Note
+}
+
+func (f *fakeResourceBackend) ListRecentlyViewedProjects() ([]*api.Notebook, error) {
+	return []*api.Notebook{{ProjectId: "nb-1", Title: "Runbooks"}}, nil
+}
+
+func (f *fakeResourceBackend) GetProject(id string) (*api.Notebook, error) {
+	return &api.Notebook{ProjectId: id, Sources: []*pb.Source{{SourceId: &pb.SourceId{SourceId: "src-1"}, Title: "Deploys"}}}, nil
+}
+
+func (f *fakeResourceBackend) LoadSource(id string) (*pb.Source, error) {
+	return &pb.Source{SourceId: &pb.SourceId{SourceId: id}, Title: "Deploys"}, nil
+}
+
+func (f *fakeResourceBackend) GetNotes(string) ([]*api.Note, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.notes, nil
+}
+
+func (f *fakeResourceBackend) ListArtifacts(string) ([]*pb.Artifact, error) {
+	return []*pb.Artifact{{ArtifactId: "a-1", Type: pb.ArtifactType_ARTIFACT_TYPE_REPORT, State: pb.ArtifactState_ARTIFACT_STATE_READY}}, nil
+}
+
+func (f *fakeResourceBackend) setNotes(notes ...*api.Note) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notes = notes
+}
+
+// connectResources serves the resources over in-memory transports.
+func connectResources(t *testing.T, backend resourceBackend, opts *mcp.ClientOptions) (*mcp.ClientSession, *resources) {
+	t.Helper()
+	res := newResources(backend)
+	res.interval = 10 * time.Millisecond
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, &mcp.ServerOptions{
+		SubscribeHandler:   res.subscribe,
+		UnsubscribeHandler: res.unsubscribe,
+	})
+	res.register(server)
+	ctx := context.Background()
+	st, ct := mcp.NewInMemoryTransports()
+	ss, err := server.Connect(ctx, st, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ss.Close() })
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, opts).Connect(ctx, ct, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cs.Close() })
+	return cs, res
+}
+
+func readText(t *testing.T, cs *mcp.ClientSession, uri string) string {
+	t.Helper()
+	res, err := cs.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		t.Fatalf("read %s: %v", uri, err)
+	}
+	return res.Contents[0].Text
+}
+
+func TestParseResourceURI(t *testing.T) {
+	for _, tt := range []struct {
+		uri                  string
+		nb, collection, item string
+		ok                   bool
+	}{
+		{"nlm://notebooks", "", "", "", true},
+		{"nlm://notebooks/nb-1/sources", "nb-1", "sources", "", true},
+		{"nlm://notebooks/nb-1/notes/n%2F1", "nb-1", "notes", "n/1", true},
+		{"nlm://notebooks/nb-1/artifacts", "nb-1", "artifacts", "", true},
+		{"nlm://notebooks/nb-1/artifacts/a-1", "", "", "", false},
+		{"nlm://notebooks/nb-1", "", "", "", false},
+		{"nlm://notebooks/nb-1/sources/", "", "", "", false},
+		{"file:///etc/passwd", "", "", "", false},
+	} {
+		nb, collection, item, ok := parseResourceURI(tt.uri)
+		if nb != tt.nb || collection != tt.collection || item != tt.item || ok != tt.ok {
+			t.Errorf("parseResourceURI(%q) = %q, %q, %q, %v", tt.uri, nb, collection, item, ok)
+		}
+	}
+}
+
+func TestReadResources(t *testing.T) {
+	backend := &fakeResourceBackend{}
+	backend.setNotes(&api.Note{NoteId: "n-1", Title: "Plan", ContentText: "Ship it."})
+	cs, _ := connectResources(t, backend, nil)
+	ctx := context.Background()
+
+	list, err := cs.ListResources(ctx, nil)
+	if err != nil || len(list.Resources) != 1 || list.Resources[0].URI != notebooksURI {
+		t.Fatalf("resources = %+v, %v", list, err)
+	}
+	templates, err := cs.ListResourceTemplates(ctx, nil)
+	if err != nil || len(templates.ResourceTemplates) != 5 {
+		t.Fatalf("templates = %+v, %v", templates, err)
+	}
+
+	var notebooks []notebookResource
+	if err := json.Unmarshal([]byte(readText(t, cs, notebooksURI)), &notebooks); err != nil {
+		t.Fatal(err)
+	}
+	if len(notebooks) != 1 || notebooks[0].Sources != "nlm://notebooks/nb-1/sources" {
+		t.Errorf("notebooks = %+v", notebooks)
+	}
+
+	var sources []itemResource
+	if err := json.Unmarshal([]byte(readText(t, cs, notebooks[0].Sources)), &sources); err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 1 || sources[0].URI != "nlm://notebooks/nb-1/sources/src-1" {
+		t.Fatalf("sources = %+v", sources)
+	}
+	if got := readText(t, cs, sources[0].URI); !strings.Contains(got, `"Deploys"`) {
+		t.Errorf("source = %s", got)
+	}
+	if got := readText(t, cs, "nlm://notebooks/nb-1/notes/n-1"); got != "# Plan\n\nShip it." {
+		t.Errorf("note = %q", got)
+	}
+	if got := readText(t, cs, notebooks[0].Artifacts); !strings.Contains(got, "ARTIFACT_TYPE_REPORT") {
+		t.Errorf("artifacts = %s", got)
+	}
+	if _, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: "nlm://notebooks/nb-1/notes/n-2"}); err == nil {
+		t.Error("reading a missing note succeeded")
+	}
+	if _, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: "nlm://notebooks/nb-1/sources/src-2"}); err == nil {
+		t.Error("reading a source of another notebook succeeded")
+	}
+}
+
+func TestResourceSubscription(t *testing.T) {
+	backend := &fakeResourceBackend{}
+	backend.setNotes(&api.Note{NoteId: "n-1", Title: "Plan"})
+	updated := make(chan string, 10)
+	cs, _ := connectResources(t, backend, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			updated <- req.Params.URI
+		},
+	})
+	ctx := context.Background()
+	const uri = "nlm://notebooks/nb-1/notes"
+	if err := cs.Subscribe(ctx, &mcp.SubscribeParams{URI: uri}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-updated:
+		t.Fatalf("update %s before any change", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	backend.setNotes(&api.Note{NoteId: "n-1", Title: "Plan"}, &api.Note{NoteId: "n-2", Title: "Retro"})
+	select {
+	case got := <-updated:
+		if got != uri {
+			t.Errorf("updated %s, want %s", got, uri)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no update after the notes changed")
+	}
+
+	if err := cs.Unsubscribe(ctx, &mcp.UnsubscribeParams{URI: uri}); err != nil {
+		t.Fatal(err)
+	}
+	backend.setNotes()
+	select {
+	case got := <-updated:
+		t.Errorf("update %s after unsubscribing", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestResourceSubscriptionEndsWithSession(t *testing.T) {
+	cs, res := connectResources(t, &fakeResourceBackend{}, nil)
+	if err := cs.Subscribe(context.Background(), &mcp.SubscribeParams{URI: "nlm://notebooks/nb-1/notes"}); err != nil {
+		t.Fatal(err)
+	}
+	cs.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		res.mu.Lock()
+		watched, polling := len(res.watched), res.polling
+		res.mu.Unlock()
+		if watched == 0 && !polling {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d resources still watched (polling %v) after the session closed", watched, polling)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -35,14 +35,24 @@ func New(client *api.Client, impl *mcp.Implementation) *mcp.Server {
 		}
 	}
 
+	res := newResources(client)
 	server := mcp.NewServer(impl, &mcp.ServerOptions{
 		Instructions: `NotebookLM MCP server.
 
 Use list_notebooks to discover notebook IDs.
 Most tools require a notebook_id argument.
-Mutating tools change NotebookLM state directly.`,
+Mutating tools change NotebookLM state directly.
+Resources under nlm://notebooks expose notebook sources, notes and
+artifacts for attaching as context; subscriptions are polled.
+Prompts named after the generate_* tools (study_guide, faq, ...) embed
+excerpts of the selected sources, found with one NotebookLM chat, so the
+transformation can be done in the chat.`,
+		SubscribeHandler:   res.subscribe,
+		UnsubscribeHandler: res.unsubscribe,
 	})
 	registerTools(server, client)
+	res.register(server)
+	registerPrompts(server, res, client.StreamChatWithContext)
 	return server
 }
 
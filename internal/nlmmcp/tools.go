@@ -58,6 +58,7 @@ type listArtifactsInput struct {
 type createAudioOverviewInput struct {
 	NotebookID   string `json:"notebook_id"`
 	Instructions string `json:"instructions,omitempty"`
+	Wait         bool   `json:"wait,omitempty" jsonschema:"Wait until the audio overview is ready, reporting progress"`
 }
 
 type getAudioOverviewInput struct {
@@ -106,6 +107,7 @@ type generateContentInput struct {
 type createVideoOverviewInput struct {
 	NotebookID   string `json:"notebook_id"`
 	Instructions string `json:"instructions"`
+	Wait         bool   `json:"wait,omitempty" jsonschema:"Wait until the video overview is ready, reporting progress"`
 }
 
 type createSlideDeckInput struct {
@@ -130,6 +132,7 @@ type getInstructionsInput struct {
 type startDeepResearchInput struct {
 	NotebookID string `json:"notebook_id"`
 	Query      string `json:"query"`
+	Wait       bool   `json:"wait,omitempty" jsonschema:"Wait until the research is done and return its content, reporting progress"`
 }
 
 type pollDeepResearchInput struct {
@@ -261,9 +264,10 @@ func registerTools(server *mcp.Server, client *api.Client) {
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "add_source_text",
-		Description: "Add text content as a source to a notebook.",
+		Description: "Add text content as a source to a notebook. The upload runs to completion even if the call is cancelled.",
 		Annotations: mutatingAnnotations,
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input addSourceTextInput) (*mcp.CallToolResult, any, error) {
+		newProgress(req).report(ctx, "adding %q (%d bytes)", input.Title, len(input.Content))
 		sourceID, err := client.AddSourceFromText(input.NotebookID, input.Content, input.Title)
 		if err != nil {
 			return errorResult(fmt.Sprintf("failed to add source: %v", err)), nil, nil
@@ -305,14 +309,20 @@ func registerTools(server *mcp.Server, client *api.Client) {
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "create_audio_overview",
-		Description: "Create a new audio overview.",
+		Description: "Create a new audio overview. With wait, returns once it is ready, reporting its state as progress. Cancelling stops the wait, not the generation.",
 		Annotations: mutatingAnnotations,
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input createAudioOverviewInput) (*mcp.CallToolResult, any, error) {
+		p := newProgress(req)
+		p.report(ctx, "starting audio overview")
 		result, err := client.CreateAudioOverview(input.NotebookID, input.Instructions)
 		if err != nil {
 			return errorResult(fmt.Sprintf("failed to create audio overview: %v", err)), nil, nil
 		}
-		return textResult(fmt.Sprintf("started audio overview %q (id: %s)", result.Title, result.AudioID)), nil, nil
+		if !input.Wait || result.AudioID == "" {
+			return textResult(fmt.Sprintf("started audio overview %q (id: %s)", result.Title, result.AudioID)), nil, nil
+		}
+		state, err := waitForArtifact(ctx, func() ([]*pb.Artifact, error) { return client.ListArtifacts(input.NotebookID) }, result.AudioID, p)
+		return artifactWaitResult("audio overview", result.AudioID, state, err), nil, nil
 	})
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -363,14 +373,20 @@ func registerTools(server *mcp.Server, client *api.Client) {
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "create_video_overview",
-		Description: "Create a new video overview for a notebook.",
+		Description: "Create a new video overview for a notebook. With wait, returns once it is ready, reporting its state as progress. Cancelling stops the wait, not the generation.",
 		Annotations: mutatingAnnotations,
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input createVideoOverviewInput) (*mcp.CallToolResult, any, error) {
+		p := newProgress(req)
+		p.report(ctx, "starting video overview")
 		result, err := client.CreateVideoOverview(input.NotebookID, input.Instructions)
 		if err != nil {
 			return errorResult(fmt.Sprintf("failed to create video overview: %v", err)), nil, nil
 		}
-		return textResult(fmt.Sprintf("started video overview (id: %s)", result.VideoID)), nil, nil
+		if !input.Wait || result.VideoID == "" {
+			return textResult(fmt.Sprintf("started video overview (id: %s)", result.VideoID)), nil, nil
+		}
+		state, err := waitForArtifact(ctx, func() ([]*pb.Artifact, error) { return client.ListArtifacts(input.NotebookID) }, result.VideoID, p)
+		return artifactWaitResult("video overview", result.VideoID, state, err), nil, nil
 	})
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -434,14 +450,25 @@ func registerTools(server *mcp.Server, client *api.Client) {
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "start_deep_research",
-		Description: "Start a deep research session. Returns a research ID that can be used with poll_deep_research to check progress.",
+		Description: "Start a deep research session. Returns a research ID that can be used with poll_deep_research to check progress, or with wait, the finished research. Cancelling stops the wait, not the research.",
 		Annotations: mutatingAnnotations,
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input startDeepResearchInput) (*mcp.CallToolResult, any, error) {
+		p := newProgress(req)
+		p.report(ctx, "starting deep research")
 		result, err := client.StartDeepResearch(input.NotebookID, input.Query)
 		if err != nil {
 			return errorResult(fmt.Sprintf("failed to start deep research: %v", err)), nil, nil
 		}
-		return jsonResult(result), nil, nil
+		if !input.Wait || result.Done {
+			return jsonResult(result), nil, nil
+		}
+		done, err := waitForResearch(ctx, func() (*api.DeepResearchResult, error) {
+			return client.PollDeepResearch(input.NotebookID, result.ResearchID)
+		}, p)
+		if err != nil {
+			return errorResult(fmt.Sprintf("stopped waiting for research %s (continue with poll_deep_research): %v", result.ResearchID, err)), nil, nil
+		}
+		return jsonResult(done), nil, nil
 	})
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -492,9 +519,10 @@ func registerTools(server *mcp.Server, client *api.Client) {
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "add_source_url",
-		Description: "Add a source from a URL.",
+		Description: "Add a source from a URL. The request runs to completion even if the call is cancelled.",
 		Annotations: mutatingAnnotations,
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input addSourceURLInput) (*mcp.CallToolResult, any, error) {
+		newProgress(req).report(ctx, "adding %s", input.URL)
 		sourceID, err := client.AddSourceFromURL(input.NotebookID, input.URL)
 		if err != nil {
 			return errorResult(fmt.Sprintf("failed to add source: %v", err)), nil, nil
@@ -504,57 +532,76 @@ func registerTools(server *mcp.Server, client *api.Client) {
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "generate_chat",
-		Description: "Generate a NotebookLM chat response from a prompt.",
+		Description: "Generate a NotebookLM chat response from a prompt. Thinking steps are reported as progress.",
 		Annotations: mutatingAnnotations,
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input generateChatInput) (*mcp.CallToolResult, any, error) {
-		response, err := client.GenerateFreeFormStreamed(input.NotebookID, input.Prompt, nil)
+		answer, err := streamChat(ctx, client.StreamChatWithContext, api.ChatRequest{
+			ProjectID: input.NotebookID,
+			Prompt:    input.Prompt,
+		}, newProgress(req))
 		if err != nil {
 			return errorResult(fmt.Sprintf("failed to generate chat: %v", err)), nil, nil
 		}
-		if response == nil || response.Chunk == "" {
+		if answer == "" {
 			return textResult("(no response received)"), nil, nil
 		}
-		return textResult(response.Chunk), nil, nil
+		return textResult(answer), nil, nil
 	})
 
 	registerGenerationTools(server, client)
 }
 
-func registerGenerationTools(server *mcp.Server, client *api.Client) {
-	actions := map[string]string{
-		"summarize":    "summarize",
-		"briefing_doc": "briefing_doc",
-		"faq":          "faq",
-		"study_guide":  "study_guide",
-		"rephrase":     "rephrase",
-		"expand":       "expand",
-		"critique":     "critique",
-		"brainstorm":   "brainstorm",
-		"verify":       "verify",
-		"explain":      "explain",
-		"outline":      "outline",
-		"mindmap":      "interactive_mindmap",
-		"timeline":     "timeline",
-		"toc":          "table_of_contents",
-	}
+// generationAction is a content transformation run by actOnSources. Each
+// is registered as a generate_<name> tool and as a <name> prompt.
+type generationAction struct {
+	name        string
+	action      string // actOnSources action
+	instruction string // prompt instruction, completed by "from the sources"
+}
 
-	for toolName, action := range actions {
-		toolName := toolName
-		action := action
+var generationActions = []generationAction{
+	{"summarize", "summarize", "Summarize the key points"},
+	{"briefing_doc", "briefing_doc", "Write a briefing document covering the main themes, facts and conclusions"},
+	{"faq", "faq", "Write frequently asked questions, with answers,"},
+	{"study_guide", "study_guide", "Write a study guide with key concepts, a short quiz and a glossary"},
+	{"rephrase", "rephrase", "Rephrase the content in clearer language"},
+	{"expand", "expand", "Expand on the main ideas"},
+	{"critique", "critique", "Critique the arguments, evidence and gaps"},
+	{"brainstorm", "brainstorm", "Brainstorm new ideas building on the content"},
+	{"verify", "verify", "Check the claims against each other and flag inconsistencies or unsupported statements"},
+	{"explain", "explain", "Explain the concepts"},
+	{"outline", "outline", "Write a structured outline"},
+	{"mindmap", "interactive_mindmap", "Organize the ideas as a mind map, as a nested Markdown list,"},
+	{"timeline", "timeline", "Build a timeline of the events"},
+	{"toc", "table_of_contents", "Write a table of contents"},
+}
 
+func registerGenerationTools(server *mcp.Server, client *api.Client) {
+	for _, g := range generationActions {
 		mcp.AddTool(server, &mcp.Tool{
-			Name:        "generate_" + toolName,
-			Description: fmt.Sprintf("Generate a %s from sources.", toolName),
+			Name:        "generate_" + g.name,
+			Description: fmt.Sprintf("Generate a %s from sources.", g.name),
 			Annotations: mutatingAnnotations,
 		}, func(ctx context.Context, req *mcp.CallToolRequest, input generateContentInput) (*mcp.CallToolResult, any, error) {
-			if err := client.ActOnSources(input.NotebookID, action, input.SourceIDs); err != nil {
-				return errorResult(fmt.Sprintf("failed to generate %s: %v", toolName, err)), nil, nil
+			if err := client.ActOnSources(input.NotebookID, g.action, input.SourceIDs); err != nil {
+				return errorResult(fmt.Sprintf("failed to generate %s: %v", g.name, err)), nil, nil
 			}
-			return textResult(fmt.Sprintf("triggered %s generation", toolName)), nil, nil
+			return textResult(fmt.Sprintf("triggered %s generation", g.name)), nil, nil
 		})
 	}
 }
 
+// artifactWaitResult reports the outcome of waitForArtifact.
+func artifactWaitResult(kind, id string, state pb.ArtifactState, err error) *mcp.CallToolResult {
+	switch {
+	case err != nil:
+		return errorResult(fmt.Sprintf("stopped waiting for %s %s (check list_artifacts): %v", kind, id, err))
+	case state == pb.ArtifactState_ARTIFACT_STATE_FAILED:
+		return errorResult(fmt.Sprintf("%s %s failed", kind, id))
+	}
+	return textResult(fmt.Sprintf("%s %s is ready", kind, id))
+}
+
 func textResult(text string) *mcp.CallToolResult {
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+)
+
+// WaitArtifacts polls for artifact generation to finish. It calls list
+// and passes the artifacts to check, right away and then every interval,
+// until check reports done or fails, list fails, or ctx ends. Callers
+// bound the wait with a context deadline.
+//
+// list is usually a closure over Client.ListArtifacts.
+func WaitArtifacts(ctx context.Context, interval time.Duration, list func() ([]*pb.Artifact, error), check func([]*pb.Artifact) (done bool, err error)) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		// Both may be ready at once; never poll after ctx ends.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		artifacts, err := list()
+		if err != nil {
+			return fmt.Errorf("list artifacts: %w", err)
+		}
+		if done, err := check(artifacts); done || err != nil {
+			return err
+		}
+		timer.Reset(interval)
+	}
+}
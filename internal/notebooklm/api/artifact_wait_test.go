@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/tmc/nlm/gen/notebooklm/v1alpha1"
+)
+
+func TestWaitArtifacts(t *testing.T) {
+	polls := 0
+	list := func() ([]*pb.Artifact, error) {
+		polls++
+		return nil, nil
+	}
+	err := WaitArtifacts(context.Background(), time.Millisecond, list, func([]*pb.Artifact) (bool, error) {
+		return polls == 3, nil
+	})
+	if err != nil || polls != 3 {
+		t.Errorf("WaitArtifacts = %v after %d polls, want nil after 3", err, polls)
+	}
+
+	boom := errors.New("boom")
+	if err := WaitArtifacts(context.Background(), time.Millisecond, list, func([]*pb.Artifact) (bool, error) { return false, boom }); err != boom {
+		t.Errorf("check error = %v, want %v", err, boom)
+	}
+	failing := func() ([]*pb.Artifact, error) { return nil, boom }
+	if err := WaitArtifacts(context.Background(), time.Millisecond, failing, nil); !errors.Is(err, boom) {
+		t.Errorf("list error = %v, want %v", err, boom)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := WaitArtifacts(ctx, time.Hour, list, func([]*pb.Artifact) (bool, error) { return false, nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout error = %v, want deadline exceeded", err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	polls = 0
+	if err := WaitArtifacts(canceled, time.Millisecond, list, nil); !errors.Is(err, context.Canceled) || polls != 0 {
+		t.Errorf("canceled wait = %v after %d polls, want canceled after none", err, polls)
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Citation is a numbered reference from a chat answer to a source passage.
+// Number matches the bracketed markers the server inlines in the answer
+// text ("[1]", "[2-4]").
+type Citation struct {
+	Number      int         `json:"number"`
+	SourceID    string      `json:"source_id,omitempty"` // empty unless the chat used a single source
+	SourceTitle string      `json:"source_title,omitempty"`
+	Text        string      `json:"text,omitempty"`       // cited source fragment
+	Spans       []TextRange `json:"spans,omitempty"`      // answer text supported by this citation
+	Confidence  float64     `json:"confidence,omitempty"` // server relevance score, 0-1
+}
+
+// TextRange is a half-open range of character offsets into the full
+// answer text, as reported by the server.
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// parseChatCitations extracts citations from the inner JSON of a chat
+// frame. Per docs/dev/capture-analysis.md the frame holds
+//
+//	[1]: cited passages, one per citation number:
+//	     [null, null, confidence, [[null, start, end]], [excerpts...]]
+//	[2]: answer spans: [[null, start, end], [passage indices]]
+//
+// The ranges in [1] are offsets into the cited source, not the answer (the
+// captured answer is 5627 characters, its passages start past 12000), so
+// the indices in [2] are 0-based positions in [1]. No field names the
+// source a passage comes from, so SourceID is set only when the request
+// named a single source; otherwise attribution is unknown and it is left
+// empty. It returns nil if the frame carries no citations.
+func parseChatCitations(inner []interface{}, sourceIDs []string) []Citation {
+	if len(inner) < 2 {
+		return nil
+	}
+	details, _ := inner[1].([]interface{})
+	if len(details) == 0 {
+		return nil
+	}
+	citations := make([]Citation, len(details))
+	for i, d := range details {
+		c := Citation{Number: i + 1}
+		entry, _ := d.([]interface{})
+		if len(entry) > 2 {
+			c.Confidence, _ = entry[2].(float64)
+		}
+		if len(entry) > 4 {
+			c.Text = strings.Join(strings.Fields(strings.Join(collectStrings(entry[4], nil), " ")), " ")
+		}
+		if len(sourceIDs) == 1 {
+			c.SourceID = sourceIDs[0]
+		}
+		citations[i] = c
+	}
+
+	if len(inner) > 2 {
+		mappings, _ := inner[2].([]interface{})
+		for _, m := range mappings {
+			pair, _ := m.([]interface{})
+			if len(pair) < 2 {
+				continue
+			}
+			span, ok := parseTextRange(pair[0])
+			if !ok {
+				continue
+			}
+			indices, _ := pair[1].([]interface{})
+			for _, idx := range indices {
+				n, ok := idx.(float64)
+				if !ok || n < 0 || int(n) >= len(citations) {
+					continue
+				}
+				citations[int(n)].Spans = append(citations[int(n)].Spans, span)
+			}
+		}
+	}
+	return citations
+}
+
+// parseTextRange decodes a [null, start, end] range.
+func parseTextRange(v interface{}) (TextRange, bool) {
+	arr, _ := v.([]interface{})
+	if len(arr) < 3 {
+		return TextRange{}, false
+	}
+	start, ok1 := arr[1].(float64)
+	end, ok2 := arr[2].(float64)
+	if !ok1 || !ok2 {
+		return TextRange{}, false
+	}
+	return TextRange{Start: int(start), End: int(end)}, true
+}
+
+// collectStrings appends the strings nested anywhere in v, in order.
+func collectStrings(v interface{}, out []string) []string {
+	switch v := v.(type) {
+	case string:
+		return append(out, v)
+	case []interface{}:
+		for _, e := range v {
+			out = collectStrings(e, out)
+		}
+	}
+	return out
+}
+
+// projectSources returns the IDs of the notebook's sources, in order, and
+// their titles by ID.
+func (c *Client) projectSources(projectID string) ([]string, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	project, err := c.GetProjectWithContext(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ids []string
+	titles := make(map[string]string)
+	for _, source := range project.Sources {
+		if source.SourceId != nil {
+			ids = append(ids, source.SourceId.SourceId)
+			titles[source.SourceId.SourceId] = strings.TrimSpace(source.Title)
+		}
+	}
+	return ids, titles, nil
+}
+
+// fillCitationTitles sets SourceTitle on citations from titles, fetching
+// the notebook's source titles only when titles is nil. It returns the
+// titles used. Lookup failures leave the titles empty.
+func (c *Client) fillCitationTitles(projectID string, citations []Citation, titles map[string]string) map[string]string {
+	if titles == nil {
+		var err error
+		if _, titles, err = c.projectSources(projectID); err != nil {
+			if c.config.Debug {
+				fmt.Fprintf(os.Stderr, "DEBUG: failed to get source titles: %v\n", err)
+			}
+			return nil
+		}
+	}
+	for i := range citations {
+		citations[i].SourceTitle = titles[citations[i].SourceID]
+	}
+	return titles
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/json"
+	"os"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -15,7 +17,7 @@ func TestParseChatResponseChunked(t *testing.T) {
 
 	var got []ChatChunk
 	c := &Client{}
-	err := c.parseChatResponseChunked(strings.NewReader(stream), func(chunk ChatChunk) bool {
+	err := c.parseChatResponseChunked(strings.NewReader(stream), nil, func(chunk ChatChunk) bool {
 		got = append(got, chunk)
 		return true
 	})
@@ -38,6 +40,97 @@ func TestParseChatResponseChunked(t *testing.T) {
 	}
 }
 
+// chatCitationsFrame is the inner JSON of the final chat frame excerpted in
+// docs/dev/capture-analysis.md (capture entry 22), with the 5627-character
+// answer shortened to its placeholder there.
+func chatCitationsFrame(t *testing.T) []interface{} {
+	t.Helper()
+	data, err := os.ReadFile("testdata/chat_citations_frame.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inner []interface{}
+	if err := json.Unmarshal(data, &inner); err != nil {
+		t.Fatal(err)
+	}
+	return inner
+}
+
+func TestParseChatResponseChunkedCitations(t *testing.T) {
+	inner := chatCitationsFrame(t)
+	frame := func(inner interface{}) string {
+		data, err := json.Marshal(inner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		envelope, err := json.Marshal([]interface{}{"wrb.fr", "mock", string(data)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "1\n" + string(envelope) + "\n"
+	}
+	first := []interface{}{[]interface{}{"cumulative answer", nil, []interface{}{"conv_id", "msg_id", 2}}}
+	stream := ")]}'\n" + frame(first) + frame(inner)
+
+	var got []ChatChunk
+	c := &Client{}
+	err := c.parseChatResponseChunked(strings.NewReader(stream), []string{"src-a", "src-b"}, func(chunk ChatChunk) bool {
+		got = append(got, chunk)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("parseChatResponseChunked() error = %v", err)
+	}
+	if len(got) != 3 || got[2].Phase != ChatChunkCitations {
+		t.Fatalf("got %#v, want two answer chunks and a citations chunk", got)
+	}
+	if text := got[0].Text + got[1].Text; text != "cumulative answer text" {
+		t.Errorf("answer = %q", text)
+	}
+	cs := got[2].Citations
+	if len(cs) != 1 {
+		t.Fatalf("got %d citations, want 1", len(cs))
+	}
+	want := Citation{
+		Number:     1,
+		Text:       "Real-Time Interactive Audio via WebRTC additional excerpt text",
+		Confidence: 0.9937714107754594,
+	}
+	if cs[0].Number != want.Number || cs[0].Text != want.Text || cs[0].Confidence != want.Confidence {
+		t.Errorf("citation 1 = %+v, want %+v", cs[0], want)
+	}
+	if cs[0].SourceID != "" {
+		t.Errorf("citation 1 source = %q, want none: the frame does not say which of two sources it quotes", cs[0].SourceID)
+	}
+	// Spans naming passages 1 and 2 point past the one passage in [1].
+	if want := []TextRange{{2111, 2272}, {2466, 2533}}; !slices.Equal(cs[0].Spans, want) {
+		t.Errorf("citation 1 spans = %v, want %v", cs[0].Spans, want)
+	}
+}
+
+func TestParseChatCitationsSingleSource(t *testing.T) {
+	cs := parseChatCitations(chatCitationsFrame(t), []string{"only-source"})
+	if len(cs) != 1 || cs[0].SourceID != "only-source" {
+		t.Fatalf("citations = %+v, want source only-source", cs)
+	}
+	if cs := parseChatCitations([]interface{}{[]interface{}{"no citations"}}, nil); cs != nil {
+		t.Errorf("citations = %+v, want nil", cs)
+	}
+}
+
+func TestFillCitationTitlesReusesTitles(t *testing.T) {
+	// A zero Client has no RPC client, so any fetch would panic.
+	c := &Client{}
+	titles := map[string]string{"src-1": "Design Notes"}
+	cs := []Citation{{Number: 1, SourceID: "src-1"}, {Number: 2, SourceID: "src-2"}}
+	if got := c.fillCitationTitles("project-123", cs, titles); got == nil {
+		t.Fatal("fillCitationTitles dropped the titles")
+	}
+	if cs[0].SourceTitle != "Design Notes" || cs[1].SourceTitle != "" {
+		t.Fatalf("citations = %+v", cs)
+	}
+}
+
 func TestAnswerOnlyCallback(t *testing.T) {
 	var got []string
 	callback := answerOnlyCallback(func(chunk string) bool {
@@ -50,6 +143,7 @@ func TestAnswerOnlyCallback(t *testing.T) {
 		{Phase: ChatChunkAnswer, Text: "Answer"},
 		{Phase: ChatChunkAnswer, Text: " continued"},
 		{Phase: ChatChunkAnswer, Text: ""},
+		{Phase: ChatChunkCitations, Citations: []Citation{{Number: 1}}},
 	} {
 		if !callback(chunk) {
 			t.Fatalf("callback returned false for %#v", chunk)
@@ -90,6 +90,22 @@ func (c *Client) SetAuthUser(authUser string) {
 	c.config.AuthUser = authUser
 }
 
+// OrchestrationService returns the generated orchestration service client,
+// for callers that work with the request and response messages directly.
+func (c *Client) OrchestrationService() *service.LabsTailwindOrchestrationServiceClient {
+	return c.orchestrationService
+}
+
+// SharingService returns the generated sharing service client.
+func (c *Client) SharingService() *service.LabsTailwindSharingServiceClient {
+	return c.sharingService
+}
+
+// GuidebooksService returns the generated guidebooks service client.
+func (c *Client) GuidebooksService() *service.LabsTailwindGuidebooksServiceClient {
+	return c.guidebooksService
+}
+
 // authUserOrDefault returns the configured authuser value or "0".
 func (c *Client) authUserOrDefault() string {
 	if c.config.AuthUser != "" {
@@ -2778,15 +2794,17 @@ type ChatRequest struct {
 type ChatChunkPhase int
 
 const (
-	ChatChunkThinking ChatChunkPhase = iota // Reasoning trace (replaced by next thinking chunk)
-	ChatChunkAnswer                         // Final answer text (cumulative delta)
+	ChatChunkThinking  ChatChunkPhase = iota // Reasoning trace (replaced by next thinking chunk)
+	ChatChunkAnswer                          // Final answer text (cumulative delta)
+	ChatChunkCitations                       // Citations for the complete answer, sent once after it
 )
 
 // ChatChunk is a parsed chunk from the chat stream with phase metadata.
 type ChatChunk struct {
-	Text   string         // The text content (delta for answer, full replacement for thinking)
-	Header string         // For thinking chunks: the bold header line only
-	Phase  ChatChunkPhase // Whether this is thinking or answer
+	Text      string         // The text content (delta for answer, full replacement for thinking)
+	Header    string         // For thinking chunks: the bold header line only
+	Phase     ChatChunkPhase // Whether this is thinking, answer or citations
+	Citations []Citation     // For citation chunks: the answer's citations, by number
 }
 
 // chatEndpoint is the gRPC-Web endpoint for GenerateFreeFormStreamed.
@@ -2824,7 +2842,13 @@ func (c *Client) GenerateFreeFormStreamedWithCallback(projectID string, prompt s
 // StreamChat streams the response with phase-aware ChatChunk callbacks.
 // Thinking chunks are complete reasoning traces; answer chunks are cumulative deltas.
 func (c *Client) StreamChat(req ChatRequest, callback func(ChatChunk) bool) error {
-	return c.doChatStreamedChunked(req, callback)
+	return c.doChatStreamedChunked(context.Background(), req, callback)
+}
+
+// StreamChatWithContext is like StreamChat but accepts a context for
+// cancellation. If ctx is canceled mid-stream, it returns ctx.Err().
+func (c *Client) StreamChatWithContext(ctx context.Context, req ChatRequest, callback func(ChatChunk) bool) error {
+	return c.doChatStreamedChunked(ctx, req, callback)
 }
 
 func answerOnlyCallback(callback func(string) bool) func(ChatChunk) bool {
@@ -2843,27 +2867,28 @@ func (c *Client) ChatWithHistory(req ChatRequest) (string, error) {
 
 // resolveSourceIDs fills in source IDs from the project if not provided.
 func (c *Client) resolveSourceIDs(projectID string, sourceIDs []string) []string {
+	sourceIDs, _ = c.resolveSources(projectID, sourceIDs)
+	return sourceIDs
+}
+
+// resolveSources is resolveSourceIDs that also returns the titles of the
+// project's sources by ID when it had to fetch the project, and nil when
+// it did not.
+func (c *Client) resolveSources(projectID string, sourceIDs []string) ([]string, map[string]string) {
 	if len(sourceIDs) > 0 || os.Getenv("NLM_SKIP_SOURCES") == "true" {
-		return sourceIDs
+		return sourceIDs, nil
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	project, err := c.GetProjectWithContext(ctx, projectID)
+	sourceIDs, titles, err := c.projectSources(projectID)
 	if err != nil {
 		if c.config.Debug {
 			fmt.Fprintf(os.Stderr, "DEBUG: failed to get project sources: %v\n", err)
 		}
-		return sourceIDs
-	}
-	for _, source := range project.Sources {
-		if source.SourceId != nil {
-			sourceIDs = append(sourceIDs, source.SourceId.SourceId)
-		}
+		return nil, nil
 	}
 	if c.config.Debug {
 		fmt.Fprintf(os.Stderr, "DEBUG: using %d sources for chat\n", len(sourceIDs))
 	}
-	return sourceIDs
+	return sourceIDs, titles
 }
 
 type chatWireHistoryEntry struct {
@@ -3096,7 +3121,11 @@ func (c *Client) doChatStreamed(req ChatRequest, callback func(chunk string) boo
 }
 
 // doChatStreamedChunked sends a chat request and streams phase-aware ChatChunks via callback.
-func (c *Client) doChatStreamedChunked(req ChatRequest, callback func(ChatChunk) bool) error {
+func (c *Client) doChatStreamedChunked(ctx context.Context, req ChatRequest, callback func(ChatChunk) bool) error {
+	// Resolve sources up front so citations can fall back to them, and
+	// keep their titles for the citation footnotes.
+	var titles map[string]string
+	req.SourceIDs, titles = c.resolveSources(req.ProjectID, req.SourceIDs)
 	body, err := c.buildChatRequestBody(req)
 	if err != nil {
 		return err
@@ -3104,7 +3133,7 @@ func (c *Client) doChatStreamedChunked(req ChatRequest, callback func(ChatChunk)
 
 	chatURL := c.buildChatURL(req.ProjectID)
 
-	httpReq, err := http.NewRequest("POST", chatURL, strings.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", chatURL, strings.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("create chat request: %w", err)
 	}
@@ -3116,6 +3145,9 @@ func (c *Client) doChatStreamedChunked(req ChatRequest, callback func(ChatChunk)
 	client := httpClientWithTimeout(120 * time.Second)
 	resp, err := client.Do(httpReq)
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("chat request: %w", err)
 	}
 	defer resp.Body.Close()
@@ -3125,7 +3157,16 @@ func (c *Client) doChatStreamedChunked(req ChatRequest, callback func(ChatChunk)
 		return fmt.Errorf("chat request failed: %d %s: %s", resp.StatusCode, resp.Status, string(respBody)[:min(500, len(respBody))])
 	}
 
-	return c.parseChatResponseChunked(resp.Body, callback)
+	err = c.parseChatResponseChunked(resp.Body, req.SourceIDs, func(chunk ChatChunk) bool {
+		if chunk.Phase == ChatChunkCitations {
+			titles = c.fillCitationTitles(req.ProjectID, chunk.Citations, titles)
+		}
+		return callback(chunk)
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
 
 // parseChatResponseChunked reads the stream incrementally and emits phase-aware
@@ -3136,13 +3177,17 @@ func (c *Client) doChatStreamedChunked(req ChatRequest, callback func(ChatChunk)
 //	<json>\n       (the actual data — may contain ["wrb.fr", ...] envelope)
 //
 // Chunks are emitted immediately as they are read, enabling real-time streaming.
-func (c *Client) parseChatResponseChunked(r io.Reader, callback func(ChatChunk) bool) error {
+// Citations are revised along with the answer, so the last set seen is
+// emitted as a single ChatChunkCitations chunk once the stream ends.
+// sourceIDs are the sources the request was scoped to.
+func (c *Client) parseChatResponseChunked(r io.Reader, sourceIDs []string, callback func(ChatChunk) bool) error {
 	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 0, 256*1024), 1024*1024) // up to 1MB lines
 
 	var lastThinking string
 	var lastAnswer string
 	var answerStarted bool
+	var citations []Citation
 	firstLine := true
 
 	for scanner.Scan() {
@@ -3198,7 +3243,14 @@ func (c *Client) parseChatResponseChunked(r io.Reader, callback func(ChatChunk)
 			continue
 		}
 
-		text := extractChatText(innerStr)
+		var inner []interface{}
+		if err := json.Unmarshal([]byte(innerStr), &inner); err != nil {
+			continue
+		}
+		if cs := parseChatCitations(inner, sourceIDs); cs != nil {
+			citations = cs
+		}
+		text := chatFrameText(inner)
 		if text == "" {
 			continue
 		}
@@ -3262,7 +3314,13 @@ func (c *Client) parseChatResponseChunked(r io.Reader, callback func(ChatChunk)
 		lastAnswer = text
 	}
 
-	return scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(citations) > 0 {
+		callback(ChatChunk{Phase: ChatChunkCitations, Citations: citations})
+	}
+	return nil
 }
 
 // parseChatResponse reads the Google chunked response format and extracts text.
@@ -3410,10 +3468,14 @@ func extractChatText(innerJSON string) string {
 		return ""
 	}
 
-	// The response structure contains the full response text (not deltas).
-	// Navigate: [0] -> [0] to find the text string
-	arr, ok := data.([]interface{})
-	if !ok || len(arr) == 0 {
+	arr, _ := data.([]interface{})
+	return chatFrameText(arr)
+}
+
+// chatFrameText returns the full (not delta) response text of a parsed chat
+// frame, found at [0][0].
+func chatFrameText(arr []interface{}) string {
+	if len(arr) == 0 {
 		return ""
 	}
 